@@ -0,0 +1,65 @@
+//go:build acctest
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// TestAcceptanceImport imports the repo of a task with the import
+// command and executes the resulting formula.
+func TestAcceptanceImport(t *testing.T) {
+
+	// build gitalchemist binary to test
+	err := exec.Command(goCmd, "build").Run()
+	if err != nil {
+		t.Fatalf("ERROR: test preparation: %v", err)
+	}
+
+	const source, task = "cmd_merge", "cmd_import"
+	cfgDir := filepath.Join(defaultCwd, "imported")
+	repoDir := filepath.Join(cfgDir, "repos")
+	err = os.RemoveAll(cfgDir)
+	if err != nil {
+		t.Fatalf("ERROR: test preparation: %v", err)
+	}
+
+	for _, args := range [][]string{
+		{"-cfgdir", testDataDir, "-targetdir", repoDir, source},
+		{"import", filepath.Join(repoDir, source), filepath.Join(cfgDir, task)},
+		{"-cfgdir", cfgDir, task},
+	} {
+		cmd := exec.Command(gitAlchemistCmd, args...)
+		if testing.Verbose() {
+			cmd.Stdout = os.Stderr
+			cmd.Stderr = os.Stderr
+		}
+		err = cmd.Run()
+		if err != nil {
+			t.Fatalf("ERROR: %v: %v", args, err)
+		}
+	}
+
+	// the imported history has the same trees, authors, and messages
+	args := []string{"log", "--graph", "--branches", "--format=%T %an: %s"}
+	cmd := exec.Command(gitCmd, args...)
+	cmd.Dir = filepath.Join(repoDir, source)
+	want, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("ERROR: %v: %v", args, err)
+	}
+	checkFormulaResult(t, accTestCase{
+		name: task,
+		gitList: []gitPara{{
+			args: args,
+			want: string(want),
+		}, {
+			args: []string{"branch"},
+			want: "  feature/start_project\n" +
+				"* main\n",
+		}},
+	})
+}
@@ -0,0 +1,68 @@
+//go:build acctest && !windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// TestAcceptanceRecord records a scripted session with the record
+// command and executes the resulting formula.
+func TestAcceptanceRecord(t *testing.T) {
+
+	// build gitalchemist binary to test
+	err := exec.Command(goCmd, "build").Run()
+	if err != nil {
+		t.Fatalf("ERROR: test preparation: %v", err)
+	}
+
+	const task = "cmd_record"
+	cfgDir := filepath.Join(defaultCwd, "recorded")
+	err = os.RemoveAll(cfgDir)
+	if err != nil {
+		t.Fatalf("ERROR: test preparation: %v", err)
+	}
+
+	session, err := filepath.Abs(filepath.Join(testDataDir, "record", "session.sh"))
+	if err != nil {
+		t.Fatalf("ERROR: test preparation: %v", err)
+	}
+	cmd := exec.Command(gitAlchemistCmd, "record", "-shell", session,
+		filepath.Join(cfgDir, task))
+	if testing.Verbose() {
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+	}
+	err = cmd.Run()
+	if err != nil {
+		t.Fatalf("ERROR: record: %v", err)
+	}
+
+	cmd = exec.Command(gitAlchemistCmd, "-cfgdir", cfgDir, task)
+	if testing.Verbose() {
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+	}
+	err = cmd.Run()
+	if err != nil {
+		t.Fatalf("ERROR: run recorded formula: %v", err)
+	}
+
+	checkFormulaResult(t, accTestCase{
+		name: task,
+		gitList: []gitPara{{
+			args: []string{"log", "--graph", "--format=%an: %s"},
+			want: "*   Richard Red: merge feature\n" +
+				"|\\  \n" +
+				"| * Betty Blue: extend plan\n" +
+				"|/  \n" +
+				"* Richard Red: add plan\n",
+		}, {
+			args: []string{"show", "origin/main:plan.md"},
+			want: "# Plan\n* record\n",
+		}},
+	})
+}
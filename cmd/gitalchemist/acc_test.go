@@ -78,11 +78,10 @@ func checkFormulaResult(t *testing.T, c accTestCase) {
 	checkGit(t, filepath.Join(defaultCwd, "remotes", c.name),
 		"true\n", "rev-parse", "--is-bare-repository")
 
-	// remote repo must always be set
+	// remote repo must always be set, additional remotes are allowed
 	checkGit(t, gitDir,
-		"origin\t"+filepath.Join("..", "remotes", c.name)+" (fetch)\n"+
-			"origin\t"+filepath.Join("..", "remotes", c.name)+" (push)\n",
-		"remote", "-v")
+		filepath.Join("..", "remotes", c.name)+"\n",
+		"remote", "get-url", "origin")
 
 	// compare file content
 	for _, pair := range c.compareList {
@@ -246,6 +245,336 @@ var testCases = []accTestCase{
 			want: "clean up timeline notes\nhello world",
 		}},
 	},
+	{
+		name: "cmd_repeat",
+		compareList: []filePara{{
+			from: filepath.Join("files", "notes_2.md"),
+			to:   "notes.md",
+		}},
+		gitList: []gitPara{{
+			args: []string{"log", "--pretty=format:%an: %s"},
+			want: "Richard Red: update config\n" +
+				"Richard Red: update api\n" +
+				"Garry Green: release notes version 2\n" +
+				"Betty Blue: release notes version 1\n" +
+				"Richard Red: release notes version 0",
+		}},
+	},
+	{
+		name: "cmd_when",
+		compareList: []filePara{{
+			from: filepath.Join("files", "exercises_beginner.md"),
+			to:   "exercises.md",
+		}},
+		gitList: []gitPara{{
+			args: []string{"log", "--pretty=format:%s"},
+			want: "recent git\nbasic exercises",
+		}},
+	},
+	{
+		name: "cmd_bisect_history",
+		gitList: []gitPara{{
+			args: []string{"log", "--pretty=format:%an: %s", "-G", "^status = broken"},
+			want: "Betty Blue: update src/settings.txt (5)",
+		}, {
+			args: []string{"rev-list", "--count", "HEAD"},
+			want: "8\n",
+		}, {
+			args: []string{"show", "--pretty=", "--name-status", "HEAD~7"},
+			want: "A\tbisect_test.sh\nA\tsrc/settings.txt\n",
+		}},
+	},
+	{
+		name: "cmd_rewrite_history",
+		compareList: []filePara{{
+			from: filepath.Join("files", "readme.md"),
+			to:   "readme.md",
+		}},
+		gitList: []gitPara{{
+			args: []string{"log", "--all", "--pretty=format:%s", "-S", "hunter2"},
+			want: "",
+		}, {
+			args: []string{"log", "--all", "--pretty=format:%s", "--", "secrets.env"},
+			want: "",
+		}, {
+			args: []string{"log", "--pretty=format:%s", "-S", "${DB_PASSWORD}"},
+			want: "initial service setup",
+		}, {
+			args: []string{"ls-tree", "--name-only", "v0.1"}, // rewritten tag
+			want: "config.yaml\nreadme.md\n",
+		}, {
+			args: []string{"status", "--short", "--branch"}, // ensure push
+			want: "## main...origin/main\n",
+		}},
+	},
+	{
+		name: "cmd_clone",
+		compareList: []filePara{{
+			from: filepath.Join("files", "notes_richard.md"),
+			to:   "notes.md",
+		}},
+		gitList: []gitPara{{
+			args: []string{"log", "--pretty=format:%an: %s"},
+			want: "Richard Red: move kickoff\nRichard Red: team notes",
+		}, {
+			args: []string{"-C", filepath.Join("..", "cmd_clone_betty"),
+				"log", "--pretty=format:%an: %s"},
+			want: "Betty Blue: add retro\nRichard Red: team notes",
+		}, {
+			args: []string{"-C", filepath.Join("..", "cmd_clone_betty"),
+				"config", "user.name"},
+			want: "Betty Blue\n",
+		}, {
+			args: []string{"fetch", "--quiet"},
+			want: "",
+		}, {
+			args: []string{"status", "--short", "--branch"},
+			want: "## main...origin/main [ahead 1, behind 1]\n",
+		}},
+	},
+	{
+		name: "cmd_pull",
+		compareList: []filePara{{
+			from: filepath.Join("files", "plan.md"),
+			to:   "plan.md",
+		}, {
+			from: filepath.Join("files", "todo.md"),
+			to:   filepath.Join("..", "cmd_pull_betty", "todo.md"),
+		}},
+		gitList: []gitPara{{
+			args: []string{"log", "--pretty=format:%an: %s", "--first-parent"},
+			want: "Richard Red: Merge branch 'main' of ../remotes/cmd_pull\n" +
+				"Richard Red: add todo\n" +
+				"Richard Red: team notes",
+		}, {
+			args: []string{"-C", filepath.Join("..", "cmd_pull_betty"),
+				"status", "--short", "--branch"},
+			want: "## main...origin/main\n",
+		}},
+	},
+	{
+		name: "cmd_remote",
+		compareList: []filePara{{
+			from: filepath.Join("files", "notes.md"),
+			to:   "notes.md",
+		}},
+		gitList: []gitPara{{
+			args: []string{"remote"},
+			want: "origin\nupstream\n",
+		}, {
+			args: []string{"rev-list", "--count", "main..upstream/main"},
+			want: "1\n",
+		}, {
+			args: []string{"log", "--pretty=format:%an: %s", "upstream/main"},
+			want: "Betty Blue: add plan\n" +
+				"Richard Red: team notes",
+		}},
+	},
+	{
+		name: "cmd_server_hook",
+		compareList: []filePara{{
+			from: filepath.Join("hooks", "update.sh"),
+			to:   filepath.Join("..", "remotes", "cmd_server_hook", "hooks", "update"),
+		}},
+		gitList: []gitPara{{
+			args: []string{"status", "--short", "--branch"},
+			want: "## main...origin/main\n",
+		}, {
+			args: []string{"log", "--pretty=format:%s", "origin/main"},
+			want: "GA-1: team notes",
+		}},
+	},
+	{
+		name: "cmd_hook",
+		compareList: []filePara{{
+			from: filepath.Join("hooks", "commit-msg.sh"),
+			to:   filepath.Join(".git", "hooks", "commit-msg"),
+		}},
+		gitList: []gitPara{{
+			args: []string{"config", "--local", "pull.rebase"},
+			want: "true\n",
+		}, {
+			args: []string{"log", "--pretty=format:%s"},
+			want: "GA-12: add notes",
+		}},
+	},
+	{
+		name: "cmd_submodule",
+		compareList: []filePara{{
+			from: filepath.Join("files", "plan.md"),
+			to:   filepath.Join("lib", "plan.md"),
+		}, {
+			from: filepath.Join("files", "todo.md"),
+			to:   filepath.Join("vendor", "lib", "todo.md"),
+		}},
+		gitList: []gitPara{{
+			args: []string{"config", "-f", ".gitmodules", "submodule.lib.url"},
+			want: "../cmd_submodule_lib\n",
+		}, {
+			args: []string{"log", "--pretty=format:%s", "--first-parent"},
+			want: "update lib subtree\n" +
+				"add lib as subtree\n" +
+				"add lib as submodule\n" +
+				"team notes",
+		}, {
+			args: []string{"status", "--short", "--branch"},
+			want: "## main...origin/main\n",
+		}},
+	},
+	{
+		name: "cmd_worktree",
+		compareList: []filePara{{
+			from: filepath.Join("files", "notes.md"),
+			to:   filepath.Join("docs", "notes.md"),
+		}, {
+			from: filepath.Join("hooks", "commit-msg.sh"),
+			to:   filepath.Join(".git", "hooks", "commit-msg"),
+		}},
+		gitList: []gitPara{{
+			args: []string{"sparse-checkout", "list"},
+			want: "docs\n",
+		}, {
+			args: []string{"ls-files", "-t", "src"},
+			want: "S src/plan.md\nS src/todo.md\n",
+		}, {
+			args: []string{"log", "--pretty=format:%an: %s"},
+			want: "Betty Blue: hotfix: fix todo\n" +
+				"Richard Red: initial structure",
+		}, {
+			// the branch can only be deleted if the worktree is removed
+			args: []string{"branch", "--list", "hotfix"},
+			want: "",
+		}},
+	},
+	{
+		name: "cmd_bundle",
+		gitList: []gitPara{{
+			args: []string{"-C", filepath.Join("..", "cmd_bundle_shallow"),
+				"rev-parse", "--is-shallow-repository"},
+			want: "true\n",
+		}, {
+			args: []string{"-C", filepath.Join("..", "cmd_bundle_shallow"),
+				"branch", "--remotes"},
+			want: "  origin/HEAD -> origin/main\n  origin/main\n",
+		}, {
+			args: []string{"-C", filepath.Join("..", "cmd_bundle_partial"),
+				"config", "remote.origin.partialclonefilter"},
+			want: "blob:none\n",
+		}, {
+			args: []string{"-C", filepath.Join("..", "cmd_bundle_offline"),
+				"log", "--pretty=format:%s"},
+			want: "add todo\n" +
+				"add plan\n" +
+				"team notes",
+		}},
+	},
+	{
+		name: "cmd_signing",
+		gitList: []gitPara{{
+			args: []string{"log", "--pretty=format:%G? %an: %s"},
+			want: "N Garry Green: add todo\n" +
+				"U Betty Blue: add plan\n" +
+				"G Richard Red: team notes",
+		}, {
+			args: []string{"for-each-ref", "--format=%(objecttype) %(refname:short) %(subject)",
+				"refs/tags"},
+			want: "tag v1.0 first release\n",
+		}},
+	},
+	{
+		name: "cmd_chmod",
+		compareList: []filePara{{
+			from: filepath.Join("files", "run.sh"),
+			to:   "run.sh",
+		}},
+		gitList: []gitPara{{
+			args: []string{"ls-files", "--format=%(objectmode) %(path)"},
+			want: "100644 run.sh\n100755 tool.sh\n",
+		}, {
+			args: []string{"status", "--short"},
+			want: "",
+		}},
+	},
+	{
+		name: "cmd_generate_file",
+		gitList: []gitPara{{
+			args: []string{"cat-file", "-s", "HEAD:assets/data.bin"},
+			want: "1048576\n",
+		}, {
+			// the content is the same in every run
+			args: []string{"rev-parse", "HEAD:assets/data.bin", "HEAD:logs/app.log"},
+			want: "20b61c1238b354dde4c86cc52d81ae1d822b3957\n" +
+				"6dbf13184c6a602697c40ec03d0561e46e50e1db\n",
+		}},
+	},
+	{
+		name: "cmd_switch",
+		gitList: []gitPara{{
+			args: []string{"log", "--pretty=format:%s", "gh-pages"},
+			want: "publish docs",
+		}, {
+			args: []string{"ls-tree", "--name-only", "gh-pages"},
+			want: "index.md\n",
+		}, {
+			args: []string{"log", "--pretty=format:%s", "feature/review"},
+			want: "add plan\nteam notes",
+		}, {
+			args: []string{"status", "--short", "--branch"},
+			want: "## HEAD (no branch)\n",
+		}, {
+			args: []string{"log", "-1", "--pretty=format:%s"},
+			want: "team notes",
+		}},
+	},
+	{
+		name: "cmd_amend_notes",
+		gitList: []gitPara{{
+			args: []string{"log", "--pretty=format:%an %s"},
+			want: "Betty Blue add notes",
+		}, {
+			args: []string{"ls-tree", "--name-only", "HEAD"},
+			want: "notes.md\nplan.md\n",
+		}, {
+			args: []string{"notes", "show"},
+			want: "reviewed by green\n\nlooks good\n",
+		}, {
+			args: []string{"notes", "--ref", "review", "list"},
+			want: "",
+		}},
+	},
+	{
+		name: "cmd_commit_message",
+		gitList: []gitPara{{
+			args: []string{"log", "-1", "--skip=1", "--format=%B"},
+			want: "feat(plan): add project plan\n\nThe plan lists the next steps.\n\n" +
+				"It replaces the notes in the wiki.\n\n" +
+				"Co-authored-by: Betty Blue <betty@pw-compa.ny>\n" +
+				"Signed-off-by: Richard Red <richard@pw-compa.ny>\nFixes: #12\n\n",
+		}, {
+			args: []string{"log", "-1", "--skip=1", "--format=%(trailers:key=Fixes,valueonly)"},
+			want: "#12\n\n",
+		}, {
+			args: []string{"log", "-1", "--format=%s%n%(trailers:key=Refs)"},
+			want: "docs: add team notes\nRefs: #7\n\n",
+		}},
+	},
+	{
+		name: "cmd_line_endings",
+		gitList: []gitPara{{
+			args: []string{"ls-files", "--eol", "readme.txt", "build.bat"},
+			want: "i/lf    w/crlf  attr/text eol=crlf    \tbuild.bat\n" +
+				"i/lf    w/crlf  attr/text=auto        \treadme.txt\n",
+		}, {
+			args: []string{"show", "HEAD~2:readme.txt"},
+			want: "first line\r\nsecond line\r\n",
+		}, {
+			args: []string{"show", "HEAD:readme.txt"},
+			want: "first line\nsecond line\n",
+		}, {
+			args: []string{"config", "core.eol"},
+			want: "lf\n",
+		}},
+	},
 	{
 		name:    "error case this does not exist",
 		wantErr: "exit status 6",
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/HMS-Analytical-Software/goGitAlchemist/pkg/alchemist"
+)
+
+// exportCommand is the first argument that exports a task as script.
+const exportCommand = "export"
+
+// exportOptions represent the settings of the export command.
+type exportOptions struct {
+	format    string
+	targetdir string
+	cfgDir    string
+	task      string
+	variables map[string]string
+}
+
+// runExport writes the script of the task to the writer.
+func runExport(opt exportOptions, w io.Writer) error {
+
+	fileList, err := alchemist.ListPages(opt.cfgDir, opt.task)
+	if err != nil {
+		return err
+	}
+
+	alchemistOpt := alchemist.Options{
+		RepoDir:   opt.targetdir,
+		CfgDir:    opt.cfgDir,
+		Variables: opt.variables,
+	}
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+	export := func(f alchemist.Formula, taskOpt alchemist.Options, logger *log.Logger) error {
+		return alchemist.Export(f, taskOpt, opt.format, w, logger)
+	}
+	return runTaskList(export, fileList, alchemistOpt, logger)
+}
+
+// getExportOptions retrieves the command line options of the export
+// command. The first argument is the export command.
+func getExportOptions(args []string, getenv getEnvFunc, stderr io.Writer) (exportOptions, error) {
+
+	var f flag.FlagSet
+	f.SetOutput(stderr)
+
+	f.Usage = func() {
+		fmt.Fprintf(stderr, `usage: gitalchemist %s [-format bash|powershell] <path/to/dir>
+
+Writes the formula %q in the directory as script to stdout.
+The script needs the directory to copy the files.
+
+`, args[0], alchemist.FormulaFileName)
+		fmt.Fprintf(stderr, "usage of %s:\n", args[0])
+		f.PrintDefaults()
+	}
+
+	targetDir := getenv("GITALCHEMIST_TARGETDIR")
+	if targetDir == "" {
+		targetDir = defaultCwd
+	}
+	optFormat := f.String("format", alchemist.ExportBash, "script format, bash or powershell")
+	optTargetDir := f.String("targetdir", targetDir,
+		"default base directory for the git repos of the script (default: $GITALCHEMIST_TARGETDIR)")
+	optCfgDir := f.String("cfgdir", getenv("GITALCHEMIST_CFGDIR"),
+		"base directory for git alchemy recipes (default: $GITALCHEMIST_CFGDIR)")
+
+	variables := map[string]string{}
+	f.Func("set", "set formula variable, e.g. -set level=advanced\n"+
+		"can be used multiple times", setVariable(variables))
+
+	err := f.Parse(args[1:])
+	if err != nil {
+		return exportOptions{}, err
+	}
+
+	if f.NArg() != 1 {
+		f.Usage()
+		return exportOptions{}, fmt.Errorf("specify one task directory")
+	}
+
+	return exportOptions{
+		format:    *optFormat,
+		targetdir: *optTargetDir,
+		cfgDir:    *optCfgDir,
+		task:      f.Arg(0),
+		variables: variables,
+	}, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/HMS-Analytical-Software/goGitAlchemist/pkg/alchemist"
+)
+
+// graphCommand is the first argument that draws the history of a task.
+const graphCommand = "graph"
+
+// graphOptions represent the settings of the graph command.
+type graphOptions struct {
+	format    string
+	cfgDir    string
+	task      string
+	variables map[string]string
+}
+
+// runGraph writes the history graph of the task to the writer.
+func runGraph(opt graphOptions, w io.Writer) error {
+
+	fileList, err := alchemist.ListPages(opt.cfgDir, opt.task)
+	if err != nil {
+		return err
+	}
+
+	alchemistOpt := alchemist.Options{
+		RepoDir:   defaultCwd,
+		CfgDir:    opt.cfgDir,
+		Variables: opt.variables,
+	}
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+	graph := func(f alchemist.Formula, taskOpt alchemist.Options, logger *log.Logger) error {
+		return alchemist.Graph(f, taskOpt, opt.format, w, logger)
+	}
+	return runTaskList(graph, fileList, alchemistOpt, logger)
+}
+
+// getGraphOptions retrieves the command line options of the graph
+// command. The first argument is the graph command.
+func getGraphOptions(args []string, getenv getEnvFunc, stderr io.Writer) (graphOptions, error) {
+
+	var f flag.FlagSet
+	f.SetOutput(stderr)
+
+	f.Usage = func() {
+		fmt.Fprintf(stderr, `usage: gitalchemist %s [-format mermaid|dot] <path/to/dir>
+
+Writes the history that the formula %q in the directory creates
+as diagram to stdout. The formula is simulated, no repo is created.
+Only the clone and its worktrees are drawn, not the commits of other
+clones. A fast-forward merge is drawn as highlighted merge in Mermaid.
+
+`, args[0], alchemist.FormulaFileName)
+		fmt.Fprintf(stderr, "usage of %s:\n", args[0])
+		f.PrintDefaults()
+	}
+
+	optFormat := f.String("format", alchemist.GraphMermaid, "diagram format, mermaid or dot")
+	optCfgDir := f.String("cfgdir", getenv("GITALCHEMIST_CFGDIR"),
+		"base directory for git alchemy recipes (default: $GITALCHEMIST_CFGDIR)")
+
+	variables := map[string]string{}
+	f.Func("set", "set formula variable, e.g. -set level=advanced\n"+
+		"can be used multiple times", setVariable(variables))
+
+	err := f.Parse(args[1:])
+	if err != nil {
+		return graphOptions{}, err
+	}
+
+	if f.NArg() != 1 {
+		f.Usage()
+		return graphOptions{}, fmt.Errorf("specify one task directory")
+	}
+
+	return graphOptions{
+		format:    *optFormat,
+		cfgDir:    *optCfgDir,
+		task:      f.Arg(0),
+		variables: variables,
+	}, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/HMS-Analytical-Software/goGitAlchemist/pkg/alchemist"
+)
+
+// importCommand is the first argument that imports a repo.
+const importCommand = "import"
+
+// importOptions represent the settings of the import command.
+type importOptions struct {
+	repo    string
+	taskDir string
+}
+
+// runImport writes the history of the repo as formula into the task dir.
+func runImport(opt importOptions) error {
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+	return alchemist.Import(opt.repo, opt.taskDir, logger)
+}
+
+// getImportOptions retrieves the command line options of the import
+// command. The first argument is the import command.
+func getImportOptions(args []string, stderr io.Writer) (importOptions, error) {
+
+	var f flag.FlagSet
+	f.SetOutput(stderr)
+
+	f.Usage = func() {
+		fmt.Fprintf(stderr, `usage: gitalchemist %s <path/to/repo> <path/to/dir>
+
+Writes the commits of all local branches and the tags of the repo
+to %q in the directory, the committed files to files/.
+
+`, args[0], alchemist.FormulaFileName)
+	}
+
+	err := f.Parse(args[1:])
+	if err != nil {
+		return importOptions{}, err
+	}
+
+	if f.NArg() != 2 {
+		f.Usage()
+		return importOptions{}, fmt.Errorf("specify a repo and a task directory")
+	}
+
+	return importOptions{
+		repo:    f.Arg(0),
+		taskDir: f.Arg(1),
+	}, nil
+}
@@ -9,26 +9,64 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/HMS-Analytical-Software/goGitAlchemist/pkg/alchemist"
 )
 
 func main() {
 
-	opt, err := getOptions(os.Args, os.Getenv, os.Stderr)
-	if err != nil {
-		if err != flag.ErrHelp {
-			fmt.Fprintf(os.Stderr, "%v\n", err)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case alchemist.RecordGitCommand:
+			os.Exit(recordGit(os.Args[2:]))
+		case recordCommand:
+			opt, err := getRecordOptions(os.Args[1:], os.Getenv, os.Stderr)
+			exitOnOptionError(err)
+			exit(runRecord(opt))
+			return
+		case exportCommand:
+			opt, err := getExportOptions(os.Args[1:], os.Getenv, os.Stderr)
+			exitOnOptionError(err)
+			exit(runExport(opt, os.Stdout))
+			return
+		case graphCommand:
+			opt, err := getGraphOptions(os.Args[1:], os.Getenv, os.Stderr)
+			exitOnOptionError(err)
+			exit(runGraph(opt, os.Stdout))
+			return
+		case importCommand:
+			opt, err := getImportOptions(os.Args[1:], os.Stderr)
+			exitOnOptionError(err)
+			exit(runImport(opt))
+			return
 		}
-		os.Exit(1)
 	}
 
+	opt, err := getOptions(os.Args, os.Getenv, os.Stderr)
+	exitOnOptionError(err)
+
 	if opt.version {
 		fmt.Println(Version)
 		return
 	}
 
-	err = run(opt)
+	exit(run(opt))
+}
+
+// exitOnOptionError exits if there is an error in the command line options.
+func exitOnOptionError(err error) {
+	if err != nil {
+		if err != flag.ErrHelp {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// exit exits with a return code depending on the error type.
+// If there is no error, it just logs ok.
+func exit(err error) {
 	if err != nil {
 		log.Printf("[ERROR] %v", err)
 
@@ -62,15 +100,17 @@ func main() {
 
 // options represent the settings from the command line.
 type options struct {
-	targetdir string
-	cfgDir    string
-	verbose   bool
-	test      bool
-	taskList  []string
-	maxSteps  int
-	runAll    bool
-	clean     bool
-	version   bool
+	targetdir  string
+	cfgDir     string
+	verbose    bool
+	test       bool
+	fastImport bool
+	taskList   []string
+	maxSteps   int
+	runAll     bool
+	clean      bool
+	version    bool
+	variables  map[string]string
 }
 
 // run executes the main program and returns the error status.
@@ -87,7 +127,9 @@ func run(opt options) (err error) {
 		CfgDir:        opt.cfgDir,
 		Verbose:       opt.verbose,
 		Test:          opt.test,
+		FastImport:    opt.fastImport,
 		ExecuteSpells: opt.maxSteps,
+		Variables:     opt.variables,
 	}
 
 	var fileList []string
@@ -150,6 +192,10 @@ func getOptions(args []string, getenv getEnvFunc, stderr io.Writer) (options, er
 		fmt.Fprintf(stderr, `usage: %s <path/to/dir> [ path/to/dir ... ]]
 usage: %[1]s -runall
 usage: %[1]s -clean
+usage: %[1]s record <path/to/dir>
+usage: %[1]s import <path/to/repo> <path/to/dir>
+usage: %[1]s export [-format bash|powershell] <path/to/dir>
+usage: %[1]s graph [-format mermaid|dot] <path/to/dir>
 
 The directories must contain a definition file named %q 
 and all the files that are used in the definition.
@@ -173,10 +219,16 @@ and all the files that are used in the definition.
 
 	optVerbose := f.Bool("verbose", false, "verbose messages")
 	optTest := f.Bool("test", false, "test run, steps are logged but not executed")
+	optFastImport := f.Bool("fastimport", false, "create the history with git fast-import\n"+
+		"falls back to git commands for unsupported spells")
 	optRunAll := f.Bool("runall", false, "run all recipes")
 	optClean := f.Bool("clean", false, "remove targetdir")
 	optVersion := f.Bool("version", false, "show version")
 
+	variables := map[string]string{}
+	f.Func("set", "set formula variable, e.g. -set level=advanced\n"+
+		"can be used multiple times", setVariable(variables))
+
 	// parse command line flags
 	err := f.Parse(args[1:])
 	if err != nil {
@@ -202,18 +254,33 @@ and all the files that are used in the definition.
 	}
 
 	return options{
-		targetdir: *optTargetDir,
-		cfgDir:    *optCfgDir,
-		verbose:   *optVerbose,
-		test:      *optTest,
-		taskList:  taskList,
-		maxSteps:  *optSteps,
-		runAll:    *optRunAll,
-		clean:     *optClean,
-		version:   *optVersion,
+		targetdir:  *optTargetDir,
+		cfgDir:     *optCfgDir,
+		verbose:    *optVerbose,
+		test:       *optTest,
+		fastImport: *optFastImport,
+		taskList:   taskList,
+		maxSteps:   *optSteps,
+		runAll:     *optRunAll,
+		clean:      *optClean,
+		version:    *optVersion,
+		variables:  variables,
 	}, nil
 }
 
+// setVariable returns a flag function that sets a formula variable
+// from name=value.
+func setVariable(variables map[string]string) func(string) error {
+	return func(s string) error {
+		name, value, ok := strings.Cut(s, "=")
+		if !ok || name == "" {
+			return fmt.Errorf("invalid variable %q, use name=value", s)
+		}
+		variables[name] = value
+		return nil
+	}
+}
+
 // Version contains the git tag this binary was built with.
 // It is set during compilation on the command line.
 var Version string
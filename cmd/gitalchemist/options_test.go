@@ -4,6 +4,7 @@ package main
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/HMS-Analytical-Software/goGitAlchemist/pkg/check"
@@ -36,16 +37,21 @@ func TestGetOptions(t *testing.T) {
 			"-maxsteps", "5",
 			"-verbose",
 			"-test",
+			"-fastimport",
+			"-set", "level=advanced",
+			"-set", "seed=",
 			"task1",
 			"task2",
 		},
 		want: options{
-			targetdir: "targetdir",
-			cfgDir:    "cfgdir",
-			verbose:   true,
-			test:      true,
-			taskList:  []string{"task1", "task2"},
-			maxSteps:  5,
+			targetdir:  "targetdir",
+			cfgDir:     "cfgdir",
+			verbose:    true,
+			test:       true,
+			fastImport: true,
+			taskList:   []string{"task1", "task2"},
+			maxSteps:   5,
+			variables:  map[string]string{"level": "advanced", "seed": ""},
 		},
 	}, {
 		name: "values from environment variables",
@@ -58,6 +64,7 @@ func TestGetOptions(t *testing.T) {
 			targetdir: "target_from_env",
 			cfgDir:    "cfgdir_from_env",
 			taskList:  []string{"task1"},
+			variables: map[string]string{},
 		},
 	}, {
 		name: "flags override environment variables",
@@ -74,6 +81,7 @@ func TestGetOptions(t *testing.T) {
 			targetdir: "targetdir_flag",
 			cfgDir:    "cfgdir_flag",
 			taskList:  []string{"task1"},
+			variables: map[string]string{},
 		},
 	}, {
 		name: "runall and default cwd",
@@ -82,6 +90,7 @@ func TestGetOptions(t *testing.T) {
 			targetdir: defaultCwd,
 			runAll:    true,
 			taskList:  []string{},
+			variables: map[string]string{},
 		},
 	}, {
 		name: "clean and default cwd",
@@ -90,6 +99,7 @@ func TestGetOptions(t *testing.T) {
 			targetdir: defaultCwd,
 			clean:     true,
 			taskList:  []string{},
+			variables: map[string]string{},
 		},
 	}, {
 		name: "version",
@@ -98,7 +108,14 @@ func TestGetOptions(t *testing.T) {
 			targetdir: defaultCwd,
 			version:   true,
 			taskList:  []string{},
+			variables: map[string]string{},
 		},
+	}, {
+		name:    "invalid variable",
+		args:    []string{pgmName, "-set", "level", "task1"},
+		wantErr: `invalid value "level" for flag -set: invalid variable "level", use name=value`,
+		wantMsg: `invalid value "level" for flag -set: invalid variable "level", use name=value` +
+			"\n" + helpMessage,
 	}, {
 		name:    "task and runall",
 		args:    []string{pgmName, "-runall", "task11"},
@@ -162,6 +179,10 @@ func TestGetOptions(t *testing.T) {
 var helpMessage = `usage: gitalchemist <path/to/dir> [ path/to/dir ... ]]
 usage: gitalchemist -runall
 usage: gitalchemist -clean
+usage: gitalchemist record <path/to/dir>
+usage: gitalchemist import <path/to/repo> <path/to/dir>
+usage: gitalchemist export [-format bash|powershell] <path/to/dir>
+usage: gitalchemist graph [-format mermaid|dot] <path/to/dir>
 
 The directories must contain a definition file named "gitalchemist.yaml" 
 and all the files that are used in the definition.
@@ -171,11 +192,17 @@ usage of gitalchemist:
     	base directory for git alchemy recipes (default: $GITALCHEMIST_CFGDIR)
   -clean
     	remove targetdir
+  -fastimport
+    	create the history with git fast-import
+    	falls back to git commands for unsupported spells
   -maxsteps int
     	execute only number of specified steps
     	0 executes all steps
   -runall
     	run all recipes
+  -set value
+    	set formula variable, e.g. -set level=advanced
+    	can be used multiple times
   -targetdir string
     	base directory for generated git repos (default: $GITALCHEMIST_TARGETDIR) (default "cwd")
   -test
@@ -185,3 +212,245 @@ usage of gitalchemist:
   -version
     	show version
 `
+
+func TestGetRecordOptions(t *testing.T) {
+
+	testCases := []struct {
+		name    string
+		args    []string
+		setenv  map[string]string
+		want    recordOptions
+		wantMsg string
+		wantErr string
+	}{{
+		name: "all options",
+		args: []string{recordCommand, "-shell", "bash", "task1"},
+		want: recordOptions{taskDir: "task1", shell: "bash"},
+	}, {
+		name:   "default shell",
+		args:   []string{recordCommand, "task1"},
+		setenv: map[string]string{"SHELL": "zsh", "COMSPEC": "zsh"},
+		want:   recordOptions{taskDir: "task1", shell: "zsh"},
+	}, {
+		name:    "task missing",
+		args:    []string{recordCommand},
+		wantErr: "specify one task directory",
+		wantMsg: recordHelpMessage,
+	}, {
+		name:    "two tasks",
+		args:    []string{recordCommand, "task1", "task2"},
+		wantErr: "specify one task directory",
+		wantMsg: recordHelpMessage,
+	}}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+
+			var buf bytes.Buffer
+			getenv := func(name string) string {
+				return c.setenv[name]
+			}
+
+			gotOpt, err := getRecordOptions(c.args, getenv, &buf)
+
+			// check terminal messages, the default shell depends on the os
+			if c.wantMsg != "" && !strings.HasPrefix(buf.String(), c.wantMsg) {
+				t.Errorf("ERROR: got %q, want prefix %q", buf.String(), c.wantMsg)
+			}
+
+			check.ErrorString(t, err, c.wantErr)
+
+			if diff := cmp.Diff(gotOpt, c.want,
+				cmp.AllowUnexported(recordOptions{}),
+			); diff != "" {
+				t.Errorf("ERROR: got- want+: %s", diff)
+			}
+		})
+	}
+}
+
+var recordHelpMessage = `usage: gitalchemist record [-shell shell] <path/to/dir>
+
+Starts a shell in the clone of a new repo. All git commands executed
+in the clone are recorded. When the shell exits, they are written
+to "gitalchemist.yaml" in the directory, the committed files to files/.
+
+usage of record:
+  -shell string
+    	shell of the record session`
+
+func TestGetImportOptions(t *testing.T) {
+
+	testCases := []struct {
+		name    string
+		args    []string
+		want    importOptions
+		wantMsg string
+		wantErr string
+	}{{
+		name: "repo and task",
+		args: []string{importCommand, "../repo", "task1"},
+		want: importOptions{repo: "../repo", taskDir: "task1"},
+	}, {
+		name:    "task missing",
+		args:    []string{importCommand, "../repo"},
+		wantErr: "specify a repo and a task directory",
+		wantMsg: importHelpMessage,
+	}, {
+		name:    "unknown flag",
+		args:    []string{importCommand, "-unknown", "../repo", "task1"},
+		wantErr: "flag provided but not defined: -unknown",
+		wantMsg: "flag provided but not defined: -unknown\n" + importHelpMessage,
+	}}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+
+			var buf bytes.Buffer
+			gotOpt, err := getImportOptions(c.args, &buf)
+
+			if diff := cmp.Diff(buf.String(), c.wantMsg); diff != "" {
+				t.Errorf("ERROR: got- want+: %s", diff)
+			}
+
+			check.ErrorString(t, err, c.wantErr)
+
+			if diff := cmp.Diff(gotOpt, c.want,
+				cmp.AllowUnexported(importOptions{}),
+			); diff != "" {
+				t.Errorf("ERROR: got- want+: %s", diff)
+			}
+		})
+	}
+}
+
+var importHelpMessage = `usage: gitalchemist import <path/to/repo> <path/to/dir>
+
+Writes the commits of all local branches and the tags of the repo
+to "gitalchemist.yaml" in the directory, the committed files to files/.
+
+`
+
+func TestGetExportOptions(t *testing.T) {
+
+	testCases := []struct {
+		name    string
+		args    []string
+		setenv  map[string]string
+		want    exportOptions
+		wantMsg string
+		wantErr string
+	}{{
+		name: "all options",
+		args: []string{exportCommand, "-format", "powershell", "-targetdir", "repos",
+			"-cfgdir", "tasks", "-set", "level=advanced", "task1"},
+		want: exportOptions{format: "powershell", targetdir: "repos", cfgDir: "tasks",
+			task: "task1", variables: map[string]string{"level": "advanced"}},
+	}, {
+		name:   "defaults",
+		args:   []string{exportCommand, "task1"},
+		setenv: map[string]string{"GITALCHEMIST_CFGDIR": "tasks"},
+		want: exportOptions{format: "bash", targetdir: defaultCwd, cfgDir: "tasks",
+			task: "task1", variables: map[string]string{}},
+	}, {
+		name:    "two tasks",
+		args:    []string{exportCommand, "task1", "task2"},
+		wantErr: "specify one task directory",
+		wantMsg: exportHelpMessage,
+	}}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+
+			var buf bytes.Buffer
+			getenv := func(name string) string {
+				return c.setenv[name]
+			}
+
+			gotOpt, err := getExportOptions(c.args, getenv, &buf)
+
+			if c.wantMsg != "" && !strings.HasPrefix(buf.String(), c.wantMsg) {
+				t.Errorf("ERROR: got %q, want prefix %q", buf.String(), c.wantMsg)
+			}
+
+			check.ErrorString(t, err, c.wantErr)
+
+			if diff := cmp.Diff(gotOpt, c.want,
+				cmp.AllowUnexported(exportOptions{}),
+			); diff != "" {
+				t.Errorf("ERROR: got- want+: %s", diff)
+			}
+		})
+	}
+}
+
+var exportHelpMessage = `usage: gitalchemist export [-format bash|powershell] <path/to/dir>
+
+Writes the formula "gitalchemist.yaml" in the directory as script to stdout.
+The script needs the directory to copy the files.
+
+usage of export:
+`
+
+func TestGetGraphOptions(t *testing.T) {
+
+	testCases := []struct {
+		name    string
+		args    []string
+		setenv  map[string]string
+		want    graphOptions
+		wantMsg string
+		wantErr string
+	}{{
+		name: "all options",
+		args: []string{graphCommand, "-format", "dot", "-cfgdir", "tasks",
+			"-set", "level=advanced", "task1"},
+		want: graphOptions{format: "dot", cfgDir: "tasks", task: "task1",
+			variables: map[string]string{"level": "advanced"}},
+	}, {
+		name:   "defaults",
+		args:   []string{graphCommand, "task1"},
+		setenv: map[string]string{"GITALCHEMIST_CFGDIR": "tasks"},
+		want: graphOptions{format: "mermaid", cfgDir: "tasks", task: "task1",
+			variables: map[string]string{}},
+	}, {
+		name:    "no task",
+		args:    []string{graphCommand},
+		wantErr: "specify one task directory",
+		wantMsg: graphHelpMessage,
+	}}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+
+			var buf bytes.Buffer
+			getenv := func(name string) string {
+				return c.setenv[name]
+			}
+
+			gotOpt, err := getGraphOptions(c.args, getenv, &buf)
+
+			if c.wantMsg != "" && !strings.HasPrefix(buf.String(), c.wantMsg) {
+				t.Errorf("ERROR: got %q, want prefix %q", buf.String(), c.wantMsg)
+			}
+
+			check.ErrorString(t, err, c.wantErr)
+
+			if diff := cmp.Diff(gotOpt, c.want,
+				cmp.AllowUnexported(graphOptions{}),
+			); diff != "" {
+				t.Errorf("ERROR: got- want+: %s", diff)
+			}
+		})
+	}
+}
+
+var graphHelpMessage = `usage: gitalchemist graph [-format mermaid|dot] <path/to/dir>
+
+Writes the history that the formula "gitalchemist.yaml" in the directory creates
+as diagram to stdout. The formula is simulated, no repo is created.
+Only the clone and its worktrees are drawn, not the commits of other
+clones. A fast-forward merge is drawn as highlighted merge in Mermaid.
+
+usage of graph:
+`
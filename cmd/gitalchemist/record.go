@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+
+	"github.com/HMS-Analytical-Software/goGitAlchemist/pkg/alchemist"
+)
+
+// recordCommand is the first argument that starts a record session.
+const recordCommand = "record"
+
+// recordOptions represent the settings of the record command.
+type recordOptions struct {
+	taskDir string
+	shell   string
+}
+
+// runRecord starts a shell in the clone of a record session.
+// When the shell exits, the recorded git commands are written
+// as formula into the task dir.
+func runRecord(opt recordOptions) (err error) {
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+	rec, err := alchemist.NewRecording(opt.taskDir, exe, logger)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeErr := rec.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}()
+
+	logger.Printf("[INFO] recording git commands in %s", rec.CloneDir())
+	logger.Printf("[INFO] exit the shell to write the formula to %s", opt.taskDir)
+
+	shell := exec.Command(opt.shell)
+	shell.Dir = rec.CloneDir()
+	shell.Stdin, shell.Stdout, shell.Stderr = os.Stdin, os.Stdout, os.Stderr
+	shell.Env = append(os.Environ(), rec.Environ()...)
+
+	// the exit code of the shell is the one of the last command
+	err = shell.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return alchemist.ExecError{Cmd: opt.shell, Err: err}
+	}
+
+	return rec.Write()
+}
+
+// recordGit runs the git shim of a record session and returns the
+// exit code.
+func recordGit(args []string) int {
+	code, err := alchemist.RecordGit(args, os.Stdin, os.Stdout, os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gitalchemist %s: %v\n", alchemist.RecordGitCommand, err)
+	}
+	return code
+}
+
+// getRecordOptions retrieves the command line options of the record
+// command. The first argument is the record command.
+func getRecordOptions(args []string, getenv getEnvFunc, stderr io.Writer) (recordOptions, error) {
+
+	var f flag.FlagSet
+	f.SetOutput(stderr)
+
+	f.Usage = func() {
+		fmt.Fprintf(stderr, `usage: gitalchemist %s [-shell shell] <path/to/dir>
+
+Starts a shell in the clone of a new repo. All git commands executed
+in the clone are recorded. When the shell exits, they are written
+to %q in the directory, the committed files to files/.
+
+`, args[0], alchemist.FormulaFileName)
+		fmt.Fprintf(stderr, "usage of %s:\n", args[0])
+		f.PrintDefaults()
+	}
+
+	optShell := f.String("shell", defaultShell(getenv), "shell of the record session")
+
+	err := f.Parse(args[1:])
+	if err != nil {
+		return recordOptions{}, err
+	}
+
+	if f.NArg() != 1 {
+		f.Usage()
+		return recordOptions{}, fmt.Errorf("specify one task directory")
+	}
+
+	return recordOptions{
+		taskDir: f.Arg(0),
+		shell:   *optShell,
+	}, nil
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"log"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/HMS-Analytical-Software/goGitAlchemist/pkg/alchemist"
@@ -135,3 +136,30 @@ func TestRunTaskList(t *testing.T) {
 		})
 	}
 }
+
+// TestRunTestMode tests that test mode does not skip a spell whose
+// condition needs the git version, the real run casts it.
+func TestRunTestMode(t *testing.T) {
+
+	var out strings.Builder
+	opt := alchemist.Options{CfgDir: testDataDir, RepoDir: t.TempDir(), Test: true}
+	err := runOneTask(alchemist.Transmute,
+		filepath.Join(testDataDir, "cmd_when", alchemist.FormulaFileName),
+		opt, log.New(&out, "", 0))
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+
+	for _, want := range []string{
+		"[INFO] 2/4: copy",
+		"[INFO] 3/4: skip, condition is false: level == \"advanced\"",
+		"[INFO] 4/4: condition can not be evaluated, value of git_version is not known: git_version >= 2.20",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("ERROR: log does not contain %q\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "skip, condition is false: git_version") {
+		t.Errorf("ERROR: spell with git version skipped\n%s", out.String())
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+
+package main
+
+// defaultShell returns the shell of the user for the record session.
+func defaultShell(getenv getEnvFunc) string {
+	shell := getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+	return shell
+}
@@ -0,0 +1,11 @@
+package main
+
+// defaultShell returns the command interpreter for the record session
+// on windows.
+func defaultShell(getenv getEnvFunc) string {
+	shell := getenv("COMSPEC")
+	if shell == "" {
+		shell = "cmd.exe"
+	}
+	return shell
+}
@@ -0,0 +1,10 @@
+#!/bin/sh
+# commit-msg hook: adds the ticket of the branch to the commit message,
+# e.g. branch feature/GA-12-notes and message "add notes" results in
+# "GA-12: add notes"
+branch=$(git symbolic-ref --short HEAD)
+ticket=$(echo "$branch" | sed -n 's/^[a-z]*\/\([A-Z][A-Z]*-[0-9][0-9]*\).*/\1/p')
+if [ -n "$ticket" ] && ! grep -q "^$ticket: " "$1"; then
+    sed "1s/^/$ticket: /" "$1" > "$1.tmp" && mv "$1.tmp" "$1"
+fi
+exit 0
@@ -0,0 +1,33 @@
+#!/bin/sh
+# update hook: rejects force pushes to main and commit messages
+# that do not start with a ticket, e.g. "GA-12: add notes"
+refname="$1"
+oldrev="$2"
+newrev="$3"
+zero=0000000000000000000000000000000000000000
+
+if [ "$refname" = "refs/heads/main" ] && [ "$oldrev" != "$zero" ]; then
+    if [ "$(git merge-base "$oldrev" "$newrev")" != "$oldrev" ]; then
+        echo "*** force push to main is not allowed"
+        exit 1
+    fi
+fi
+
+if [ "$oldrev" = "$zero" ]; then
+    range="$newrev"
+else
+    range="$oldrev..$newrev"
+fi
+
+for commit in $(git rev-list "$range"); do
+    subject=$(git log -1 --format=%s "$commit")
+    case "$subject" in
+        [A-Z]*-[0-9]*:*) ;;
+        *)
+            echo "*** commit $commit: message must start with a ticket, e.g. GA-12: ..."
+            exit 1
+            ;;
+    esac
+done
+
+exit 0
@@ -0,0 +1,6 @@
+#!/bin/sh
+# commit-msg hook: adds the branch to the commit message,
+# e.g. branch hotfix and message "fix todo" results in "hotfix: fix todo"
+branch=$(git symbolic-ref --short HEAD)
+sed "1s/^/$branch: /" "$1" > "$1.tmp" && mv "$1.tmp" "$1"
+exit 0
@@ -0,0 +1,14 @@
+#!/bin/sh
+# session.sh simulates an instructor's shell session for the
+# acceptance test of the record command.
+set -e
+printf '# Plan\n' > plan.md
+git add plan.md
+git commit --quiet -m "add plan"
+git switch --quiet --create feature
+printf '* record\n' >> plan.md
+git commit --quiet --all -m "extend plan" --author="Betty Blue <betty@pw-compa.ny>"
+git status
+git switch --quiet main
+git merge --quiet --no-ff -m "merge feature" feature
+git push --quiet origin main
@@ -36,6 +36,7 @@ func (a adept) git(dir string, args ...string) error {
 
 	cmd := exec.Command(a.exe, args...)
 	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), gitEnvironment...)
 
 	output, err := cmd.CombinedOutput()
 	if len(output) > 0 {
@@ -52,6 +53,23 @@ func (a adept) git(dir string, args ...string) error {
 	return nil
 }
 
+// gitOutput executes the git command in the provided directory
+// and returns the standard output.
+// The directory must exist.
+func (a adept) gitOutput(dir string, args ...string) (string, error) {
+	_, _ = a.novice.gitOutput(dir, args...)
+
+	cmd := exec.Command(a.exe, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), gitEnvironment...)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", ExecError{Cmd: gitCmd, Args: args, Err: err}
+	}
+	return string(output), nil
+}
+
 // makedir creates the target dir and all missing directories on the path.
 func (a adept) makedir(dir string) error {
 	a.novice.makedir(dir)
@@ -63,6 +81,83 @@ func (a adept) makedir(dir string) error {
 	return nil
 }
 
+// writeFile writes the data to the file and sets the mode.
+// Missing directories of the file path are created.
+func (a adept) writeFile(name string, data []byte, mode fs.FileMode) error {
+	_ = a.novice.writeFile(name, data, mode)
+
+	err := os.MkdirAll(filepath.Dir(name), dirMode)
+	if err != nil {
+		return IOError{Cmd: "make dir", Arg: filepath.Dir(name), Err: err}
+	}
+
+	err = os.WriteFile(name, data, mode)
+	if err != nil {
+		return IOError{Cmd: "write", Arg: name, Err: err}
+	}
+
+	// the mode of an existing file is not changed by WriteFile
+	err = os.Chmod(name, mode)
+	if err != nil {
+		return IOError{Cmd: "chmod", Arg: name, Err: err}
+	}
+
+	return nil
+}
+
+// readFile returns the content of the file.
+func (a adept) readFile(name string) ([]byte, error) {
+	_, _ = a.novice.readFile(name)
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, IOError{Cmd: "read", Arg: name, Err: err}
+	}
+
+	return data, nil
+}
+
+// chmod changes the mode of the file.
+func (a adept) chmod(name string, mode fs.FileMode) error {
+	_ = a.novice.chmod(name, mode)
+
+	err := os.Chmod(name, mode)
+	if err != nil {
+		return IOError{Cmd: "chmod", Arg: name, Err: err}
+	}
+
+	return nil
+}
+
+// keygen creates an ed25519 key pair without passphrase with ssh-keygen.
+// An existing key is reused. It returns the public key.
+func (a adept) keygen(file, comment string) (string, error) {
+	_, _ = a.novice.keygen(file, comment)
+
+	_, err := os.Stat(file)
+	if err != nil {
+		err = os.MkdirAll(filepath.Dir(file), dirMode)
+		if err != nil {
+			return "", IOError{Cmd: "make dir", Arg: filepath.Dir(file), Err: err}
+		}
+
+		args := []string{"-q", "-t", "ed25519", "-N", "", "-C", comment, "-f", file}
+		output, err := exec.Command(sshKeygenCmd, args...).CombinedOutput()
+		if len(output) > 0 {
+			a.debug("%s", strings.TrimSpace(string(output)))
+		}
+		if err != nil {
+			return "", ExecError{Cmd: sshKeygenCmd, Args: args, Err: err}
+		}
+	}
+
+	public, err := os.ReadFile(file + ".pub")
+	if err != nil {
+		return "", IOError{Cmd: "read", Arg: file + ".pub", Err: err}
+	}
+	return strings.TrimSpace(string(public)), nil
+}
+
 // copy copies the file to the target.
 // The target directory must exist.
 func (a adept) copy(from, to string) error {
@@ -112,15 +207,33 @@ func (a adept) copy(from, to string) error {
 
 // copyFile copies the file to the target.
 // The target directory must exist.
+// The permission bits are preserved, symbolic links are copied as links.
+// A symbolic link at the target is replaced, not followed.
 func (a adept) copyFile(from, to string) error {
 
+	info, err := os.Lstat(from)
+	if err != nil {
+		return IOError{Cmd: "stat", Arg: from, Err: err}
+	}
+	if info.Mode()&fs.ModeSymlink != 0 {
+		return a.copySymlink(from, to)
+	}
+
 	srcFile, err := os.Open(from)
 	if err != nil {
 		return IOError{Cmd: "open", Arg: from, Err: err}
 	}
 	defer srcFile.Close()
 
-	targetFile, err := os.Create(to)
+	target, err := os.Lstat(to)
+	if err == nil && target.Mode()&fs.ModeSymlink != 0 {
+		err = os.Remove(to)
+		if err != nil {
+			return IOError{Cmd: "remove", Arg: to, Err: err}
+		}
+	}
+
+	targetFile, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
 	if err != nil {
 		return IOError{Cmd: "create", Arg: to, Err: err}
 	}
@@ -131,6 +244,34 @@ func (a adept) copyFile(from, to string) error {
 		return IOError{Cmd: "copy", Arg: from + " - " + to, Err: err}
 	}
 
+	// the mode of an existing file is not changed by OpenFile
+	err = os.Chmod(to, info.Mode().Perm())
+	if err != nil {
+		return IOError{Cmd: "chmod", Arg: to, Err: err}
+	}
+
+	return nil
+}
+
+// copySymlink creates a symbolic link with the same destination
+// as the link from. An existing target is replaced.
+func (a adept) copySymlink(from, to string) error {
+
+	dest, err := os.Readlink(from)
+	if err != nil {
+		return IOError{Cmd: "read link", Arg: from, Err: err}
+	}
+
+	err = os.Remove(to)
+	if err != nil && !os.IsNotExist(err) {
+		return IOError{Cmd: "remove", Arg: to, Err: err}
+	}
+
+	err = os.Symlink(dest, to)
+	if err != nil {
+		return IOError{Cmd: "symlink", Arg: from + " - " + to, Err: err}
+	}
+
 	return nil
 }
 
@@ -145,7 +286,7 @@ func (a adept) copyFile(from, to string) error {
 // The source is determined with the following logic.
 //
 //   - If from does not exist, it returns an error.
-//   - If from is a file, then recursive will be false.
+//   - If from is a file or a symbolic link, then recursive will be false.
 //   - If from is a directory, then recursive will be true.
 //
 // The target is determined with the following logic.
@@ -165,7 +306,7 @@ func (a adept) copyFile(from, to string) error {
 // If a directory to the target should be created, dir contains the name.
 func examine(from, to string) (target, dir string, recursive bool, err error) {
 
-	fromInfo, err := os.Stat(from)
+	fromInfo, err := os.Lstat(from)
 	if err != nil {
 		return "", "", false, IOError{Cmd: "stat", Arg: from, Err: err}
 	}
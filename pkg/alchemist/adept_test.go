@@ -2,8 +2,10 @@ package alchemist
 
 import (
 	"io"
+	"io/fs"
 	"log"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -61,6 +63,147 @@ func TestAdeptMakeDir(t *testing.T) {
 	}
 }
 
+// TestAdeptWriteFile tests writing a file in testdata.
+func TestAdeptWriteFile(t *testing.T) {
+
+	helper := newAdept(log.New(io.Discard, "", 0), Options{})
+	baseDir := filepath.Join(TestDataDir, "write")
+
+	testCases := []struct {
+		name    string
+		file    string
+		mode    fs.FileMode
+		wantErr error
+	}{{
+		name: "ok",
+		file: filepath.Join(baseDir, "dir", toName),
+		mode: 0644,
+	}, {
+		name: "executable",
+		file: filepath.Join(baseDir, toName),
+		mode: 0755,
+	}, {
+		name:    "target is a directory",
+		file:    baseDir,
+		mode:    0644,
+		wantErr: IOError{Cmd: "write", Arg: baseDir},
+	}}
+
+	err := os.RemoveAll(baseDir)
+	if err != nil {
+		t.Fatalf("ERROR: test setup failed: %v", err)
+	}
+	if !testing.Verbose() {
+		defer os.RemoveAll(baseDir)
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+
+			err = helper.writeFile(c.file, []byte(srcFileContent), c.mode)
+			check.Error(t, err, c.wantErr,
+				cmpopts.IgnoreFields(IOError{}, "Err"))
+
+			got, err := os.ReadFile(c.file)
+			if err != nil {
+				t.Fatalf("ERROR: got error: %v", err)
+			}
+			if string(got) != srcFileContent {
+				t.Errorf("ERROR: got %q, want %q", got, srcFileContent)
+			}
+			checkMode(t, c.file, c.mode)
+		})
+	}
+}
+
+// TestAdeptReadFile tests reading a file in testdata.
+func TestAdeptReadFile(t *testing.T) {
+
+	helper := newAdept(log.New(io.Discard, "", 0), Options{})
+
+	got, err := helper.readFile(filepath.Join(TestDataDir, fromName))
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+	if want := srcFileContent + "\n"; string(got) != want {
+		t.Errorf("ERROR: got %q, want %q", got, want)
+	}
+
+	missing := filepath.Join(TestDataDir, notExistName)
+	_, err = helper.readFile(missing)
+	check.Error(t, err, IOError{Cmd: "read", Arg: missing},
+		cmpopts.IgnoreFields(IOError{}, "Err"))
+}
+
+// TestAdeptChmod tests changing the mode of a file in testdata.
+func TestAdeptChmod(t *testing.T) {
+
+	helper := newAdept(log.New(io.Discard, "", 0), Options{})
+	baseDir := filepath.Join(TestDataDir, "chmod")
+	file := filepath.Join(baseDir, toName)
+
+	err := os.RemoveAll(baseDir)
+	if err != nil {
+		t.Fatalf("ERROR: test setup failed: %v", err)
+	}
+	if !testing.Verbose() {
+		defer os.RemoveAll(baseDir)
+	}
+	err = helper.writeFile(file, []byte(srcFileContent), 0644)
+	if err != nil {
+		t.Fatalf("ERROR: test setup failed: %v", err)
+	}
+
+	err = helper.chmod(file, 0755)
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+	checkMode(t, file, 0755)
+
+	missing := filepath.Join(baseDir, notExistName)
+	err = helper.chmod(missing, 0755)
+	check.Error(t, err, IOError{Cmd: "chmod", Arg: missing},
+		cmpopts.IgnoreFields(IOError{}, "Err"))
+}
+
+// TestAdeptKeygen tests the creation of ssh keys in testdata.
+func TestAdeptKeygen(t *testing.T) {
+
+	_, err := exec.LookPath(sshKeygenCmd)
+	if err != nil {
+		t.Skipf("%s not found", sshKeygenCmd)
+	}
+
+	helper := newAdept(log.New(io.Discard, "", 0), Options{})
+	baseDir := filepath.Join(TestDataDir, "keys")
+	file := filepath.Join(baseDir, "red")
+
+	err = os.RemoveAll(baseDir)
+	if err != nil {
+		t.Fatalf("ERROR: test setup failed: %v", err)
+	}
+	if !testing.Verbose() {
+		defer os.RemoveAll(baseDir)
+	}
+
+	public, err := helper.keygen(file, email["red"])
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+	if !strings.HasPrefix(public, "ssh-ed25519 ") || !strings.HasSuffix(public, email["red"]) {
+		t.Errorf("ERROR: unexpected public key %q", public)
+	}
+
+	// an existing key is reused
+	again, err := helper.keygen(file, email["red"])
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+	if again != public {
+		t.Errorf("ERROR: got %q, want %q", again, public)
+	}
+}
+
 // TestAdeptCopyFileError tests the error behavior of copyFile.
 // The behavior of copy is tested in TestAdeptCopyMove.
 func TestAdeptCopyFileError(t *testing.T) {
@@ -74,7 +217,7 @@ func TestAdeptCopyFileError(t *testing.T) {
 	}{{
 		name:    "source not found",
 		from:    notExistName,
-		wantErr: IOError{Cmd: "open", Arg: notExistName},
+		wantErr: IOError{Cmd: "stat", Arg: notExistName},
 	}, {
 		name: "target not usable",
 		from: filepath.Join(TestDataDir, fromName),
@@ -254,6 +397,70 @@ func validateFileCopy(t *testing.T, c struct {
 	}
 }
 
+// TestAdeptCopyModeAndSymlink tests that copy preserves the
+// permission bits and symbolic links, also in directories.
+func TestAdeptCopyModeAndSymlink(t *testing.T) {
+
+	helper := newAdept(log.New(io.Discard, "", 0), Options{})
+	from, to := filepath.Join(t.TempDir(), "from"), filepath.Join(t.TempDir(), "to")
+
+	script := filepath.Join(from, "run.sh")
+	err := helper.writeFile(script, []byte(srcFileContent), 0755)
+	if err != nil {
+		t.Fatalf("ERROR: test setup failed: %v", err)
+	}
+	err = os.Symlink("run.sh", filepath.Join(from, "link.sh"))
+	if err != nil {
+		t.Skipf("symbolic links not supported: %v", err)
+	}
+
+	// copy a single file over an existing file
+	err = helper.writeFile(filepath.Join(to, "single.sh"), nil, 0644)
+	if err != nil {
+		t.Fatalf("ERROR: test setup failed: %v", err)
+	}
+	err = helper.copy(script, filepath.Join(to, "single.sh"))
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+	checkMode(t, filepath.Join(to, "single.sh"), 0755)
+
+	// copy a file over a link, the target of the link is not changed
+	outside := filepath.Join(t.TempDir(), "outside.txt")
+	err = helper.writeFile(outside, []byte("outside"), 0644)
+	if err != nil {
+		t.Fatalf("ERROR: test setup failed: %v", err)
+	}
+	err = os.Symlink(outside, filepath.Join(to, "linked.sh"))
+	if err != nil {
+		t.Fatalf("ERROR: test setup failed: %v", err)
+	}
+	err = helper.copy(script, filepath.Join(to, "linked.sh"))
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+	checkMode(t, filepath.Join(to, "linked.sh"), 0755)
+	content, err := os.ReadFile(outside)
+	if err != nil || string(content) != "outside" {
+		t.Errorf("ERROR: got %q, %v, want unchanged target of the link", content, err)
+	}
+
+	// copy a directory
+	err = helper.copy(from, to)
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+	checkMode(t, filepath.Join(to, "from", "run.sh"), 0755)
+
+	dest, err := os.Readlink(filepath.Join(to, "from", "link.sh"))
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+	if dest != "run.sh" {
+		t.Errorf("ERROR: got link to %q, want %q", dest, "run.sh")
+	}
+}
+
 func TestAdeptDirCopy(t *testing.T) {
 
 	newPage := "new_page"
@@ -1,13 +1,27 @@
 package alchemist
 
+import "io/fs"
+
 // assistant define the ability to execute low-level commands.
 type assistant interface {
 	// git execute a git command in the provided directory
 	git(dir string, args ...string) error
+	// gitOutput executes a git command in the provided directory
+	// and returns its output
+	gitOutput(dir string, args ...string) (string, error)
 	// copy copies a file
 	copy(from, to string) error
 	// makedir creates a directory
 	makedir(dir string) error
+	// readFile returns the content of a file
+	readFile(name string) ([]byte, error)
+	// writeFile writes the data to a file with the provided mode
+	writeFile(name string, data []byte, mode fs.FileMode) error
+	// chmod changes the mode of a file
+	chmod(name string, mode fs.FileMode) error
+	// keygen creates an ssh key pair unless it exists and returns
+	// the public key
+	keygen(file, comment string) (string, error)
 
 	// debug emits a debug message
 	debug(msg string, args ...any)
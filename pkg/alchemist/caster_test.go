@@ -3,6 +3,8 @@ package alchemist
 import (
 	"errors"
 	"testing"
+
+	"gopkg.in/yaml.v3"
 )
 
 // TestCasterValidate tests the validate function of all casters.
@@ -23,6 +25,13 @@ func TestCasterValidate(t *testing.T) {
 		name:    "initRepoSpell clone_to missing",
 		spell:   initRepoSpell{Bare: "x"},
 		wantErr: MissingValueError("clone_to"),
+	}, {
+		name:  "initRepoSpell fork ok",
+		spell: initRepoSpell{Bare: "x", CloneTo: "y", ForkOf: "z", Remote: "fork"},
+	}, {
+		name:    "initRepoSpell fork itself",
+		spell:   initRepoSpell{Bare: "x", CloneTo: "y", ForkOf: "x"},
+		wantErr: InvalidValueError{Variable: "fork_of", Reason: "can not fork itself"},
 	}, {
 		name:    "initRepoSpell both missing",
 		spell:   initRepoSpell{},
@@ -34,6 +43,10 @@ func TestCasterValidate(t *testing.T) {
 		name:    "createFileSpell source missing",
 		spell:   createFileSpell{Target: "y"},
 		wantErr: MissingValueError("source"),
+	}, {
+		name:    "createFileSpell unknown eol",
+		spell:   createFileSpell{Source: "x", Target: "y", EOL: "cr"},
+		wantErr: InvalidValueError{Variable: "eol", Reason: "unknown line ending cr"},
 	}, {
 		name:    "createFileSpell target missing",
 		spell:   createFileSpell{Source: "x"},
@@ -60,9 +73,42 @@ func TestCasterValidate(t *testing.T) {
 		name:    "commitSpell author missing",
 		spell:   commitSpell{Message: "x"},
 		wantErr: MissingValueError("author"),
+	}, {
+		name:  "commitSpell message file ok",
+		spell: commitSpell{MessageFile: "x", Author: "y", Trailers: []string{"Fixes: #1"}},
+	}, {
+		name:    "commitSpell message file and message",
+		spell:   commitSpell{MessageFile: "x", Message: "x", Author: "y"},
+		wantErr: InvalidValueError{Variable: "message_file", Reason: "can not be used with message or body"},
+	}, {
+		name:    "commitSpell body without message",
+		spell:   commitSpell{Body: "x", Author: "y", Amend: true},
+		wantErr: InvalidValueError{Variable: "body", Reason: "can only be used with message"},
+	}, {
+		name:    "commitSpell invalid trailer",
+		spell:   commitSpell{Message: "x", Author: "y", Trailers: []string{"Fixes #1"}},
+		wantErr: InvalidValueError{Variable: "trailers", Reason: "invalid trailer Fixes #1, want 'key: value'"},
+	}, {
+		name:  "commitSpell amend ok",
+		spell: commitSpell{Amend: true, Files: []string{"x"}},
+	}, {
+		name:    "commitSpell files without amend",
+		spell:   commitSpell{Message: "x", Author: "y", Files: []string{"x"}},
+		wantErr: InvalidValueError{Variable: "files", Reason: "can only be used with amend"},
+	}, {
+		name:  "commitSpell sign ok",
+		spell: commitSpell{Message: "x", Author: "red", Sign: true},
+	}, {
+		name:    "commitSpell sign unknown author",
+		spell:   commitSpell{Message: "x", Author: "y", Sign: true},
+		wantErr: InvalidValueError{Variable: "author", Reason: "unknown author y can not sign"},
 	}, {
 		name:  "createAddCommitSpell ok",
 		spell: createAddCommitSpell{Files: []string{"x=>a"}, Message: "y", Author: "z"},
+	}, {
+		name:    "createAddCommitSpell unknown eol",
+		spell:   createAddCommitSpell{Files: []string{"x=>a"}, Message: "y", Author: "z", EOL: "mac"},
+		wantErr: InvalidValueError{Variable: "eol", Reason: "unknown line ending mac"},
 	}, {
 		name:    "createAddCommitSpell separator missing",
 		spell:   createAddCommitSpell{Files: []string{"x-a"}, Message: "y", Author: "z"},
@@ -138,6 +184,449 @@ func TestCasterValidate(t *testing.T) {
 		name:    "removeAndCommitSpell author missing",
 		spell:   removeAndCommitSpell{Files: []string{"x"}, Message: "y"},
 		wantErr: MissingValueError("author"),
+	}, {
+		name:  "bisectHistorySpell ok",
+		spell: bisectHistorySpell{File: "x", Commits: 5, RegressionAt: 3, Author: "y"},
+	}, {
+		name:  "bisectHistorySpell seed ok",
+		spell: bisectHistorySpell{File: "x", Commits: 5, Seed: 42, Author: "y"},
+	}, {
+		name:    "bisectHistorySpell file missing",
+		spell:   bisectHistorySpell{Commits: 5, Author: "y"},
+		wantErr: MissingValueError("file"),
+	}, {
+		name:    "bisectHistorySpell too few commits",
+		spell:   bisectHistorySpell{File: "x", Commits: 1, Author: "y"},
+		wantErr: InvalidValueError{Variable: "commits", Reason: "at least 2 commits are needed"},
+	}, {
+		name:  "bisectHistorySpell regression at first commit",
+		spell: bisectHistorySpell{File: "x", Commits: 5, RegressionAt: 1, Author: "y"},
+		wantErr: InvalidValueError{Variable: "regression_at",
+			Reason: "must be between 2 and 5"},
+	}, {
+		name:  "bisectHistorySpell regression after last commit",
+		spell: bisectHistorySpell{File: "x", Commits: 5, RegressionAt: 6, Author: "y"},
+		wantErr: InvalidValueError{Variable: "regression_at",
+			Reason: "must be between 2 and 5"},
+	}, {
+		name: "bisectHistorySpell regression and seed",
+		spell: bisectHistorySpell{File: "x", Commits: 5, RegressionAt: 2, Seed: 1,
+			Author: "y"},
+		wantErr: InvalidValueError{Variable: "regression_at",
+			Reason: "can not be combined with seed"},
+	}, {
+		name:    "bisectHistorySpell author missing",
+		spell:   bisectHistorySpell{File: "x", Commits: 5},
+		wantErr: MissingValueError("author"),
+	}, {
+		name:  "rewriteHistorySpell remove ok",
+		spell: rewriteHistorySpell{Branches: []string{"x"}, RemovePath: "y"},
+	}, {
+		name:  "rewriteHistorySpell replace ok",
+		spell: rewriteHistorySpell{Branches: []string{"x"}, Replace: replacement{From: "y"}},
+	}, {
+		name:    "rewriteHistorySpell branches missing",
+		spell:   rewriteHistorySpell{RemovePath: "y"},
+		wantErr: MissingValueError("branches"),
+	}, {
+		name:    "rewriteHistorySpell remove and replace missing",
+		spell:   rewriteHistorySpell{Branches: []string{"x"}},
+		wantErr: MissingValueError("remove_path or replace"),
+	}, {
+		name: "rewriteHistorySpell replace from missing",
+		spell: rewriteHistorySpell{Branches: []string{"x"}, RemovePath: "y",
+			Replace: replacement{To: "z"}},
+		wantErr: MissingValueError("replace.from"),
+	}, {
+		name:  "cloneSpell ok",
+		spell: cloneSpell{Bare: "x", CloneTo: "y", User: "blue"},
+	}, {
+		name:  "cloneSpell default user",
+		spell: cloneSpell{Bare: "x", CloneTo: "y"},
+	}, {
+		name:    "cloneSpell bare missing",
+		spell:   cloneSpell{CloneTo: "y"},
+		wantErr: MissingValueError("bare or bundle"),
+	}, {
+		name:  "cloneSpell partial ok",
+		spell: cloneSpell{Bare: "x", CloneTo: "y", Depth: 1, Filter: "blob:none", SingleBranch: true},
+	}, {
+		name:  "cloneSpell bundle ok",
+		spell: cloneSpell{Bundle: "x.bundle", CloneTo: "y", Depth: 1},
+	}, {
+		name:    "cloneSpell bare and bundle",
+		spell:   cloneSpell{Bare: "x", Bundle: "x.bundle", CloneTo: "y"},
+		wantErr: InvalidValueError{Variable: "bare", Reason: "can not be combined with bundle"},
+	}, {
+		name:    "cloneSpell negative depth",
+		spell:   cloneSpell{Bare: "x", CloneTo: "y", Depth: -1},
+		wantErr: InvalidValueError{Variable: "depth", Reason: "must not be negative"},
+	}, {
+		name:    "cloneSpell bundle with filter",
+		spell:   cloneSpell{Bundle: "x.bundle", CloneTo: "y", Filter: "blob:none"},
+		wantErr: InvalidValueError{Variable: "filter", Reason: "can not be used with bundle"},
+	}, {
+		name:    "cloneSpell clone_to missing",
+		spell:   cloneSpell{Bare: "x"},
+		wantErr: MissingValueError("clone_to"),
+	}, {
+		name:    "cloneSpell unknown user",
+		spell:   cloneSpell{Bare: "x", CloneTo: "y", User: "skywalker"},
+		wantErr: InvalidValueError{Variable: "user", Reason: "unknown user skywalker"},
+	}, {
+		name:  "fetchSpell ok",
+		spell: fetchSpell{},
+	}, {
+		name:  "pullSpell ok",
+		spell: pullSpell{Remote: "x", Branch: "y", Rebase: true},
+	}, {
+		name:    "pullSpell rebase and ff_only",
+		spell:   pullSpell{Rebase: true, FFOnly: true},
+		wantErr: InvalidValueError{Variable: "rebase", Reason: "can not be combined with ff_only"},
+	}, {
+		name:  "remoteSpell add ok",
+		spell: remoteSpell{Name: "x", Bare: "y"},
+	}, {
+		name:  "remoteSpell set-url ok",
+		spell: remoteSpell{Action: "set-url", Name: "x", URL: "y"},
+	}, {
+		name:  "remoteSpell rename ok",
+		spell: remoteSpell{Action: "rename", Name: "x", NewName: "y"},
+	}, {
+		name:  "remoteSpell remove ok",
+		spell: remoteSpell{Action: "remove", Name: "x"},
+	}, {
+		name:    "remoteSpell name missing",
+		spell:   remoteSpell{Bare: "y"},
+		wantErr: MissingValueError("name"),
+	}, {
+		name:    "remoteSpell url missing",
+		spell:   remoteSpell{Name: "x"},
+		wantErr: MissingValueError("bare or url"),
+	}, {
+		name:    "remoteSpell bare and url",
+		spell:   remoteSpell{Name: "x", Bare: "y", URL: "z"},
+		wantErr: InvalidValueError{Variable: "bare", Reason: "can not be combined with url"},
+	}, {
+		name:    "remoteSpell new name missing",
+		spell:   remoteSpell{Action: "rename", Name: "x"},
+		wantErr: MissingValueError("new_name"),
+	}, {
+		name:    "remoteSpell unknown action",
+		spell:   remoteSpell{Action: "prune", Name: "x"},
+		wantErr: InvalidValueError{Variable: "action", Reason: "unknown action prune"},
+	}, {
+		name:  "serverHookSpell ok",
+		spell: serverHookSpell{Bare: "x", Hook: "pre-receive", Source: "y"},
+	}, {
+		name:    "serverHookSpell bare missing",
+		spell:   serverHookSpell{Hook: "update", Source: "y"},
+		wantErr: MissingValueError("bare"),
+	}, {
+		name:    "serverHookSpell hook missing",
+		spell:   serverHookSpell{Bare: "x", Source: "y"},
+		wantErr: MissingValueError("hook"),
+	}, {
+		name:    "serverHookSpell unknown hook",
+		spell:   serverHookSpell{Bare: "x", Hook: "pre-commit", Source: "y"},
+		wantErr: InvalidValueError{Variable: "hook", Reason: "unknown server hook pre-commit"},
+	}, {
+		name:    "serverHookSpell source missing",
+		spell:   serverHookSpell{Bare: "x", Hook: "update"},
+		wantErr: MissingValueError("source"),
+	}, {
+		name:  "hookSpell ok",
+		spell: hookSpell{Hook: "commit-msg", Source: "y"},
+	}, {
+		name:    "hookSpell hook missing",
+		spell:   hookSpell{Source: "y"},
+		wantErr: MissingValueError("hook"),
+	}, {
+		name:    "hookSpell unknown hook",
+		spell:   hookSpell{Hook: "pre-receive", Source: "y"},
+		wantErr: InvalidValueError{Variable: "hook", Reason: "unknown client hook pre-receive"},
+	}, {
+		name:    "hookSpell source missing",
+		spell:   hookSpell{Hook: "pre-commit"},
+		wantErr: MissingValueError("source"),
+	}, {
+		name:  "configSpell ok",
+		spell: configSpell{Key: "pull.rebase", Value: "true"},
+	}, {
+		name:  "configSpell unset ok",
+		spell: configSpell{Key: "pull.rebase", Unset: true},
+	}, {
+		name:    "configSpell key missing",
+		spell:   configSpell{Value: "true"},
+		wantErr: MissingValueError("key"),
+	}, {
+		name:    "configSpell key without section",
+		spell:   configSpell{Key: "rebase", Value: "true"},
+		wantErr: InvalidValueError{Variable: "key", Reason: "must be section.name"},
+	}, {
+		name:    "configSpell unset with value",
+		spell:   configSpell{Key: "pull.rebase", Value: "true", Unset: true},
+		wantErr: InvalidValueError{Variable: "unset", Reason: "can not be combined with value"},
+	}, {
+		name:  "submoduleAddSpell ok",
+		spell: submoduleAddSpell{Bare: "x", Path: "y", Message: "m", Author: "red"},
+	}, {
+		name:    "submoduleAddSpell bare missing",
+		spell:   submoduleAddSpell{Path: "y", Message: "m", Author: "red"},
+		wantErr: MissingValueError("bare"),
+	}, {
+		name:    "submoduleAddSpell path missing",
+		spell:   submoduleAddSpell{Bare: "x", Message: "m", Author: "red"},
+		wantErr: MissingValueError("path"),
+	}, {
+		name:    "submoduleAddSpell author missing",
+		spell:   submoduleAddSpell{Bare: "x", Path: "y", Message: "m"},
+		wantErr: MissingValueError("author"),
+	}, {
+		name:  "subtreeAddSpell ok",
+		spell: subtreeAddSpell{Bare: "x", Prefix: "y"},
+	}, {
+		name:    "subtreeAddSpell prefix missing",
+		spell:   subtreeAddSpell{Bare: "x"},
+		wantErr: MissingValueError("prefix"),
+	}, {
+		name:    "subtreePullSpell bare missing",
+		spell:   subtreePullSpell{Prefix: "y"},
+		wantErr: MissingValueError("bare"),
+	}, {
+		name:  "worktreeSpell add ok",
+		spell: worktreeSpell{Path: "x", Branch: "y", Create: true, Base: "main"},
+	}, {
+		name:  "worktreeSpell remove ok",
+		spell: worktreeSpell{Action: "remove", Path: "x", Force: true},
+	}, {
+		name:    "worktreeSpell path missing",
+		spell:   worktreeSpell{Branch: "y"},
+		wantErr: MissingValueError("path"),
+	}, {
+		name:    "worktreeSpell create without branch",
+		spell:   worktreeSpell{Path: "x", Create: true},
+		wantErr: MissingValueError("branch"),
+	}, {
+		name:    "worktreeSpell base without create",
+		spell:   worktreeSpell{Path: "x", Branch: "y", Base: "main"},
+		wantErr: InvalidValueError{Variable: "base", Reason: "can only be used with create"},
+	}, {
+		name:  "worktreeSpell remove with branch",
+		spell: worktreeSpell{Action: "remove", Path: "x", Branch: "y"},
+		wantErr: InvalidValueError{Variable: "action",
+			Reason: "remove can not be combined with branch, create, or base"},
+	}, {
+		name:    "worktreeSpell unknown action",
+		spell:   worktreeSpell{Action: "move", Path: "x"},
+		wantErr: InvalidValueError{Variable: "action", Reason: "unknown action move"},
+	}, {
+		name:  "sparseCheckoutSpell ok",
+		spell: sparseCheckoutSpell{Patterns: []string{"docs"}},
+	}, {
+		name:  "sparseCheckoutSpell disable ok",
+		spell: sparseCheckoutSpell{Action: "disable"},
+	}, {
+		name:    "sparseCheckoutSpell patterns missing",
+		spell:   sparseCheckoutSpell{Action: "add"},
+		wantErr: MissingValueError("patterns"),
+	}, {
+		name:    "sparseCheckoutSpell disable with patterns",
+		spell:   sparseCheckoutSpell{Action: "disable", Patterns: []string{"docs"}},
+		wantErr: InvalidValueError{Variable: "patterns", Reason: "can not be used with disable"},
+	}, {
+		name:    "sparseCheckoutSpell unknown action",
+		spell:   sparseCheckoutSpell{Action: "init"},
+		wantErr: InvalidValueError{Variable: "action", Reason: "unknown action init"},
+	}, {
+		name:  "bundleSpell ok",
+		spell: bundleSpell{File: "x.bundle", Refs: []string{"main"}},
+	}, {
+		name:    "bundleSpell file missing",
+		spell:   bundleSpell{},
+		wantErr: MissingValueError("file"),
+	}, {
+		name:  "signingKeysSpell ok",
+		spell: signingKeysSpell{Authors: []string{"red", "blue"}, Trusted: []string{"red"}},
+	}, {
+		name:    "signingKeysSpell authors missing",
+		spell:   signingKeysSpell{},
+		wantErr: MissingValueError("authors"),
+	}, {
+		name:    "signingKeysSpell unknown author",
+		spell:   signingKeysSpell{Authors: []string{"red", "skywalker"}},
+		wantErr: InvalidValueError{Variable: "authors", Reason: "unknown author skywalker"},
+	}, {
+		name:    "signingKeysSpell trusted without key",
+		spell:   signingKeysSpell{Authors: []string{"red"}, Trusted: []string{"blue"}},
+		wantErr: InvalidValueError{Variable: "trusted", Reason: "no key for blue"},
+	}, {
+		name:  "tagSpell ok",
+		spell: tagSpell{Name: "v1.0"},
+	}, {
+		name:  "tagSpell signed ok",
+		spell: tagSpell{Name: "v1.0", Message: "release", Author: "red", Sign: true},
+	}, {
+		name:    "tagSpell name missing",
+		spell:   tagSpell{Message: "release"},
+		wantErr: MissingValueError("name"),
+	}, {
+		name:    "tagSpell signed without message",
+		spell:   tagSpell{Name: "v1.0", Author: "red", Sign: true},
+		wantErr: InvalidValueError{Variable: "sign", Reason: "signed tags need a message"},
+	}, {
+		name:    "tagSpell signed without author",
+		spell:   tagSpell{Name: "v1.0", Message: "release", Sign: true},
+		wantErr: InvalidValueError{Variable: "author", Reason: "unknown author  can not sign"},
+	}, {
+		name:  "chmodSpell ok",
+		spell: chmodSpell{Files: []string{"x"}, Mode: "+x", Stage: true},
+	}, {
+		name:    "chmodSpell files missing",
+		spell:   chmodSpell{Mode: "-x"},
+		wantErr: MissingValueError("files"),
+	}, {
+		name:    "chmodSpell mode missing",
+		spell:   chmodSpell{Files: []string{"x"}},
+		wantErr: MissingValueError("mode"),
+	}, {
+		name:    "chmodSpell invalid mode",
+		spell:   chmodSpell{Files: []string{"x"}, Mode: "755"},
+		wantErr: InvalidValueError{Variable: "mode", Reason: "must be +x or -x"},
+	}, {
+		name:  "generateFileSpell binary ok",
+		spell: generateFileSpell{File: "x.bin", Size: "10MiB", Seed: 1},
+	}, {
+		name:  "generateFileSpell text ok",
+		spell: generateFileSpell{File: "x.txt", Kind: "text", Lines: 100},
+	}, {
+		name:    "generateFileSpell file missing",
+		spell:   generateFileSpell{Size: "1KB"},
+		wantErr: MissingValueError("file"),
+	}, {
+		name:    "generateFileSpell binary size missing",
+		spell:   generateFileSpell{File: "x.bin"},
+		wantErr: MissingValueError("size"),
+	}, {
+		name:    "generateFileSpell binary with lines",
+		spell:   generateFileSpell{File: "x.bin", Size: "1KB", Lines: 10},
+		wantErr: InvalidValueError{Variable: "lines", Reason: "can only be used with text"},
+	}, {
+		name:    "generateFileSpell text size and lines",
+		spell:   generateFileSpell{File: "x.txt", Kind: "text", Size: "1KB", Lines: 10},
+		wantErr: InvalidValueError{Variable: "size", Reason: "can not be combined with lines"},
+	}, {
+		name:    "generateFileSpell text size missing",
+		spell:   generateFileSpell{File: "x.txt", Kind: "text"},
+		wantErr: MissingValueError("size or lines"),
+	}, {
+		name:    "generateFileSpell invalid size",
+		spell:   generateFileSpell{File: "x.bin", Size: "ten MB"},
+		wantErr: InvalidValueError{Variable: "size", Reason: "invalid size ten MB"},
+	}, {
+		name:    "generateFileSpell size too big",
+		spell:   generateFileSpell{File: "x.bin", Size: "2GiB"},
+		wantErr: InvalidValueError{Variable: "size", Reason: "must not exceed 1GiB"},
+	}, {
+		name:    "generateFileSpell unknown kind",
+		spell:   generateFileSpell{File: "x", Kind: "image", Size: "1KB"},
+		wantErr: InvalidValueError{Variable: "kind", Reason: "unknown kind image"},
+	}, {
+		name:  "switchSpell ok",
+		spell: switchSpell{Branch: "x", Create: true, Base: "main"},
+	}, {
+		name:  "switchSpell detach ok",
+		spell: switchSpell{Detach: "v1.0"},
+	}, {
+		name:    "switchSpell branch missing",
+		spell:   switchSpell{Create: true},
+		wantErr: MissingValueError("branch or detach"),
+	}, {
+		name:    "switchSpell branch and detach",
+		spell:   switchSpell{Branch: "x", Detach: "v1.0"},
+		wantErr: InvalidValueError{Variable: "detach", Reason: "can not be combined with branch"},
+	}, {
+		name:    "switchSpell create and orphan",
+		spell:   switchSpell{Branch: "x", Create: true, Orphan: true},
+		wantErr: InvalidValueError{Variable: "orphan", Reason: "can not be combined with create"},
+	}, {
+		name:    "switchSpell base without create",
+		spell:   switchSpell{Branch: "x", Base: "main"},
+		wantErr: InvalidValueError{Variable: "base", Reason: "can only be used with create"},
+	}, {
+		name:  "renormalizeSpell ok",
+		spell: renormalizeSpell{},
+	}, {
+		name:  "notesSpell ok",
+		spell: notesSpell{Message: "x"},
+	}, {
+		name:  "notesSpell remove ok",
+		spell: notesSpell{Action: "remove", Commit: "HEAD~1"},
+	}, {
+		name:    "notesSpell message missing",
+		spell:   notesSpell{Action: "append"},
+		wantErr: MissingValueError("message"),
+	}, {
+		name:    "notesSpell remove with message",
+		spell:   notesSpell{Action: "remove", Message: "x"},
+		wantErr: InvalidValueError{Variable: "message", Reason: "can not be used with remove"},
+	}, {
+		name:    "notesSpell unknown action",
+		spell:   notesSpell{Action: "copy", Message: "x"},
+		wantErr: InvalidValueError{Variable: "action", Reason: "unknown action copy"},
+	}}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+
+			err := c.spell.validate()
+
+			if c.wantErr != nil {
+				if !errors.Is(err, c.wantErr) {
+					t.Errorf("ERROR: got: %v, want: %v", err, c.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("ERROR: got error: %v", err)
+			}
+		})
+	}
+}
+
+// TestRepeatValidate tests the validate function of the repeat spell.
+// It is not a caster, because it is expanded when the formula is read.
+func TestRepeatValidate(t *testing.T) {
+
+	body := yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{{}}}
+
+	testCases := []struct {
+		name    string
+		spell   repeatSpell
+		wantErr error
+	}{{
+		name:  "count ok",
+		spell: repeatSpell{Count: 2, Body: body},
+	}, {
+		name:  "over ok",
+		spell: repeatSpell{Over: []string{"x"}, Body: body},
+	}, {
+		name:    "count and over missing",
+		spell:   repeatSpell{Body: body},
+		wantErr: MissingValueError("count or over"),
+	}, {
+		name:    "count and over",
+		spell:   repeatSpell{Count: 1, Over: []string{"x"}, Body: body},
+		wantErr: InvalidValueError{Variable: "count", Reason: "can not be combined with over"},
+	}, {
+		name:    "negative count",
+		spell:   repeatSpell{Count: -1, Body: body},
+		wantErr: InvalidValueError{Variable: "count", Reason: "must not be negative"},
+	}, {
+		name:    "body missing",
+		spell:   repeatSpell{Count: 1},
+		wantErr: MissingValueError("body"),
 	}}
 
 	for _, c := range testCases {
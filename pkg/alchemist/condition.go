@@ -0,0 +1,378 @@
+package alchemist
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// condition is a parsed when expression.
+//
+// The grammar of the expression is:
+//
+//	or         = and { "||" and }
+//	and        = not { "&&" not }
+//	not        = "!" not | comparison
+//	comparison = operand [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" ) operand ]
+//	operand    = identifier | string | number | "(" or ")"
+//
+// Identifiers are the names of formula variables or gitVersionVariable.
+// Strings are enclosed in double or single quotes.
+// Values that look like versions (e.g. 2.38.1) are compared numerically,
+// all other values are compared as strings.
+// An operand without comparison is true unless it is empty, "false" or "0".
+type condition interface {
+	eval(lookup lookupFunc) (string, error)
+}
+
+// lookupFunc returns the value of a variable.
+type lookupFunc func(name string) (string, error)
+
+// gitVersionVariable is the name of the variable that contains the
+// version of the git executable, e.g. "2.39.5".
+const gitVersionVariable = "git_version"
+
+// values of boolean results.
+const (
+	trueValue  = "true"
+	falseValue = "false"
+)
+
+// parseCondition parses the expression.
+// It returns an error if the expression is not valid.
+func parseCondition(expr string) (condition, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, InvalidValueError{Variable: "when", Reason: err.Error()}
+	}
+
+	p := conditionParser{tokens: tokens}
+	result, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos].value)
+	}
+	if err != nil {
+		return nil, InvalidValueError{Variable: "when", Reason: err.Error()}
+	}
+
+	return result, nil
+}
+
+// isTrue evaluates the condition and reports if it is true.
+func isTrue(c condition, lookup lookupFunc) (bool, error) {
+	value, err := c.eval(lookup)
+	if err != nil {
+		return false, err
+	}
+	return truthy(value), nil
+}
+
+// truthy reports if a value is considered true.
+func truthy(value string) bool {
+	return value != "" && value != falseValue && value != "0"
+}
+
+// boolValue converts a bool to its string representation.
+func boolValue(b bool) string {
+	if b {
+		return trueValue
+	}
+	return falseValue
+}
+
+// token kinds of a when expression.
+const (
+	tokenIdent = iota
+	tokenLiteral
+	tokenOperator
+)
+
+// token is a single element of a when expression.
+type token struct {
+	kind  int
+	value string
+}
+
+// operators lists the operators, two character operators first.
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")"}
+
+// tokenize splits the expression into tokens. The expression is read
+// rune by rune, so values can contain any unicode letter.
+func tokenize(expr string) ([]token, error) {
+
+	var tokens []token
+	rest := expr
+	for {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if rest == "" {
+			return tokens, nil
+		}
+
+		switch r, size := utf8.DecodeRuneInString(rest); {
+		case r == '"' || r == '\'':
+			end := strings.IndexRune(rest[size:], r)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string %s", rest)
+			}
+			tokens = append(tokens, token{kind: tokenLiteral, value: rest[size : size+end]})
+			rest = rest[2*size+end:]
+			continue
+		case r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			end := strings.IndexFunc(rest, func(r rune) bool {
+				return r != '_' && r != '.' && r != '-' &&
+					!unicode.IsLetter(r) && !unicode.IsDigit(r)
+			})
+			if end < 0 {
+				end = len(rest)
+			}
+			kind := tokenIdent
+			if unicode.IsDigit(r) {
+				kind = tokenLiteral
+			}
+			tokens = append(tokens, token{kind: kind, value: rest[:end]})
+			rest = rest[end:]
+			continue
+		}
+
+		found := false
+		for _, op := range operators {
+			if strings.HasPrefix(rest, op) {
+				tokens = append(tokens, token{kind: tokenOperator, value: op})
+				rest = rest[len(op):]
+				found = true
+				break
+			}
+		}
+		if !found {
+			r, _ := utf8.DecodeRuneInString(rest)
+			return nil, fmt.Errorf("unexpected character %q", r)
+		}
+	}
+}
+
+// conditionParser is a recursive descent parser for when expressions.
+type conditionParser struct {
+	tokens []token
+	pos    int
+}
+
+// accept consumes the next token if it is one of the operators.
+func (p *conditionParser) accept(ops ...string) (string, bool) {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenOperator {
+		return "", false
+	}
+	for _, op := range ops {
+		if p.tokens[p.pos].value == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+// parseOr parses operands combined with ||.
+func (p *conditionParser) parseOr() (condition, error) {
+	left, err := p.parseAnd()
+	for err == nil {
+		if _, ok := p.accept("||"); !ok {
+			return left, nil
+		}
+		var right condition
+		right, err = p.parseAnd()
+		left = binaryCondition{op: "||", left: left, right: right}
+	}
+	return nil, err
+}
+
+// parseAnd parses operands combined with &&, they bind stronger than ||.
+func (p *conditionParser) parseAnd() (condition, error) {
+	left, err := p.parseNot()
+	for err == nil {
+		if _, ok := p.accept("&&"); !ok {
+			return left, nil
+		}
+		var right condition
+		right, err = p.parseNot()
+		left = binaryCondition{op: "&&", left: left, right: right}
+	}
+	return nil, err
+}
+
+// parseNot parses an optionally negated comparison.
+func (p *conditionParser) parseNot() (condition, error) {
+	if _, ok := p.accept("!"); ok {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notCondition{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+// parseComparison parses an operand or the comparison of two operands.
+func (p *conditionParser) parseComparison() (condition, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.accept("==", "!=", "<=", ">=", "<", ">")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return binaryCondition{op: op, left: left, right: right}, nil
+}
+
+// parseOperand parses a variable, a literal, or an expression in
+// parentheses.
+func (p *conditionParser) parseOperand() (condition, error) {
+	if _, ok := p.accept("("); ok {
+		result, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.accept(")"); !ok {
+			return nil, fmt.Errorf("missing ')'")
+		}
+		return result, nil
+	}
+
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	t := p.tokens[p.pos]
+	switch t.kind {
+	case tokenIdent:
+		p.pos++
+		return variableCondition(t.value), nil
+	case tokenLiteral:
+		p.pos++
+		return literalCondition(t.value), nil
+	}
+	return nil, fmt.Errorf("unexpected %q", t.value)
+}
+
+// literalCondition is a string or number.
+type literalCondition string
+
+// eval returns the literal.
+func (c literalCondition) eval(lookupFunc) (string, error) {
+	return string(c), nil
+}
+
+// variableCondition is the name of a variable.
+type variableCondition string
+
+// eval returns the value of the variable.
+func (c variableCondition) eval(lookup lookupFunc) (string, error) {
+	return lookup(string(c))
+}
+
+// notCondition negates its operand.
+type notCondition struct {
+	operand condition
+}
+
+// eval returns true if the operand is not true.
+func (c notCondition) eval(lookup lookupFunc) (string, error) {
+	ok, err := isTrue(c.operand, lookup)
+	if err != nil {
+		return "", err
+	}
+	return boolValue(!ok), nil
+}
+
+// binaryCondition combines or compares two operands.
+type binaryCondition struct {
+	op          string
+	left, right condition
+}
+
+// eval returns the result of the operator, && and || are evaluated
+// from left to right and stop when the result is known.
+func (c binaryCondition) eval(lookup lookupFunc) (string, error) {
+	left, err := c.left.eval(lookup)
+	if err != nil {
+		return "", err
+	}
+
+	// short circuit evaluation
+	switch {
+	case c.op == "&&" && !truthy(left):
+		return falseValue, nil
+	case c.op == "||" && truthy(left):
+		return trueValue, nil
+	}
+
+	right, err := c.right.eval(lookup)
+	if err != nil {
+		return "", err
+	}
+
+	switch c.op {
+	case "&&", "||":
+		return boolValue(truthy(right)), nil
+	case "==":
+		return boolValue(compareValues(left, right) == 0), nil
+	case "!=":
+		return boolValue(compareValues(left, right) != 0), nil
+	case "<":
+		return boolValue(compareValues(left, right) < 0), nil
+	case "<=":
+		return boolValue(compareValues(left, right) <= 0), nil
+	case ">":
+		return boolValue(compareValues(left, right) > 0), nil
+	case ">=":
+		return boolValue(compareValues(left, right) >= 0), nil
+	}
+	return "", fmt.Errorf("unknown operator %q", c.op)
+}
+
+// regexpVersion matches numbers and versions like 2.38.1
+var regexpVersion = regexp.MustCompile(`^\d+(\.\d+)*$`)
+
+// compareValues compares two values. Versions are compared numerically,
+// other values as strings.
+func compareValues(a, b string) int {
+	if !regexpVersion.MatchString(a) || !regexpVersion.MatchString(b) {
+		return strings.Compare(a, b)
+	}
+
+	partsA, partsB := strings.Split(a, "."), strings.Split(b, ".")
+	for i := range max(len(partsA), len(partsB)) {
+		var numA, numB int
+		if i < len(partsA) {
+			numA, _ = strconv.Atoi(partsA[i])
+		}
+		if i < len(partsB) {
+			numB, _ = strconv.Atoi(partsB[i])
+		}
+		if numA != numB {
+			if numA < numB {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// parseGitVersion extracts the version from the output of 'git version',
+// e.g. "git version 2.39.5.windows.1" returns "2.39.5".
+func parseGitVersion(output string) string {
+	fields := strings.Fields(output)
+	if len(fields) < 3 {
+		return ""
+	}
+	return regexpVersionPrefix.FindString(fields[2])
+}
+
+// regexpVersionPrefix matches the numeric part of a version.
+var regexpVersionPrefix = regexp.MustCompile(`^\d+(\.\d+)*`)
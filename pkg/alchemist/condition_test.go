@@ -0,0 +1,122 @@
+package alchemist
+
+import (
+	"testing"
+
+	"github.com/HMS-Analytical-Software/goGitAlchemist/pkg/check"
+)
+
+// TestCondition tests parsing and evaluating when expressions.
+func TestCondition(t *testing.T) {
+
+	vars := map[string]string{
+		"level": "advanced",
+		"empty": "",
+		"count": "10",
+		"größe": "groß",
+		"stufe": "Übung",
+	}
+	lookup := Options{Variables: vars}.lookup(&assistantSpy{output: "git version 2.39.5.windows.1\n"})
+
+	testCases := []struct {
+		name    string
+		expr    string
+		want    bool
+		wantErr string
+	}{{
+		name: "equal",
+		expr: `level == "advanced"`,
+		want: true,
+	}, {
+		name: "equal single quotes",
+		expr: `level == 'beginner'`,
+		want: false,
+	}, {
+		name: "not equal",
+		expr: `level != "beginner"`,
+		want: true,
+	}, {
+		name: "variable only",
+		expr: `level`,
+		want: true,
+	}, {
+		name: "empty variable",
+		expr: `empty`,
+		want: false,
+	}, {
+		name: "not",
+		expr: `!empty`,
+		want: true,
+	}, {
+		name: "and or",
+		expr: `level == "beginner" || empty == "" && count > 9`,
+		want: true,
+	}, {
+		name: "parentheses",
+		expr: `(level == "beginner" || empty == "") && count > 10`,
+		want: false,
+	}, {
+		name: "numeric compare",
+		expr: `count >= 9`,
+		want: true,
+	}, {
+		name: "git version",
+		expr: `git_version >= 2.38 && git_version < "2.40"`,
+		want: true,
+	}, {
+		name: "git version patch level",
+		expr: `git_version <= 2.39.4`,
+		want: false,
+	}, {
+		name: "short circuit",
+		expr: `level == "advanced" || unknown`,
+		want: true,
+	}, {
+		name: "unicode",
+		expr: `größe == "groß" || stufe != 'Übung'`,
+		want: true,
+	}, {
+		name:    "unknown variable",
+		expr:    `unknown == "x"`,
+		wantErr: "value for unknown: unknown variable",
+	}, {
+		name:    "unterminated string",
+		expr:    `level == "advanced`,
+		wantErr: `value for when: unterminated string "advanced`,
+	}, {
+		name:    "missing parenthesis",
+		expr:    `(level == "advanced"`,
+		wantErr: "value for when: missing ')'",
+	}, {
+		name:    "missing operand",
+		expr:    `level ==`,
+		wantErr: "value for when: unexpected end of expression",
+	}, {
+		name:    "trailing token",
+		expr:    `level "x"`,
+		wantErr: `value for when: unexpected "x"`,
+	}, {
+		name:    "invalid character",
+		expr:    `level = "x"`,
+		wantErr: `value for when: unexpected character '='`,
+	}, {
+		name:    "invalid unicode character",
+		expr:    `level == €`,
+		wantErr: `value for when: unexpected character '€'`,
+	}}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+
+			cond, err := parseCondition(c.expr)
+			if err == nil {
+				var got bool
+				got, err = isTrue(cond, lookup)
+				if got != c.want {
+					t.Errorf("ERROR: got: %v, want: %v", got, c.want)
+				}
+			}
+			check.ErrorString(t, err, c.wantErr)
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package alchemist
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
 
 // MissingValueError signals a missing value in the recipe definition.
 type MissingValueError string
@@ -10,6 +13,15 @@ func (e MissingValueError) Error() string {
 	return "value for " + string(e) + " is missing"
 }
 
+// UnknownValueError signals a value that is not known without
+// executing git, e.g. the git version in test mode.
+type UnknownValueError string
+
+// Error implents the error interface.
+func (e UnknownValueError) Error() string {
+	return "value of " + string(e) + " is not known"
+}
+
 // InvalidValueError signals an invalid value in the recipe definition.
 type InvalidValueError struct {
 	Variable, Reason string
@@ -86,3 +98,24 @@ func (e IOError) Error() string {
 func (e IOError) Unwrap() error {
 	return e.Err
 }
+
+// SpellError signals an error that happend while a spell was cast.
+// It keeps the number and the symbol of the spell and the underlying
+// error.
+type SpellError struct {
+	Spell  int
+	Symbol string
+	Err    error
+}
+
+// Error returns the symbol and the number of the spell and the message
+// of the underlying error.
+// It implents the error interface.
+func (e SpellError) Error() string {
+	return fmt.Sprintf("%s (%d): %v", e.Symbol, e.Spell, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e SpellError) Unwrap() error {
+	return e.Err
+}
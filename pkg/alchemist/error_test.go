@@ -22,6 +22,10 @@ func TestErrorMessage(t *testing.T) {
 		name: "MissingValueError",
 		err:  MissingValueError("x"),
 		want: "value for x is missing",
+	}, {
+		name: "UnknownValueError",
+		err:  UnknownValueError("x"),
+		want: "value of x is not known",
 	}, {
 		name: "YamlDecodeError",
 		err:  YamlDecodeError{Element: "node", Err: elementErr},
@@ -54,6 +58,14 @@ func TestErrorMessage(t *testing.T) {
 		name: "Unwrap IOError",
 		err:  IOError{Cmd: "open", Arg: "file", Err: execErr}.Unwrap(),
 		want: execErrMsg,
+	}, {
+		name: "SpellError",
+		err:  SpellError{Spell: 3, Symbol: "merge", Err: execErr},
+		want: "merge (3): " + execErrMsg,
+	}, {
+		name: "Unwrap SpellError",
+		err:  SpellError{Spell: 3, Symbol: "merge", Err: execErr}.Unwrap(),
+		want: execErrMsg,
 	}}
 
 	for _, c := range testCases {
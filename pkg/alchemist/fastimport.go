@@ -0,0 +1,1277 @@
+package alchemist
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"maps"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// UnsupportedError signals a command that can not be cast with
+// git fast-import. Transmute casts the formula with git instead.
+type UnsupportedError string
+
+// Error implents the error interface.
+func (e UnsupportedError) Error() string {
+	return "not supported by fast import: " + string(e)
+}
+
+// fastImport casts the formula with a fastImporter and imports the
+// history. The messages are logged unless the formula is not supported.
+func fastImport(f Formula, opt Options, logger *log.Logger) error {
+
+	if f.Commands.cloneTo == "" {
+		return UnsupportedError("formula without clone")
+	}
+
+	var messages bytes.Buffer
+	var buffered *log.Logger
+	if logger != nil {
+		buffered = log.New(&messages, logger.Prefix(), logger.Flags())
+	}
+
+	fi := newFastImporter(buffered, opt, filepath.Join(opt.RepoDir, f.Commands.cloneTo))
+	err := transmute(f, opt, fi)
+	if err == nil {
+		err = fi.finish()
+	}
+
+	var unsupported UnsupportedError
+	if logger != nil && !errors.As(err, &unsupported) {
+		_, _ = logger.Writer().Write(messages.Bytes())
+	}
+	return err
+}
+
+// fastFile is a file of the worktree.
+type fastFile struct {
+	data string
+	mode string // mode of git, e.g. 100644
+}
+
+// fastEntry is a file of the index or a commit.
+type fastEntry struct {
+	mark int // mark of the blob
+	mode string
+}
+
+// fastTree maps the slash separated paths to the files.
+type fastTree map[string]fastEntry
+
+// fastCommit is a commit of the import. It keeps only the changes of
+// the files to its first parent.
+type fastCommit struct {
+	parents    []int
+	changes    fastTree // added and modified files
+	deleted    []string
+	generation int // 1 for root commits, else 1 + the maximum of the parents
+}
+
+// fastPush is a branch that is pushed to a remote.
+type fastPush struct {
+	remote, branch string
+}
+
+// fastIdent is the name and email of an author or committer.
+type fastIdent struct {
+	name, email string
+}
+
+// String returns the ident as "name <email>".
+func (i fastIdent) String() string {
+	return i.name + " <" + i.email + ">"
+}
+
+// Git modes of the files.
+const (
+	fastModeFile       = "100644"
+	fastModeExecutable = "100755"
+	fastModeSymlink    = "120000"
+)
+
+// fastNullRef deletes a ref in a fast-import stream.
+const fastNullRef = "0000000000000000000000000000000000000000"
+
+// fastImporter is an assistant that does not execute the git commands
+// in the clone. It keeps the worktree, the index, and the history in
+// memory and writes the history as stream for git fast-import.
+// The commands that create the bare repo and the clone are executed
+// before the import by finish.
+// The logging is delegated to the novice.
+//
+// It implements the assistant interface.
+//
+// Only commands of the spells that add, commit, switch, merge, tag,
+// and push are supported. The pushes are applied after the import. Other commands return an UnsupportedError, so
+// does every command that git would reject, e.g. a merge conflict.
+type fastImporter struct {
+	novice
+	adept adept
+	clone string         // directory of the clone
+	setup []func() error // commands before the import
+	user  fastIdent      // configured user of the clone
+
+	worktree map[string]fastFile
+	dirs     map[string]bool // directories created in the worktree
+	index    fastTree
+	head     string         // current branch
+	branches map[string]int // branch -> commit mark, unborn branches are missing
+	written  map[string]bool
+	tags     map[string]int // tag -> commit mark
+	commits  map[int]fastCommit
+	cache    fastTree // tree of the commit cached
+	cached   int      // mark of the cached tree
+	remotes  map[string]bool
+	pushed   map[fastPush]int  // pushed branch -> commit mark
+	upstream map[string]string // branch -> remote of push -u
+	blobs    map[string]int    // content -> mark
+	contents map[int]string    // mark -> content
+	marks    int               // last mark
+	stream   bytes.Buffer
+}
+
+// newFastImporter returns an initialized fastImporter object.
+func newFastImporter(l *log.Logger, opt Options, clone string) *fastImporter {
+	return &fastImporter{
+		novice:   newNovice(l, opt),
+		adept:    newAdept(l, opt),
+		clone:    filepath.Clean(clone),
+		worktree: map[string]fastFile{},
+		dirs:     map[string]bool{},
+		index:    fastTree{},
+		head:     defaultBranch,
+		branches: map[string]int{},
+		written:  map[string]bool{},
+		tags:     map[string]int{},
+		commits:  map[int]fastCommit{},
+		remotes:  map[string]bool{},
+		pushed:   map[fastPush]int{},
+		upstream: map[string]string{},
+		blobs:    map[string]int{},
+		contents: map[int]string{},
+	}
+}
+
+// unsupportedGit returns the UnsupportedError of a git command.
+func unsupportedGit(args []string) error {
+	return UnsupportedError("git " + strings.Join(args, " "))
+}
+
+// path returns the slash separated path of the file in the clone.
+// The path of the clone itself is ".".
+func (fi *fastImporter) path(name string) (string, bool) {
+	rel, err := filepath.Rel(fi.clone, filepath.Clean(name))
+	if err != nil || filepath.IsAbs(rel) || rel == ".." ||
+		strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	rel = filepath.ToSlash(rel)
+	first, _, _ := strings.Cut(rel, "/")
+	if first == ".git" || strings.Contains(rel, "\n") || strings.HasPrefix(rel, `"`) {
+		return "", false
+	}
+	return rel, true
+}
+
+// within reports if the path is the file or in the directory.
+func within(file, dir string) bool {
+	return dir == "." || file == dir || strings.HasPrefix(file, dir+"/")
+}
+
+// git casts the git command in memory. The commands that create the
+// repo and configure the clone are executed before the import.
+// It implements the assistant interface.
+func (fi *fastImporter) git(dir string, args ...string) error {
+	_ = fi.novice.git(dir, args...)
+
+	if filepath.Clean(dir) != fi.clone {
+		return fi.gitSetup(dir, args)
+	}
+
+	ident, cmd := fi.identity(args)
+	if len(cmd) == 0 {
+		return unsupportedGit(args)
+	}
+
+	switch cmd[0] {
+	case "remote":
+		if len(cmd) == 4 && cmd[1] == "set-url" {
+			fi.remotes[cmd[2]] = true
+			fi.later(dir, args)
+			return nil
+		}
+	case "config":
+		return fi.config(dir, args, cmd)
+	case "add":
+		return fi.add(cmd)
+	case "commit":
+		return fi.commit(ident, cmd)
+	case "switch", "checkout":
+		return fi.switchBranch(cmd)
+	case "clean":
+		return fi.clean(cmd)
+	case "merge":
+		return fi.merge(ident, cmd)
+	case "branch":
+		return fi.branch(cmd)
+	case "tag":
+		return fi.tag(ident, cmd)
+	case "push":
+		return fi.push(cmd)
+	}
+	return unsupportedGit(args)
+}
+
+// gitSetup remembers the commands that create the bare repo and the
+// clone. Other commands outside of the clone are not supported.
+func (fi *fastImporter) gitSetup(dir string, args []string) error {
+	switch {
+	case len(args) > 1 && args[0] == "init" && slices.Contains(args, "--bare"):
+	case len(args) > 2 && args[0] == "clone" && !slices.Contains(args, "--bare") &&
+		filepath.Join(dir, args[len(args)-1]) == fi.clone:
+	default:
+		return unsupportedGit(args)
+	}
+	fi.later(dir, args)
+	return nil
+}
+
+// later adds the git command to the commands before the import.
+// The command was logged already.
+func (fi *fastImporter) later(dir string, args []string) {
+	quiet := adept{exe: fi.adept.exe}
+	fi.setup = append(fi.setup, func() error { return quiet.git(dir, args...) })
+}
+
+// config remembers the user of the clone. Other values could change
+// the result of git add and are not supported.
+func (fi *fastImporter) config(dir string, args, cmd []string) error {
+	if len(cmd) != 3 {
+		return unsupportedGit(args)
+	}
+	switch cmd[1] {
+	case "user.name":
+		fi.user.name = cmd[2]
+	case "user.email":
+		fi.user.email = cmd[2]
+	case "init.defaultBranch":
+	default:
+		return unsupportedGit(args)
+	}
+	fi.later(dir, args)
+	return nil
+}
+
+// identity returns the user that is set by leading -c options and
+// the remaining arguments. Without options, the user of the clone is
+// returned.
+func (fi *fastImporter) identity(args []string) (fastIdent, []string) {
+	ident := fi.user
+	for len(args) > 1 && args[0] == "-c" {
+		key, value, _ := strings.Cut(args[1], "=")
+		switch key {
+		case "user.name":
+			ident.name = value
+		case "user.email":
+			ident.email = value
+		default:
+			return ident, args
+		}
+		args = args[2:]
+	}
+	return ident, args
+}
+
+// gitOutput returns the git version, other output is not known
+// before the import.
+// It implements the assistant interface.
+func (fi *fastImporter) gitOutput(dir string, args ...string) (string, error) {
+	if len(args) == 1 && args[0] == "version" {
+		return fi.adept.gitOutput(dir, args...)
+	}
+	_, _ = fi.novice.gitOutput(dir, args...)
+	return "", unsupportedGit(args)
+}
+
+// copy copies the files of the formula into the worktree. The target
+// is examined like the adept does it.
+// It implements the assistant interface.
+func (fi *fastImporter) copy(from, to string) error {
+	_ = fi.novice.copy(from, to)
+
+	info, err := os.Lstat(from)
+	if err != nil {
+		return IOError{Cmd: "stat", Arg: from, Err: err}
+	}
+	target, ok := fi.path(to)
+	if !ok {
+		return UnsupportedError("copy to " + to)
+	}
+
+	exists, isDir := fi.exists(target)
+	if !exists && strings.HasSuffix(to, string(os.PathSeparator)) {
+		isDir = true
+	}
+
+	if !info.IsDir() {
+		if isDir {
+			target = path.Join(target, filepath.Base(from))
+		}
+		return fi.copyFile(from, target)
+	}
+
+	if exists {
+		target = path.Join(target, filepath.Base(from))
+	}
+	return filepath.WalkDir(from, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return IOError{Cmd: "WalkDirFunc", Arg: file, Err: err}
+		}
+		if d.IsDir() {
+			return nil
+		}
+		subPath, _ := filepath.Rel(from, file)
+		return fi.copyFile(file, path.Join(target, filepath.ToSlash(subPath)))
+	})
+}
+
+// exists reports if the path is a file or directory of the worktree.
+func (fi *fastImporter) exists(name string) (exists, isDir bool) {
+	if name == "." || fi.dirs[name] {
+		return true, true
+	}
+	if _, ok := fi.worktree[name]; ok {
+		return true, false
+	}
+	for file := range fi.worktree {
+		if strings.HasPrefix(file, name+"/") {
+			return true, true
+		}
+	}
+	return false, false
+}
+
+// copyFile reads the file into the worktree, symbolic links are kept.
+func (fi *fastImporter) copyFile(from, target string) error {
+
+	info, err := os.Lstat(from)
+	if err != nil {
+		return IOError{Cmd: "stat", Arg: from, Err: err}
+	}
+	if info.Mode()&fs.ModeSymlink != 0 {
+		link, err := os.Readlink(from)
+		if err != nil {
+			return IOError{Cmd: "read link", Arg: from, Err: err}
+		}
+		fi.worktree[target] = fastFile{data: link, mode: fastModeSymlink}
+		return nil
+	}
+
+	data, err := os.ReadFile(from)
+	if err != nil {
+		return IOError{Cmd: "read", Arg: from, Err: err}
+	}
+	fi.worktree[target] = fastFile{data: string(data), mode: gitMode(info.Mode())}
+	return nil
+}
+
+// gitMode returns the git mode of a file with the permissions.
+func gitMode(mode fs.FileMode) string {
+	if mode.Perm()&0o100 != 0 {
+		return fastModeExecutable
+	}
+	return fastModeFile
+}
+
+// makedir creates the directory before the import unless it is in
+// the worktree.
+// It implements the assistant interface.
+func (fi *fastImporter) makedir(dir string) error {
+	_ = fi.novice.makedir(dir)
+
+	if name, ok := fi.path(dir); ok {
+		fi.dirs[name] = true
+		return nil
+	}
+	quiet := adept{exe: fi.adept.exe}
+	fi.setup = append(fi.setup, func() error { return quiet.makedir(dir) })
+	return nil
+}
+
+// readFile returns the content of a file of the worktree or the formula.
+// It implements the assistant interface.
+func (fi *fastImporter) readFile(name string) ([]byte, error) {
+	_, _ = fi.novice.readFile(name)
+
+	if file, ok := fi.path(name); ok {
+		f, ok := fi.worktree[file]
+		if !ok {
+			return nil, IOError{Cmd: "read", Arg: name, Err: fs.ErrNotExist}
+		}
+		return []byte(f.data), nil
+	}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, IOError{Cmd: "read", Arg: name, Err: err}
+	}
+	return data, nil
+}
+
+// writeFile writes the data to a file of the worktree.
+// It implements the assistant interface.
+func (fi *fastImporter) writeFile(name string, data []byte, mode fs.FileMode) error {
+	_ = fi.novice.writeFile(name, data, mode)
+
+	file, ok := fi.path(name)
+	if !ok {
+		return UnsupportedError("write " + name)
+	}
+	fi.worktree[file] = fastFile{data: string(data), mode: gitMode(mode)}
+	return nil
+}
+
+// chmod changes the mode of a file of the worktree.
+// It implements the assistant interface.
+func (fi *fastImporter) chmod(name string, mode fs.FileMode) error {
+	_ = fi.novice.chmod(name, mode)
+
+	file, ok := fi.path(name)
+	if !ok {
+		return UnsupportedError("chmod " + name)
+	}
+	f, ok := fi.worktree[file]
+	if !ok || f.mode == fastModeSymlink {
+		return IOError{Cmd: "chmod", Arg: name, Err: fs.ErrNotExist}
+	}
+	f.mode = gitMode(mode)
+	fi.worktree[file] = f
+	return nil
+}
+
+// keygen is not supported.
+// It implements the assistant interface.
+func (fi *fastImporter) keygen(file, comment string) (string, error) {
+	_, _ = fi.novice.keygen(file, comment)
+	return "", UnsupportedError("keygen " + file)
+}
+
+// blob returns the index entry of the file. The blob is added to the
+// stream unless the content is known.
+func (fi *fastImporter) blob(f fastFile) fastEntry {
+	mark, ok := fi.blobs[f.data]
+	if !ok {
+		fi.marks++
+		mark = fi.marks
+		fi.blobs[f.data] = mark
+		fi.contents[mark] = f.data
+		fmt.Fprintf(&fi.stream, "blob\nmark :%d\n", mark)
+		fi.data(f.data)
+	}
+	return fastEntry{mark: mark, mode: f.mode}
+}
+
+// data adds the data command to the stream.
+func (fi *fastImporter) data(data string) {
+	fmt.Fprintf(&fi.stream, "data %d\n%s\n", len(data), data)
+}
+
+// tree returns the tree of the commit, the tree of mark 0 is empty.
+// The tree is built from the changes of the first parents and cached.
+// It must not be changed, the next commit reuses the cached tree.
+func (fi *fastImporter) tree(mark int) fastTree {
+	if fi.cache != nil && fi.cached == mark {
+		return fi.cache
+	}
+
+	var chain []int
+	for next := mark; next != 0; {
+		chain = append(chain, next)
+		next = 0
+		if parents := fi.commits[chain[len(chain)-1]].parents; len(parents) > 0 {
+			next = parents[0]
+		}
+	}
+	tree := fastTree{}
+	for _, next := range slices.Backward(chain) {
+		fi.commits[next].apply(tree)
+	}
+	fi.cache, fi.cached = tree, mark
+	return tree
+}
+
+// apply changes the tree of the first parent to the tree of the commit.
+func (c fastCommit) apply(tree fastTree) {
+	for _, file := range c.deleted {
+		delete(tree, file)
+	}
+	maps.Copy(tree, c.changes)
+}
+
+// resolve returns the commit of HEAD, a branch, or a tag.
+func (fi *fastImporter) resolve(rev string) (int, bool) {
+	mark, ok := fi.branches[rev]
+	switch {
+	case rev == "HEAD":
+		mark, ok = fi.branches[fi.head]
+	case !ok:
+		mark, ok = fi.tags[rev]
+	}
+	return mark, ok
+}
+
+// add stages the files of the pathspecs. Files that are missing in
+// the worktree are removed from the index.
+func (fi *fastImporter) add(cmd []string) error {
+
+	for file := range fi.worktree {
+		// ignored files and attributes change what is added
+		if base := path.Base(file); base == ".gitignore" || base == ".gitattributes" {
+			return UnsupportedError(file)
+		}
+	}
+
+	for _, spec := range cmd[1:] {
+		if strings.ContainsAny(spec, "*?[:") {
+			return unsupportedGit(cmd)
+		}
+		spec, ok := fi.path(filepath.Join(fi.clone, spec))
+		if !ok {
+			return unsupportedGit(cmd)
+		}
+
+		matched := false
+		for file, f := range fi.worktree {
+			if within(file, spec) {
+				fi.index[file] = fi.blob(f)
+				matched = true
+			}
+		}
+		for file := range fi.index {
+			if _, ok := fi.worktree[file]; !ok && within(file, spec) {
+				delete(fi.index, file)
+				matched = true
+			}
+		}
+		if !matched {
+			// git reports that the pathspec did not match any files
+			return unsupportedGit(cmd)
+		}
+	}
+	return nil
+}
+
+// commit commits the index. The message is given with -m, the author
+// with --author, other options than --allow-empty are not supported.
+func (fi *fastImporter) commit(ident fastIdent, cmd []string) error {
+
+	var paragraphs []string
+	author := ident
+	authorDate := time.Now()
+	allowEmpty := false
+	for i := 1; i < len(cmd); i++ {
+		switch arg := cmd[i]; {
+		case arg == "--allow-empty":
+			allowEmpty = true
+		case arg == "--date="+gitCommitDateFormat:
+			authorDate = authorDate.Add(-commitDateOffset)
+		case arg == "-m" && i+1 < len(cmd):
+			paragraphs = append(paragraphs, cmd[i+1])
+			i++
+		case strings.HasPrefix(arg, "--author="):
+			name, email, ok := strings.Cut(strings.TrimPrefix(arg, "--author="), " <")
+			if !ok {
+				return unsupportedGit(cmd)
+			}
+			author = fastIdent{name: name, email: strings.TrimSuffix(email, ">")}
+		default:
+			return unsupportedGit(cmd)
+		}
+	}
+
+	message := cleanupMessage(strings.Join(paragraphs, "\n\n"))
+	parent, born := fi.branches[fi.head]
+	switch {
+	case message == "" || ident.name == "" || ident.email == "":
+		return unsupportedGit(cmd)
+	case !allowEmpty && maps.Equal(fi.index, fi.tree(parent)):
+		// git reports that there is nothing to commit
+		return unsupportedGit(cmd)
+	}
+
+	var parents []int
+	if born {
+		parents = []int{parent}
+	}
+	fi.addCommit(parents, message, author, authorDate, ident, time.Now())
+	return nil
+}
+
+// addCommit adds a commit of the index to the stream, the current
+// branch is moved to it. The files are given as changes to the
+// first parent.
+func (fi *fastImporter) addCommit(parents []int, message string,
+	author fastIdent, authorDate time.Time, committer fastIdent, commitDate time.Time) {
+
+	fi.marks++
+	mark := fi.marks
+	ref := "refs/heads/" + fi.head
+
+	if len(parents) == 0 {
+		// a new root commit, the branch may have had commits
+		fmt.Fprintf(&fi.stream, "reset %s\n", ref)
+	}
+	fmt.Fprintf(&fi.stream, "commit %s\nmark :%d\n", ref, mark)
+	fmt.Fprintf(&fi.stream, "author %s %s\n", author, rawDate(authorDate))
+	fmt.Fprintf(&fi.stream, "committer %s %s\n", committer, rawDate(commitDate))
+	fi.data(message)
+
+	commit := fastCommit{parents: parents, changes: fastTree{}, generation: 1}
+	base := fi.tree(0)
+	for i, parent := range parents {
+		commit.generation = max(commit.generation, fi.commits[parent].generation+1)
+		if i == 0 {
+			fmt.Fprintf(&fi.stream, "from :%d\n", parent)
+			base = fi.tree(parent)
+			continue
+		}
+		fmt.Fprintf(&fi.stream, "merge :%d\n", parent)
+	}
+	for _, file := range slices.Sorted(maps.Keys(base)) {
+		if _, ok := fi.index[file]; !ok {
+			fmt.Fprintf(&fi.stream, "D %s\n", file)
+			commit.deleted = append(commit.deleted, file)
+		}
+	}
+	for _, file := range slices.Sorted(maps.Keys(fi.index)) {
+		if entry := fi.index[file]; base[file] != entry {
+			fmt.Fprintf(&fi.stream, "M %s :%d %s\n", entry.mode, entry.mark, file)
+			commit.changes[file] = entry
+		}
+	}
+	fi.stream.WriteString("\n")
+
+	// the cached tree of the parent becomes the tree of the commit
+	commit.apply(base)
+	fi.cache, fi.cached = base, mark
+	fi.commits[mark] = commit
+	fi.branches[fi.head] = mark
+	fi.written[fi.head] = true
+}
+
+// rawDate returns the time in the raw format of git.
+func rawDate(t time.Time) string {
+	return fmt.Sprintf("%d %s", t.Unix(), t.Format("-0700"))
+}
+
+// cleanupMessage removes trailing whitespace, leading and trailing
+// empty lines, and repeated empty lines like git commit does for
+// messages given with -m.
+func cleanupMessage(message string) string {
+	var lines []string
+	empty := false
+	for _, line := range strings.Split(message, "\n") {
+		line = strings.TrimRight(line, " \t\r\v\f")
+		if line == "" {
+			empty = len(lines) > 0
+			continue
+		}
+		if empty {
+			lines = append(lines, "")
+			empty = false
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// switchBranch switches to an existing, a new, or an orphan branch.
+func (fi *fastImporter) switchBranch(cmd []string) error {
+
+	var branch, base string
+	create, orphan := false, false
+	for _, arg := range cmd[1:] {
+		switch {
+		case arg == "--create" || arg == "-c" || arg == "-b":
+			create = true
+		case arg == "--orphan":
+			orphan = true
+		case strings.HasPrefix(arg, "-"):
+			return unsupportedGit(cmd)
+		case branch == "":
+			branch = arg
+		case base == "":
+			base = arg
+		default:
+			return unsupportedGit(cmd)
+		}
+	}
+	_, exists := fi.branches[branch]
+	if branch == "" || (base != "" && !create) || ((create || orphan) && exists) {
+		return unsupportedGit(cmd)
+	}
+
+	from := fi.tree(fi.branches[fi.head])
+	switch {
+	case orphan:
+		if !fi.isClean(from) {
+			return unsupportedGit(cmd)
+		}
+		for file := range fi.index {
+			delete(fi.worktree, file)
+		}
+		fi.index = fastTree{}
+	case create:
+		if base == "" {
+			base = "HEAD"
+		}
+		mark, ok := fi.resolve(base)
+		if !ok || fi.update(from, fi.tree(mark)) != nil {
+			return unsupportedGit(cmd)
+		}
+		fi.branches[branch] = mark
+	default:
+		mark, ok := fi.branches[branch]
+		if !ok || fi.update(from, fi.tree(mark)) != nil {
+			return unsupportedGit(cmd)
+		}
+	}
+
+	fi.head = branch
+	return nil
+}
+
+// clean removes the untracked files of the worktree. Ignored files
+// are kept by git, so they are not supported.
+func (fi *fastImporter) clean(cmd []string) error {
+	if !slices.Equal(cmd, []string{"clean", "-d", "--force", "--quiet"}) {
+		return unsupportedGit(cmd)
+	}
+	for file := range fi.worktree {
+		if path.Base(file) == ".gitignore" {
+			return unsupportedGit(cmd)
+		}
+	}
+	for file := range fi.worktree {
+		if _, ok := fi.index[file]; !ok {
+			delete(fi.worktree, file)
+		}
+	}
+	fi.dirs = map[string]bool{}
+	return nil
+}
+
+// same reports if the file of the worktree has the content and the
+// mode of the entry.
+func (fi *fastImporter) same(f fastFile, entry fastEntry) bool {
+	return f.mode == entry.mode && fi.contents[entry.mark] == f.data
+}
+
+// isClean reports if the index and the tracked files of the worktree
+// are unchanged.
+func (fi *fastImporter) isClean(tree fastTree) bool {
+	if !maps.Equal(fi.index, tree) {
+		return false
+	}
+	for file, entry := range tree {
+		f, ok := fi.worktree[file]
+		if !ok || !fi.same(f, entry) {
+			return false
+		}
+	}
+	return true
+}
+
+// update changes the index and the worktree from one tree to the
+// other. Like git, it fails if changes in the index or the worktree
+// or untracked files would be overwritten.
+func (fi *fastImporter) update(from, to fastTree) error {
+
+	var changed []string
+	for _, tree := range []fastTree{from, to} {
+		for file := range tree {
+			if from[file] != to[file] && !slices.Contains(changed, file) {
+				changed = append(changed, file)
+			}
+		}
+	}
+
+	for _, file := range changed {
+		entry, tracked := from[file]
+		f, ok := fi.worktree[file]
+		switch {
+		case fi.index[file] != entry:
+			return UnsupportedError("local changes of " + file)
+		case tracked && (!ok || !fi.same(f, entry)):
+			return UnsupportedError("local changes of " + file)
+		case !tracked && ok:
+			return UnsupportedError("untracked file " + file)
+		}
+	}
+
+	for _, file := range changed {
+		entry, ok := to[file]
+		if !ok {
+			delete(fi.index, file)
+			delete(fi.worktree, file)
+			continue
+		}
+		fi.index[file] = entry
+		fi.worktree[file] = fastFile{data: fi.contents[entry.mark], mode: entry.mode}
+	}
+	return nil
+}
+
+// merge merges a branch into the current branch. The files are merged
+// if only one side changed them, conflicts are not supported.
+func (fi *fastImporter) merge(ident fastIdent, cmd []string) error {
+
+	var source, message string
+	noFF := false
+	for i := 1; i < len(cmd); i++ {
+		switch arg := cmd[i]; {
+		case arg == "--no-ff":
+			noFF = true
+		case arg == "-m" && i+1 < len(cmd):
+			message = cmd[i+1]
+			i++
+		case strings.HasPrefix(arg, "-") || source != "":
+			return unsupportedGit(cmd)
+		default:
+			source = arg
+		}
+	}
+
+	theirs, ok := fi.branches[source]
+	ours, born := fi.branches[fi.head]
+	if !ok || !born || !fi.isClean(fi.tree(ours)) {
+		return unsupportedGit(cmd)
+	}
+
+	switch {
+	case fi.isAncestor(theirs, ours):
+		// already up to date
+		return nil
+	case fi.isAncestor(ours, theirs) && !noFF:
+		// fast-forward
+		err := fi.update(fi.tree(ours), fi.tree(theirs))
+		if err != nil {
+			return err
+		}
+		fi.branches[fi.head] = theirs
+		return nil
+	}
+
+	base, ok := fi.mergeBase(ours, theirs)
+	if !ok {
+		return unsupportedGit(cmd)
+	}
+	merged, ok := fi.mergeTrees(fi.tree(base), fi.tree(ours), fi.tree(theirs))
+	if !ok {
+		return UnsupportedError("merge conflict of " + source)
+	}
+	err := fi.update(fi.tree(ours), merged)
+	if err != nil {
+		return err
+	}
+
+	if message == "" {
+		message = "Merge branch '" + source + "'"
+		if fi.head != "main" && fi.head != "master" {
+			message += " into " + fi.head
+		}
+	}
+	message = cleanupMessage(message)
+	if message == "" || ident.name == "" || ident.email == "" {
+		return unsupportedGit(cmd)
+	}
+
+	now := time.Now()
+	fi.addCommit([]int{ours, theirs}, message, ident, now, ident, now)
+	return nil
+}
+
+// ancestors returns the commits and all their ancestors.
+func (fi *fastImporter) ancestors(marks ...int) map[int]bool {
+	result := map[int]bool{}
+	queue := slices.Clone(marks)
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		if result[next] {
+			continue
+		}
+		result[next] = true
+		queue = append(queue, fi.commits[next].parents...)
+	}
+	return result
+}
+
+// isAncestor reports if the commit a is an ancestor of b or b itself.
+// The ancestors of a commit have a lower generation, so the walk stops
+// at the generation of a.
+func (fi *fastImporter) isAncestor(a, b int) bool {
+	generation := fi.commits[a].generation
+	seen := map[int]bool{}
+	queue := []int{b}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		if next == a {
+			return true
+		}
+		if seen[next] || fi.commits[next].generation <= generation {
+			continue
+		}
+		seen[next] = true
+		queue = append(queue, fi.commits[next].parents...)
+	}
+	return false
+}
+
+// mergeBase returns the best common ancestor of the commits.
+// It fails for unrelated histories and for several merge bases.
+func (fi *fastImporter) mergeBase(a, b int) (int, bool) {
+
+	ofA := fi.ancestors(a)
+	common := map[int]bool{}
+	var parents []int
+	for mark := range fi.ancestors(b) {
+		if ofA[mark] {
+			common[mark] = true
+			parents = append(parents, fi.commits[mark].parents...)
+		}
+	}
+
+	// the ancestors of common ancestors are not the best
+	for mark := range fi.ancestors(parents...) {
+		delete(common, mark)
+	}
+	if len(common) != 1 {
+		return 0, false
+	}
+	for mark := range common {
+		return mark, true
+	}
+	return 0, false
+}
+
+// mergeTrees merges the files of the trees. A file that both sides
+// changed differently is a conflict.
+func (fi *fastImporter) mergeTrees(base, ours, theirs fastTree) (fastTree, bool) {
+
+	merged := fastTree{}
+	for _, tree := range []fastTree{base, ours, theirs} {
+		for file := range tree {
+			b, bok := base[file]
+			o, ook := ours[file]
+			t, tok := theirs[file]
+			switch {
+			case o == t && ook == tok, b == t && bok == tok:
+				if ook {
+					merged[file] = o
+				}
+			case b == o && bok == ook:
+				if tok {
+					merged[file] = t
+				}
+			default:
+				return nil, false
+			}
+		}
+	}
+	return merged, true
+}
+
+// branch creates or deletes a branch. A deleted branch must be merged
+// into the current branch.
+func (fi *fastImporter) branch(cmd []string) error {
+
+	switch {
+	case len(cmd) == 3 && cmd[1] == "-d":
+		mark, ok := fi.branches[cmd[2]]
+		if !ok || cmd[2] == fi.head || !fi.isAncestor(mark, fi.branches[fi.head]) {
+			return unsupportedGit(cmd)
+		}
+		delete(fi.branches, cmd[2])
+		return nil
+	case len(cmd) == 2 || len(cmd) == 3:
+		if _, exists := fi.branches[cmd[1]]; exists || strings.HasPrefix(cmd[1], "-") {
+			return unsupportedGit(cmd)
+		}
+		start := "HEAD"
+		if len(cmd) == 3 {
+			start = cmd[2]
+		}
+		mark, ok := fi.resolve(start)
+		if !ok {
+			return unsupportedGit(cmd)
+		}
+		fi.branches[cmd[1]] = mark
+		return nil
+	}
+	return unsupportedGit(cmd)
+}
+
+// tag creates a lightweight or an annotated tag, signed tags are not
+// supported.
+func (fi *fastImporter) tag(ident fastIdent, cmd []string) error {
+
+	var name, ref, message string
+	annotated := false
+	for i := 1; i < len(cmd); i++ {
+		switch arg := cmd[i]; {
+		case arg == "-a":
+			annotated = true
+		case arg == "-m" && i+1 < len(cmd):
+			message = cmd[i+1]
+			i++
+		case strings.HasPrefix(arg, "-"):
+			return unsupportedGit(cmd)
+		case name == "":
+			name = arg
+		case ref == "":
+			ref = arg
+		default:
+			return unsupportedGit(cmd)
+		}
+	}
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	mark, ok := fi.resolve(ref)
+	_, exists := fi.tags[name]
+	if name == "" || !ok || exists || annotated != (message != "") {
+		return unsupportedGit(cmd)
+	}
+
+	if !annotated {
+		fmt.Fprintf(&fi.stream, "reset refs/tags/%s\nfrom :%d\n\n", name, mark)
+		fi.tags[name] = mark
+		return nil
+	}
+
+	message = cleanupMessage(message)
+	if message == "" || ident.name == "" || ident.email == "" {
+		return unsupportedGit(cmd)
+	}
+	fmt.Fprintf(&fi.stream, "tag %s\nfrom :%d\n", name, mark)
+	fmt.Fprintf(&fi.stream, "tagger %s %s\n", ident, rawDate(time.Now()))
+	fi.data(message)
+	fi.tags[name] = mark
+	return nil
+}
+
+// push pushes branches to a remote of the clone. The remote-tracking
+// branches are imported, the remote is updated after the import.
+// Like git, it fails for pushes that are not fast-forward.
+func (fi *fastImporter) push(cmd []string) error {
+
+	upstream := false
+	var names []string
+	for _, arg := range cmd[1:] {
+		switch {
+		case arg == "-u" || arg == "--set-upstream":
+			upstream = true
+		case strings.HasPrefix(arg, "-"):
+			return unsupportedGit(cmd)
+		default:
+			names = append(names, arg)
+		}
+	}
+	if len(names) < 2 || !fi.remotes[names[0]] {
+		return unsupportedGit(cmd)
+	}
+
+	for _, branch := range names[1:] {
+		p := fastPush{remote: names[0], branch: branch}
+		mark, ok := fi.branches[branch]
+		pushed, known := fi.pushed[p]
+		if !ok || (known && !fi.isAncestor(pushed, mark)) {
+			return unsupportedGit(cmd)
+		}
+		fmt.Fprintf(&fi.stream, "reset refs/remotes/%s/%s\nfrom :%d\n\n", p.remote, p.branch, mark)
+		fi.pushed[p] = mark
+		if upstream {
+			fi.upstream[branch] = p.remote
+		}
+	}
+	return nil
+}
+
+// finish executes the commands that create the repo and the clone,
+// imports the history, and checks out the current branch. Files of
+// the worktree and the index that are not committed are written and
+// added.
+func (fi *fastImporter) finish() error {
+
+	head := fi.tree(fi.branches[fi.head])
+	var staged []string
+	for file, entry := range fi.index {
+		if head[file] == entry {
+			continue
+		}
+		f, ok := fi.worktree[file]
+		if !ok || !fi.same(f, entry) {
+			return UnsupportedError("staged and changed file " + file)
+		}
+		staged = append(staged, file)
+	}
+	for file := range head {
+		if _, ok := fi.index[file]; !ok {
+			return UnsupportedError("staged removal of " + file)
+		}
+	}
+
+	for _, step := range fi.setup {
+		err := step()
+		if err != nil {
+			return err
+		}
+	}
+
+	// the final branches, deleted branches are removed
+	for _, branch := range slices.Sorted(maps.Keys(fi.written)) {
+		if _, ok := fi.branches[branch]; !ok {
+			fmt.Fprintf(&fi.stream, "reset refs/heads/%s\nfrom %s\n\n", branch, fastNullRef)
+		}
+	}
+	for _, branch := range slices.Sorted(maps.Keys(fi.branches)) {
+		fmt.Fprintf(&fi.stream, "reset refs/heads/%s\nfrom :%d\n\n", branch, fi.branches[branch])
+	}
+
+	fi.info("import %d commits into %s", len(fi.commits), fi.clone)
+	err := fi.gitImport()
+	if err != nil {
+		return err
+	}
+
+	err = fi.adept.git(fi.clone, "symbolic-ref", "HEAD", "refs/heads/"+fi.head)
+	if err == nil && len(fi.commits) > 0 {
+		if _, ok := fi.branches[fi.head]; ok {
+			err = fi.adept.git(fi.clone, "reset", "--hard", "--quiet")
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, file := range slices.Sorted(maps.Keys(fi.worktree)) {
+		f := fi.worktree[file]
+		if entry, ok := head[file]; ok && fi.same(f, entry) {
+			continue
+		}
+		err = writeWorktreeFile(filepath.Join(fi.clone, filepath.FromSlash(file)), f)
+		if err != nil {
+			return err
+		}
+	}
+	for dir := range fi.dirs {
+		err = fi.adept.makedir(filepath.Join(fi.clone, filepath.FromSlash(dir)))
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(staged) > 0 {
+		slices.Sort(staged)
+		err = fi.adept.git(fi.clone, append([]string{"add", "--"}, staged...)...)
+		if err != nil {
+			return err
+		}
+	}
+	return fi.pushAll()
+}
+
+// pushAll updates the remotes with the imported remote-tracking
+// branches and configures the upstream branches.
+func (fi *fastImporter) pushAll() error {
+
+	pushes := slices.SortedFunc(maps.Keys(fi.pushed), func(a, b fastPush) int {
+		return strings.Compare(a.remote+"/"+a.branch, b.remote+"/"+b.branch)
+	})
+	for _, p := range pushes {
+		refspec := "refs/remotes/" + p.remote + "/" + p.branch + ":refs/heads/" + p.branch
+		err := fi.adept.git(fi.clone, "push", "--quiet", p.remote, refspec)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, branch := range slices.Sorted(maps.Keys(fi.upstream)) {
+		for _, args := range [][]string{
+			{"config", "branch." + branch + ".remote", fi.upstream[branch]},
+			{"config", "branch." + branch + ".merge", "refs/heads/" + branch},
+		} {
+			err := fi.adept.git(fi.clone, args...)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// gitImport executes git fast-import with the stream in the clone.
+func (fi *fastImporter) gitImport() error {
+
+	args := []string{"fast-import", "--quiet"}
+	fi.debug("%q: git %#v", fi.clone, args)
+
+	cmd := exec.Command(fi.adept.exe, args...)
+	cmd.Dir = fi.clone
+	cmd.Env = append(os.Environ(), gitEnvironment...)
+	cmd.Stdin = &fi.stream
+
+	output, err := cmd.CombinedOutput()
+	for _, line := range strings.Split(string(output), "\n") {
+		if line != "" {
+			fi.debug("%s", line)
+		}
+	}
+	if err != nil {
+		return ExecError{Cmd: gitCmd, Args: args, Err: err}
+	}
+	return nil
+}
+
+// writeWorktreeFile writes the file of the worktree to disk.
+func writeWorktreeFile(name string, f fastFile) error {
+
+	err := os.MkdirAll(filepath.Dir(name), dirMode)
+	if err != nil {
+		return IOError{Cmd: "make dir", Arg: filepath.Dir(name), Err: err}
+	}
+	_ = os.Remove(name)
+
+	if f.mode == fastModeSymlink {
+		err = os.Symlink(f.data, name)
+		if err != nil {
+			return IOError{Cmd: "symlink", Arg: name, Err: err}
+		}
+		return nil
+	}
+
+	var mode fs.FileMode = fileMode
+	if f.mode == fastModeExecutable {
+		mode = executableMode
+	}
+	err = os.WriteFile(name, []byte(f.data), mode)
+	if err != nil {
+		return IOError{Cmd: "write", Arg: name, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,361 @@
+package alchemist
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestCleanupMessage tests the cleanup of commit messages.
+func TestCleanupMessage(t *testing.T) {
+
+	testCases := []struct {
+		name    string
+		message string
+		want    string
+	}{{
+		name:    "single line",
+		message: "add plan",
+		want:    "add plan\n",
+	}, {
+		name:    "paragraphs",
+		message: "\n add plan  \n\n\n\nwith details\t\n\n",
+		want:    " add plan\n\nwith details\n",
+	}, {
+		name:    "empty",
+		message: " \n\n",
+		want:    "",
+	}}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			got := cleanupMessage(c.message)
+			if got != c.want {
+				t.Errorf("ERROR: got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+// fastImportFormula uses all the spells that are supported by the
+// fast importer.
+const fastImportFormula = `
+title: fast import
+commands:
+  - init_bare_repo:
+      bare: remotes/fast
+      clone_to: fast
+  - create_add_commit:
+      files:
+        - source.txt => docs/source.txt
+        - source.txt => bin/run.sh
+      message: add source
+      author: blue
+  - chmod:
+      files:
+        - bin/run.sh
+      mode: +x
+  - add:
+      files:
+        - bin
+  - commit:
+      message: make it executable
+      author: blue
+  - switch:
+      branch: feature
+      create: true
+  - create_file:
+      source: source.txt
+      target: docs/crlf.txt
+      eol: crlf
+  - add:
+      files:
+        - docs
+  - commit:
+      message: use crlf
+      body: "  with a body  "
+      author: red
+  - switch:
+      branch: main
+  - generate_file:
+      file: plan.txt
+      kind: text
+      lines: 3
+      seed: 1
+  - add:
+      files:
+        - .
+  - commit:
+      message: add plan
+      author: Eve <eve@example.com>
+  - push:
+      main: true
+  - git:
+      command: push -u origin feature
+  - merge:
+      source: feature
+      target: main
+      author: green
+  - tag:
+      name: v1
+      message: first release
+      author: red
+  - switch:
+      branch: hotfix
+      create: true
+      base: feature
+  - create_add_commit:
+      files:
+        - source.txt => hotfix.txt
+      message: fix it
+      author: red
+  - merge:
+      source: hotfix
+      target: main
+      delete_source: true
+  - switch:
+      branch: ahead
+      create: true
+  - create_add_commit:
+      files:
+        - source.txt => bin/
+      message: copy into bin
+      author: blue
+  - merge:
+      source: ahead
+      target: main
+  - push:
+      main: true
+  - tag:
+      name: v2
+      ref: feature
+  - switch:
+      branch: pages
+      orphan: true
+  - create_add_commit:
+      files:
+        - source.txt => index.html
+      message: publish
+      author: red
+  - switch:
+      branch: main
+  - create_file:
+      source: source.txt
+      target: untracked.txt
+  - create_file:
+      source: source.txt
+      target: staged.txt
+  - add:
+      files:
+        - staged.txt
+`
+
+// TestFastImport casts a formula with and without fast import and
+// compares the repos.
+func TestFastImport(t *testing.T) {
+
+	_, err := exec.LookPath(gitCmd)
+	if err != nil {
+		t.Skipf("%s not found", gitCmd)
+	}
+
+	formula, err := readFormula(strings.NewReader(fastImportFormula))
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+	baseDir := fastImportDir(t)
+
+	var messages bytes.Buffer
+	logger := log.New(&messages, "", 0)
+	opt := Options{RepoDir: filepath.Join(baseDir, "cast"), CfgDir: ".", TaskDir: TestDataDir}
+	err = Transmute(formula, opt, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+	fastOpt := opt
+	fastOpt.RepoDir = filepath.Join(baseDir, "imported")
+	fastOpt.FastImport = true
+	err = Transmute(formula, fastOpt, logger)
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+
+	if !strings.Contains(messages.String(), "import 9 commits") ||
+		strings.Contains(messages.String(), "not supported") {
+		t.Errorf("ERROR: formula was not fast imported:\n%s", messages.String())
+	}
+
+	for _, args := range [][]string{
+		{"symbolic-ref", "HEAD"},
+		{"for-each-ref", "--format=%(refname) %(objecttype) %(taggername) %(contents)"},
+		{"log", "--topo-order", "--format=@%T %an %ae %cn %ce|%p%n%B", "main"},
+		{"log", "--topo-order", "--format=%T %an %ae %cn %ce %s", "feature", "pages", "v1"},
+		{"ls-files", "--stage"},
+		{"status", "--porcelain", "--untracked-files=all"},
+		{"config", "--get-regexp", "^branch\\."},
+	} {
+		want := importGit(t, filepath.Join(opt.RepoDir, "fast"), args...)
+		got := importGit(t, filepath.Join(fastOpt.RepoDir, "fast"), args...)
+		if args[0] == "log" {
+			want, got = countParents(want), countParents(got)
+		}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("ERROR: %v: got- want+\n%s\n", args, diff)
+		}
+	}
+
+	// the pushed branches of the bare repo
+	args := []string{"for-each-ref", "--format=%(refname) %(tree)"}
+	want := importGit(t, filepath.Join(opt.RepoDir, "remotes", "fast"), args...)
+	got := importGit(t, filepath.Join(fastOpt.RepoDir, "remotes", "fast"), args...)
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("ERROR: %v: got- want+\n%s\n", args, diff)
+	}
+	if !strings.Contains(got, "refs/heads/feature") {
+		t.Errorf("ERROR: feature not pushed:\n%s", got)
+	}
+}
+
+// TestFastImportFallback tests that formulas with unsupported spells
+// are cast with git.
+func TestFastImportFallback(t *testing.T) {
+
+	_, err := exec.LookPath(gitCmd)
+	if err != nil {
+		t.Skipf("%s not found", gitCmd)
+	}
+
+	formula, err := readFormula(strings.NewReader(`
+title: fallback
+commands:
+  - init_bare_repo:
+      bare: remotes/fallback
+      clone_to: fallback
+  - create_add_commit:
+      files:
+        - source.txt => source.txt
+      message: add source
+      author: blue
+  - push:
+      main: true
+  - mv:
+      source: source.txt
+      target: moved.txt
+`))
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+	baseDir := fastImportDir(t)
+
+	var messages bytes.Buffer
+	opt := Options{RepoDir: baseDir, CfgDir: ".", TaskDir: TestDataDir, FastImport: true}
+	err = Transmute(formula, opt, log.New(&messages, "", 0))
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+
+	want := "[INFO] mv (4): not supported by fast import: git mv source.txt moved.txt"
+	if !strings.Contains(messages.String(), want) {
+		t.Errorf("ERROR: got %q, want %q", messages.String(), want)
+	}
+	if strings.Count(messages.String(), "execute formula") != 1 {
+		t.Errorf("ERROR: messages of the fast import are logged:\n%s", messages.String())
+	}
+	got := importGit(t, filepath.Join(baseDir, "remotes", "fallback"), "log", "--format=%s")
+	if got != "add source\n" {
+		t.Errorf("ERROR: got %q, want the pushed commit", got)
+	}
+}
+
+// fastImportDir returns the empty directory of the test in testdata.
+func fastImportDir(t *testing.T) string {
+	t.Helper()
+
+	baseDir, err := filepath.Abs(filepath.Join(TestDataDir, "fastimport", t.Name()))
+	if err != nil {
+		t.Fatalf("ERROR: test setup failed: %v", err)
+	}
+	err = os.RemoveAll(baseDir)
+	if err != nil {
+		t.Fatalf("ERROR: test setup failed: %v", err)
+	}
+	if !testing.Verbose() {
+		t.Cleanup(func() { os.RemoveAll(filepath.Dir(baseDir)) })
+	}
+	return baseDir
+}
+
+// BenchmarkTransmute compares casting a generated history with git
+// and with git fast-import.
+func BenchmarkTransmute(b *testing.B) {
+
+	_, err := exec.LookPath(gitCmd)
+	if err != nil {
+		b.Skipf("%s not found", gitCmd)
+	}
+
+	const commits = 100
+	formula, err := readFormula(strings.NewReader(fmt.Sprintf(`
+title: benchmark
+commands:
+  - init_bare_repo:
+      bare: remotes/benchmark
+      clone_to: benchmark
+  - repeat:
+      count: %d
+      body:
+        - generate_file:
+            file: files/file_{{.index}}.txt
+            kind: text
+            lines: 10
+            seed: 1
+        - add:
+            files:
+              - files
+        - commit:
+            message: add file {{.index}}
+            author: red
+`, commits)))
+	if err != nil {
+		b.Fatalf("ERROR: got error: %v", err)
+	}
+
+	baseDir, err := filepath.Abs(filepath.Join(TestDataDir, "benchmark"))
+	if err != nil {
+		b.Fatalf("ERROR: test setup failed: %v", err)
+	}
+	defer os.RemoveAll(baseDir)
+	logger := log.New(io.Discard, "", 0)
+
+	for _, fast := range []bool{false, true} {
+		name := "exec"
+		if fast {
+			name = "fast import"
+		}
+		b.Run(name, func(b *testing.B) {
+			opt := Options{RepoDir: baseDir, CfgDir: ".", TaskDir: TestDataDir, FastImport: fast}
+			for b.Loop() {
+				b.StopTimer()
+				err := os.RemoveAll(baseDir)
+				if err != nil {
+					b.Fatalf("ERROR: test setup failed: %v", err)
+				}
+				b.StartTimer()
+
+				err = Transmute(formula, opt, logger)
+				if err != nil {
+					b.Fatalf("ERROR: got error: %v", err)
+				}
+			}
+			b.ReportMetric(float64(b.Elapsed().Milliseconds())/float64(b.N)/commits, "ms/commit")
+		})
+	}
+}
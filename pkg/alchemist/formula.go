@@ -1,34 +1,71 @@
 package alchemist
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"maps"
 	"os"
+	"path/filepath"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Formula contains the instructions from the gitalchemy.yaml file.
 type Formula struct {
-	Title    string  `yaml:"title"`
-	Commands symbols `yaml:"commands"`
+	Title     string            `yaml:"title"`
+	Variables map[string]string `yaml:"variables"` // default values
+	Commands  symbols           `yaml:"commands"`
 }
 
 // Transmute creates the git repositories according to the formula
 // and the options. Messages are writte to the logger.
+//
+// With FastImport, the history is created with git fast-import unless
+// the formula uses other spells than add, commit, switch, merge, and
+// tag.
+//
+// In test mode, nothing is executed, but the errors that git would
+// report are foreseen. The error of a spell is returned as SpellError.
 func Transmute(f Formula, opt Options, logger *log.Logger) error {
 
 	var helper assistant = newAdept(logger, opt)
 	if opt.Test {
-		helper = newNovice(logger, opt)
+		helper = newSeer(logger, opt)
 	}
+
+	// fall back to casting the spells if fast import is not possible
+	if opt.FastImport && !opt.Test {
+		err := fastImport(f, opt, logger)
+		var unsupported UnsupportedError
+		if !errors.As(err, &unsupported) {
+			return err
+		}
+		helper.info("%v, cast the spells", err)
+	}
+
+	return transmute(f, opt, helper)
+}
+
+// transmute casts the spells of the formula with the assistant.
+func transmute(f Formula, opt Options, helper assistant) error {
+
 	helper.info("execute formula %s", f.Title)
 
 	opt.cloneTo = f.Commands.cloneTo
 	opt.taskName = f.Title
 	opt.numberOfSpells = len(f.Commands.spells)
 
+	// variables from the options override the defaults of the formula
+	variables := maps.Clone(f.Variables)
+	if variables == nil {
+		variables = map[string]string{}
+	}
+	maps.Copy(variables, opt.Variables)
+	opt.Variables = variables
+
 	for i, spell := range f.Commands.spells {
 		if opt.ExecuteSpells > 0 && opt.ExecuteSpells == i {
 			return nil
@@ -37,7 +74,7 @@ func Transmute(f Formula, opt Options, logger *log.Logger) error {
 
 		err := spell.cast(helper, opt)
 		if err != nil {
-			return err
+			return SpellError{Spell: i + 1, Symbol: f.Commands.symbol(i), Err: err}
 		}
 	}
 
@@ -48,6 +85,15 @@ func Transmute(f Formula, opt Options, logger *log.Logger) error {
 type symbols struct {
 	cloneTo string
 	spells  []caster
+	names   []string // symbol of each spell
+}
+
+// symbol returns the symbol of the ith spell.
+func (c symbols) symbol(i int) string {
+	if i >= len(c.names) {
+		return ""
+	}
+	return c.names[i]
 }
 
 // A Formula file can contains these symbols for spells.
@@ -62,6 +108,29 @@ const (
 	symbolPush            = "push"
 	symbolMove            = "mv"
 	symbolRemoveCommit    = "remove_and_commit"
+	symbolRepeat          = "repeat"
+	symbolBisectHistory   = "bisect_history"
+	symbolRewriteHistory  = "rewrite_history"
+	symbolClone           = "clone"
+	symbolFetch           = "fetch"
+	symbolPull            = "pull"
+	symbolRemote          = "remote"
+	symbolServerHook      = "server_hook"
+	symbolHook            = "hook"
+	symbolConfig          = "config"
+	symbolSubmoduleAdd    = "submodule_add"
+	symbolSubtreeAdd      = "subtree_add"
+	symbolSubtreePull     = "subtree_pull"
+	symbolWorktree        = "worktree"
+	symbolSparseCheckout  = "sparse_checkout"
+	symbolBundle          = "bundle"
+	symbolSigningKeys     = "signing_keys"
+	symbolTag             = "tag"
+	symbolChmod           = "chmod"
+	symbolGenerateFile    = "generate_file"
+	symbolSwitch          = "switch"
+	symbolNotes           = "notes"
+	symbolRenormalize     = "renormalize"
 )
 
 // yaml doku
@@ -71,7 +140,17 @@ const (
 // UnmarshalYAML extracts the commands from the yaml definition
 // into a list of casters. It also checks for completeness and
 // correctness.
-func (c *symbols) UnmarshalYAML(value *yaml.Node) (err error) {
+func (c *symbols) UnmarshalYAML(value *yaml.Node) error {
+	return c.unmarshalSpells(value, nil)
+}
+
+// unmarshalSpells extracts the commands of a yaml sequence into the
+// list of casters.
+//
+// The vars are set when the sequence is the body of a repeat spell.
+// In this case, the values of the commands are rendered as templates
+// with these variables.
+func (c *symbols) unmarshalSpells(value *yaml.Node, vars map[string]any) (err error) {
 
 	for i, node := range value.Content {
 		if len(node.Content) < 2 {
@@ -82,12 +161,30 @@ func (c *symbols) UnmarshalYAML(value *yaml.Node) (err error) {
 		var spell caster
 
 		cmd := node.Content[0].Value
+		if cmd == symbolRepeat {
+			err = c.unmarshalRepeat(contentNode, vars)
+			if err != nil {
+				return fmt.Errorf("%s (%d): %w", cmd, i+1, err)
+			}
+			continue
+		}
+
+		if vars != nil {
+			contentNode, err = render(contentNode, vars)
+			if err != nil {
+				return fmt.Errorf("render %s (%d): %w", cmd, i+1, err)
+			}
+		}
+
 		switch cmd {
 		case symbolInit:
 			var initData initRepoSpell
 			initData, err = unmarshalCaster[initRepoSpell](contentNode)
 			// cloneTo is needed in following steps.
-			c.cloneTo = initData.CloneTo
+			// The clones of additional bare repos are used with workspace.
+			if c.cloneTo == "" {
+				c.cloneTo = initData.CloneTo
+			}
 			spell = initData
 		case symbolCreateFile:
 			spell, err = unmarshalCaster[createFileSpell](contentNode)
@@ -107,6 +204,50 @@ func (c *symbols) UnmarshalYAML(value *yaml.Node) (err error) {
 			spell, err = unmarshalCaster[moveSpell](contentNode)
 		case symbolRemoveCommit:
 			spell, err = unmarshalCaster[removeAndCommitSpell](contentNode)
+		case symbolBisectHistory:
+			spell, err = unmarshalCaster[bisectHistorySpell](contentNode)
+		case symbolRewriteHistory:
+			spell, err = unmarshalCaster[rewriteHistorySpell](contentNode)
+		case symbolClone:
+			spell, err = unmarshalCaster[cloneSpell](contentNode)
+		case symbolFetch:
+			spell, err = unmarshalCaster[fetchSpell](contentNode)
+		case symbolPull:
+			spell, err = unmarshalCaster[pullSpell](contentNode)
+		case symbolRemote:
+			spell, err = unmarshalCaster[remoteSpell](contentNode)
+		case symbolServerHook:
+			spell, err = unmarshalCaster[serverHookSpell](contentNode)
+		case symbolHook:
+			spell, err = unmarshalCaster[hookSpell](contentNode)
+		case symbolConfig:
+			spell, err = unmarshalCaster[configSpell](contentNode)
+		case symbolSubmoduleAdd:
+			spell, err = unmarshalCaster[submoduleAddSpell](contentNode)
+		case symbolSubtreeAdd:
+			spell, err = unmarshalCaster[subtreeAddSpell](contentNode)
+		case symbolSubtreePull:
+			spell, err = unmarshalCaster[subtreePullSpell](contentNode)
+		case symbolWorktree:
+			spell, err = unmarshalCaster[worktreeSpell](contentNode)
+		case symbolSparseCheckout:
+			spell, err = unmarshalCaster[sparseCheckoutSpell](contentNode)
+		case symbolBundle:
+			spell, err = unmarshalCaster[bundleSpell](contentNode)
+		case symbolSigningKeys:
+			spell, err = unmarshalCaster[signingKeysSpell](contentNode)
+		case symbolTag:
+			spell, err = unmarshalCaster[tagSpell](contentNode)
+		case symbolChmod:
+			spell, err = unmarshalCaster[chmodSpell](contentNode)
+		case symbolGenerateFile:
+			spell, err = unmarshalCaster[generateFileSpell](contentNode)
+		case symbolSwitch:
+			spell, err = unmarshalCaster[switchSpell](contentNode)
+		case symbolNotes:
+			spell, err = unmarshalCaster[notesSpell](contentNode)
+		case symbolRenormalize:
+			spell, err = unmarshalCaster[renormalizeSpell](contentNode)
 		default:
 			return fmt.Errorf("unkonwn command %q", cmd)
 		}
@@ -122,7 +263,44 @@ func (c *symbols) UnmarshalYAML(value *yaml.Node) (err error) {
 			return fmt.Errorf("validate %s (%d): %w", cmd, i+1, err)
 		}
 
+		// wrap the spell if modifiers like 'when' are used
+		var mod spellModifiers
+		if err := contentNode.Decode(&mod); err != nil {
+			return YamlDecodeError{Element: "modifiers of " + cmd, Err: err}
+		}
+		if mod.isSet() {
+			spell, err = newModifiedSpell(spell, mod)
+			if err != nil {
+				return fmt.Errorf("validate %s (%d): %w", cmd, i+1, err)
+			}
+		}
+
 		c.spells = append(c.spells, spell)
+		c.names = append(c.names, cmd)
+	}
+
+	return nil
+}
+
+// unmarshalRepeat expands the body of a repeat spell into
+// the list of casters.
+func (c *symbols) unmarshalRepeat(node *yaml.Node, vars map[string]any) error {
+
+	var repeat repeatSpell
+	if err := node.Decode(&repeat); err != nil {
+		return YamlDecodeError{Element: fmt.Sprintf("node %T", repeat), Err: err}
+	}
+
+	err := repeat.validate()
+	if err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+
+	for _, iterVars := range repeat.iterations(vars) {
+		err = c.unmarshalSpells(&repeat.Body, iterVars)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -162,3 +340,35 @@ func readFormula(r io.Reader) (Formula, error) {
 	}
 	return result, nil
 }
+
+// scroll is the yaml representation of a generated formula.
+// Each command maps the symbol to the spell.
+type scroll struct {
+	Title    string              `yaml:"title"`
+	Commands []map[string]caster `yaml:"commands"`
+}
+
+// add appends the spell with its symbol to the commands.
+func (s *scroll) add(symbol string, spell caster) {
+	s.Commands = append(s.Commands, map[string]caster{symbol: spell})
+}
+
+// write writes the scroll as formula file into the directory.
+func (s scroll) write(dir string) error {
+
+	file := filepath.Join(dir, FormulaFileName)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	err := enc.Encode(s)
+	if err != nil {
+		return IOError{Cmd: "yaml encode", Arg: file, Err: err}
+	}
+
+	err = os.WriteFile(file, buf.Bytes(), fileMode)
+	if err != nil {
+		return IOError{Cmd: "write", Arg: file, Err: err}
+	}
+	return nil
+}
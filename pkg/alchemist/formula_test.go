@@ -76,6 +76,18 @@ func TestReadFormulaContent(t *testing.T) {
 		name:     "unknown yaml element",
 		fileName: filepath.Join(TestDataDir, "unknown.yaml"),
 		wantErr:  `unkonwn command "not_known"`,
+	}, {
+		name:     "invalid when",
+		fileName: filepath.Join(TestDataDir, "wheninvalid.yaml"),
+		wantErr:  `validate commit (2): value for when: unexpected character '='`,
+	}, {
+		name:     "repeat",
+		fileName: filepath.Join(TestDataDir, "repeat.yaml"),
+		want:     repeatFormula,
+	}, {
+		name:     "repeat unknown variable",
+		fileName: filepath.Join(TestDataDir, "repeatinvalid.yaml"),
+		wantErr:  `repeat (2): render commit (1): value for {{.unknown}}: template: repeat:1:2: executing "repeat" at <.unknown>: map has no entry for key "unknown"`,
 	}}
 
 	for _, c := range testCases {
@@ -84,7 +96,8 @@ func TestReadFormulaContent(t *testing.T) {
 			got, err := Read(c.fileName)
 			check.ErrorString(t, err, c.wantErr)
 
-			diff := cmp.Diff(got, c.want, cmp.AllowUnexported(symbols{}))
+			diff := cmp.Diff(got, c.want, cmp.AllowUnexported(symbols{}),
+				cmpopts.IgnoreFields(symbols{}, "names"))
 			if diff != "" {
 				t.Errorf("ERROR: got-, want+\n%v\n", diff)
 			}
@@ -95,6 +108,11 @@ func TestReadFormulaContent(t *testing.T) {
 // TestFormulaTransmute tests the log messages that a Transmute call emits.
 func TestFormulaTransmute(t *testing.T) {
 
+	seerFormula, err := Read(filepath.Join(TestDataDir, "seer.yaml"))
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+
 	testCases := []struct {
 		name    string
 		formula Formula
@@ -104,24 +122,26 @@ func TestFormulaTransmute(t *testing.T) {
 		wantErr error
 	}{{
 		name:    "normal",
-		formula: completeFormula,
+		formula: seerFormula,
 		verbose: false,
-		want:    wantTestCompleteLog,
+		nSteps:  5,
+		want:    wantTestSeerLog,
 	}, {
 		name:    "verbose",
-		formula: completeFormula,
+		formula: seerFormula,
 		verbose: true,
-		want:    wantTestVerboseLog,
+		nSteps:  5,
+		want:    wantTestSeerVerboseLog,
 	}, {
-		name:    "short",
-		formula: completeFormula,
-		nSteps:  2,
-		want:    wantTestShortLog,
+		name:    "foreseen error",
+		formula: seerFormula,
+		wantErr: SpellError{Spell: 6, Symbol: symbolMerge,
+			Err: ExecError{Cmd: "git", Args: []string{"merge", "hotfix"}}},
 	}, {
 		name:    "error",
 		formula: errorFormula,
 		want:    "",
-		wantErr: ExecError{Cmd: "cast error"},
+		wantErr: SpellError{Spell: 1, Err: ExecError{Cmd: "cast error"}},
 	}}
 
 	for _, c := range testCases {
@@ -238,3 +258,25 @@ var completeFormula = Formula{
 		},
 	},
 }
+
+// repeatFormula corresponds to the content of the file testdata/repeat.yaml.
+var repeatFormula = Formula{
+	Title: "test_repeat",
+	Commands: symbols{
+		cloneTo: "test_repeat",
+		spells: []caster{
+			initRepoSpell{
+				Bare:    "remotes/test_repeat",
+				CloneTo: "test_repeat",
+			},
+			createFileSpell{Source: "files/log_0.txt", Target: "log.txt"},
+			commitSpell{Message: "log entry 1", Author: "red"},
+			createFileSpell{Source: "files/log_1.txt", Target: "log.txt"},
+			commitSpell{Message: "log entry 2", Author: "blue"},
+			gitSpell{Command: `commit --allow-empty -m "api 0"`},
+			gitSpell{Command: `commit --allow-empty -m "api 1"`},
+			gitSpell{Command: `commit --allow-empty -m "config 0"`},
+			gitSpell{Command: `commit --allow-empty -m "config 1"`},
+		},
+	},
+}
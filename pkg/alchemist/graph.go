@@ -0,0 +1,562 @@
+package alchemist
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Formats of a history graph.
+const (
+	GraphMermaid = "mermaid"
+	GraphDot     = "dot"
+)
+
+// graphFormats maps the graph formats to their renderer.
+var graphFormats = map[string]func(title string, t *timeline) string{
+	GraphMermaid: mermaidGraph,
+	GraphDot:     dotGraph,
+}
+
+// detachedLane is the lane of the commits on a detached HEAD.
+const detachedLane = "detached"
+
+// Graph simulates the formula and writes the history of its clone as
+// diagram in the format to w: a Mermaid gitGraph or a Graphviz digraph.
+// Neither git nor the files of the formula are used, but the git
+// version of conditions.
+func Graph(f Formula, opt Options, format string, w io.Writer, logger *log.Logger) error {
+
+	render, ok := graphFormats[format]
+	if !ok {
+		return InvalidValueError{Variable: "format", Reason: "unknown format " + format}
+	}
+
+	c := newCartographer(logger, opt, filepath.Join(opt.RepoDir, f.Commands.cloneTo))
+	err := transmute(f, opt, c)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, render(f.Title, c.timeline))
+	if err != nil {
+		return IOError{Cmd: "write", Arg: format + " graph", Err: err}
+	}
+	return nil
+}
+
+// cartographer is an assistant that does not execute the commands, it
+// follows the git commands of the clone and its worktrees in a
+// timeline. Commands that do not change the history are ignored, so
+// are the commands of other repos. A command that git would reject is
+// skipped with an info.
+// The logging is delegated to the novice.
+//
+// It implements the assistant interface.
+type cartographer struct {
+	novice
+	clone     string                // directory of the clone
+	user      string                // configured user of the clone
+	worktrees map[string]headOfTree // directory -> HEAD of the worktree
+	timeline  *timeline
+}
+
+// headOfTree is the HEAD of a worktree.
+type headOfTree struct {
+	branch   string
+	detached int
+}
+
+// newCartographer returns an initialized cartographer object.
+func newCartographer(l *log.Logger, opt Options, clone string) *cartographer {
+	return &cartographer{
+		novice:    newNovice(l, opt),
+		clone:     filepath.Clean(clone),
+		worktrees: map[string]headOfTree{},
+		timeline:  newTimeline(defaultBranch),
+	}
+}
+
+// git follows the git command in the timeline. The commands of a
+// worktree are followed on its HEAD.
+// It implements the assistant interface.
+func (c *cartographer) git(dir string, args ...string) error {
+	_ = c.novice.git(dir, args...)
+
+	dir = filepath.Clean(dir)
+	t := c.timeline
+	var err error
+	if worktree, ok := c.worktrees[dir]; ok {
+		head, detached := t.head, t.detached
+		t.head, t.detached = worktree.branch, worktree.detached
+		err = c.follow(dir, args)
+		c.worktrees[dir] = headOfTree{branch: t.head, detached: t.detached}
+		t.head, t.detached = head, detached
+	} else if dir == c.clone {
+		err = c.follow(dir, args)
+	}
+
+	if err != nil {
+		c.info("graph: skip git %s: %v", strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// follow follows the git command in the directory of the clone or
+// a worktree.
+func (c *cartographer) follow(dir string, args []string) error {
+
+	if len(args) < 3 || args[0] != "worktree" {
+		return followGit(c.timeline, &c.user, args)
+	}
+
+	flags, names := splitArgs(args[2:], "-b", "-B")
+	path := filepath.Join(dir, names[0])
+	switch {
+	case args[1] == "remove":
+		delete(c.worktrees, path)
+	case args[1] != "add":
+	case flags["-b"] != "" || flags["-B"] != "":
+		branch := flags["-b"] + flags["-B"]
+		base := ""
+		if len(names) > 1 {
+			base = names[1]
+		}
+		err := c.timeline.create(branch, base)
+		if err != nil {
+			return err
+		}
+		c.worktrees[path] = headOfTree{branch: branch}
+	case len(names) > 1:
+		if _, ok := c.timeline.branches[names[1]]; !ok {
+			id, err := c.timeline.resolve(names[1])
+			if err != nil {
+				return err
+			}
+			c.worktrees[path] = headOfTree{detached: id}
+			return nil
+		}
+		c.worktrees[path] = headOfTree{branch: names[1]}
+	default:
+		// git creates a branch with the name of the directory
+		branch := filepath.Base(path)
+		err := c.timeline.create(branch, "")
+		if err != nil {
+			return err
+		}
+		c.worktrees[path] = headOfTree{branch: branch}
+	}
+	return nil
+}
+
+// gitOutput returns the git version, the output of other commands is
+// empty.
+// It implements the assistant interface.
+func (c *cartographer) gitOutput(dir string, args ...string) (string, error) {
+	if len(args) == 1 && args[0] == "version" {
+		return adept{exe: gitCmd}.gitOutput(dir, args...)
+	}
+	return c.novice.gitOutput(dir, args...)
+}
+
+// followGit changes the timeline like the git command changes the
+// history. The user is the configured user of the repo. Commands that
+// do not change the history are ignored.
+func followGit(t *timeline, user *string, args []string) error {
+
+	author := *user
+	for len(args) > 1 && args[0] == "-c" {
+		if name, ok := strings.CutPrefix(args[1], "user.name="); ok {
+			author = name
+		}
+		args = args[2:]
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	withValue := []string{"-m", "-F", "--message", "--file"}
+	if args[0] == "branch" {
+		// -m renames a branch
+		withValue = nil
+	}
+	flags, names := splitArgs(args[1:], withValue...)
+	switch args[0] {
+	case "config":
+		if len(names) == 2 && names[0] == "user.name" {
+			*user = names[1]
+		}
+	case "commit":
+		if name, ok := flags["--author"]; ok {
+			author, _, _ = strings.Cut(name, " <")
+		}
+		_, amend := flags["--amend"]
+		if amend && flags["--author"] == "" {
+			author = ""
+		}
+		return t.add(message(flags), author, amend)
+	case "merge":
+		_, noFF := flags["--no-ff"]
+		_, squash := flags["--squash"]
+		_, abort := flags["--abort"]
+		if squash || abort || len(names) == 0 {
+			return nil
+		}
+		return t.merge(names[0], noFF, message(flags), author)
+	case "switch", "checkout":
+		return followSwitch(t, args[0], flags, names)
+	case "branch":
+		return followBranch(t, flags, names)
+	case "tag":
+		switch {
+		case hasFlag(flags, "-d", "--delete"):
+			for _, name := range names {
+				err := t.deleteTag(name)
+				if err != nil {
+					return err
+				}
+			}
+		case hasFlag(flags, "-l", "--list", "-v", "--verify"):
+		case len(names) == 1:
+			return t.tag(names[0], "")
+		case len(names) == 2:
+			return t.tag(names[0], names[1])
+		}
+	case "reset":
+		if len(names) != 1 || hasFlag(flags, "--") {
+			return nil
+		}
+		if _, err := t.resolve(names[0]); err != nil && !hasFlag(flags, "--hard", "--soft", "--mixed") {
+			// a file is unstaged
+			return nil
+		}
+		return t.reset(names[0])
+	case "cherry-pick":
+		for _, rev := range names {
+			id, err := t.resolve(rev)
+			if err != nil {
+				return err
+			}
+			picked := t.commit(id)
+			err = t.add(picked.message, picked.author, false)
+			if err != nil {
+				return err
+			}
+		}
+	case "revert":
+		for _, rev := range names {
+			id, err := t.resolve(rev)
+			if err != nil {
+				return err
+			}
+			err = t.add(`Revert "`+t.commit(id).message+`"`, author, false)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// followSwitch follows git switch and git checkout. A checkout of
+// files is ignored.
+func followSwitch(t *timeline, cmd string, flags map[string]string, names []string) error {
+
+	if hasFlag(flags, "--") || len(names) == 0 {
+		return nil
+	}
+	branch, base := names[0], ""
+	if len(names) > 1 {
+		base = names[1]
+	}
+
+	switch {
+	case hasFlag(flags, "--orphan"):
+		return t.orphan(branch)
+	case hasFlag(flags, "-c", "--create", "-b") && base == "" && t.current() == 0:
+		// the unborn branch is renamed
+		return t.orphan(branch)
+	case hasFlag(flags, "-c", "--create", "-b"):
+		err := t.create(branch, base)
+		if err != nil {
+			return err
+		}
+		return t.switchTo(branch)
+	case hasFlag(flags, "--detach", "-d"):
+		return t.detach(branch)
+	}
+
+	if _, ok := t.branches[branch]; ok || branch == t.head {
+		return t.switchTo(branch)
+	}
+	if cmd == "checkout" {
+		if _, err := t.resolve(branch); err == nil {
+			return t.detach(branch)
+		}
+		// a file
+		return nil
+	}
+	return t.switchTo(branch)
+}
+
+// followBranch follows git branch.
+func followBranch(t *timeline, flags map[string]string, names []string) error {
+
+	switch {
+	case hasFlag(flags, "-d", "-D", "--delete"):
+		force := hasFlag(flags, "-D", "--force", "-f")
+		for _, name := range names {
+			err := t.deleteBranch(name, force)
+			if err != nil {
+				return err
+			}
+		}
+	case hasFlag(flags, "-m", "-M", "--move"):
+		if len(names) == 1 {
+			return t.renameBranch(t.head, names[0])
+		}
+		if len(names) == 2 {
+			return t.renameBranch(names[0], names[1])
+		}
+	case len(flags) > 0:
+		// list, upstream, and other options
+	case len(names) == 1:
+		return t.create(names[0], "")
+	case len(names) == 2:
+		return t.create(names[0], names[1])
+	}
+	return nil
+}
+
+// splitArgs splits the arguments of a git command into options and
+// names. The options with values are given, their value is the next
+// argument. Other values are given with "=". Arguments after "--"
+// are not returned.
+func splitArgs(args []string, withValue ...string) (map[string]string, []string) {
+
+	flags := map[string]string{}
+	var names []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			flags[arg] = ""
+			return flags, names
+		case slices.Contains(withValue, arg) && i+1 < len(args):
+			// repeated messages are paragraphs
+			if flags[arg] != "" {
+				flags[arg] += "\n\n"
+			}
+			flags[arg] += args[i+1]
+			i++
+		case strings.HasPrefix(arg, "-"):
+			name, value, _ := strings.Cut(arg, "=")
+			flags[name] = value
+		default:
+			names = append(names, arg)
+		}
+	}
+	return flags, names
+}
+
+// hasFlag reports if one of the options is set.
+func hasFlag(flags map[string]string, names ...string) bool {
+	for _, name := range names {
+		if _, ok := flags[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// message returns the commit message of the options -m or -F.
+// A message file that can not be read is ignored.
+func message(flags map[string]string) string {
+	for _, name := range []string{"-m", "--message"} {
+		if flags[name] != "" {
+			return flags[name]
+		}
+	}
+	for _, name := range []string{"-F", "--file"} {
+		if flags[name] != "" {
+			data, err := os.ReadFile(flags[name])
+			if err == nil {
+				return string(data)
+			}
+		}
+	}
+	return ""
+}
+
+// laneOf returns the lane of the commit in the graph.
+func laneOf(t *timeline, id int) string {
+	if branch := t.commit(id).branch; branch != "" {
+		return branch
+	}
+	return detachedLane
+}
+
+// tagsOf returns the tags of each commit.
+func tagsOf(t *timeline) map[int][]string {
+	result := map[int][]string{}
+	for _, name := range slices.Sorted(maps.Keys(t.tags)) {
+		result[t.tags[name]] = append(result[t.tags[name]], name)
+	}
+	return result
+}
+
+// mermaidGraph returns the reachable commits as Mermaid gitGraph.
+// Each commit is drawn on the branch it was created on. A branch
+// starts at the last commit of the branch of its first commit's
+// parent, because gitGraph can only branch from the current commit.
+// gitGraph can not move a branch, so a fast-forward merge is drawn as
+// highlighted merge.
+func mermaidGraph(title string, t *timeline) string {
+
+	var g strings.Builder
+	reachable := t.reachable()
+	tags := tagsOf(t)
+	quote := strings.NewReplacer(`"`, "'", "\n", " ").Replace
+
+	fmt.Fprintf(&g, "---\ntitle: %q\n---\n", title)
+	ids := map[string]bool{}
+	rendered := map[string]bool{}
+	last := map[string]int{} // lane -> last drawn commit
+	current := ""
+
+	// the ids must be unique
+	unique := func(text string) string {
+		name := quote(text)
+		for n := 2; ids[name]; n++ {
+			name = fmt.Sprintf("%s (%d)", quote(text), n)
+		}
+		ids[name] = true
+		return name
+	}
+
+	// forward draws the fast-forward merges that happened before the
+	// commit. A fast-forward to a commit that is not the last one of
+	// its lane can not be drawn.
+	next := 0
+	forward := func(before int) {
+		for ; next < len(t.forwards) && t.forwards[next].after < before; next++ {
+			ff := t.forwards[next]
+			from := laneOf(t, ff.commit)
+			if !reachable[ff.commit] || from == ff.branch || !rendered[ff.branch] || last[from] != ff.commit {
+				continue
+			}
+			if ff.branch != current {
+				fmt.Fprintf(&g, "  checkout %s\n", ff.branch)
+			}
+			current = ff.branch
+			fmt.Fprintf(&g, "  merge %s id: \"%s\" type: HIGHLIGHT\n", from, unique("Fast-forward "+ff.branch))
+			last[ff.branch] = 0
+		}
+	}
+
+	for id := 1; id <= len(t.commits); id++ {
+		if !reachable[id] {
+			continue
+		}
+		forward(id)
+		c := t.commit(id)
+		lane := laneOf(t, id)
+
+		switch {
+		case current == "":
+			if lane != defaultBranch {
+				fmt.Fprintf(&g, "%%%%{init: {'gitGraph': {'mainBranchName': '%s'}}}%%%%\n", lane)
+			}
+			g.WriteString("gitGraph\n")
+			rendered[lane] = true
+		case !rendered[lane]:
+			if len(c.parents) == 0 {
+				fmt.Fprintf(&g, "  %%%% %s is an orphan branch\n", lane)
+			} else if from := laneOf(t, c.parents[0]); from != current && rendered[from] {
+				fmt.Fprintf(&g, "  checkout %s\n", from)
+			}
+			fmt.Fprintf(&g, "  branch %s\n", lane)
+			rendered[lane] = true
+		case lane != current:
+			fmt.Fprintf(&g, "  checkout %s\n", lane)
+		}
+		current = lane
+
+		command := "commit"
+		if len(c.parents) > 1 {
+			if from := laneOf(t, c.parents[1]); from != lane && rendered[from] {
+				command = "merge " + from
+			}
+		}
+		fmt.Fprintf(&g, "  %s id: \"%s\"", command, unique(c.message))
+		if len(tags[id]) > 0 {
+			fmt.Fprintf(&g, " tag: \"%s\"", quote(strings.Join(tags[id], ", ")))
+		}
+		g.WriteString("\n")
+		last[lane] = id
+	}
+	forward(len(t.commits) + 1)
+
+	if current == "" {
+		g.WriteString("gitGraph\n")
+	}
+	return g.String()
+}
+
+// dotGraph returns the reachable commits, the branches, the tags, and
+// HEAD as Graphviz digraph. The edges point from the parents to the
+// children.
+func dotGraph(title string, t *timeline) string {
+
+	var g strings.Builder
+	reachable := t.reachable()
+
+	fmt.Fprintf(&g, "digraph %q {\n", title)
+	g.WriteString("  rankdir=LR;\n")
+	g.WriteString("  node [shape=box, style=rounded];\n")
+
+	for id := 1; id <= len(t.commits); id++ {
+		if !reachable[id] {
+			continue
+		}
+		c := t.commit(id)
+		label := c.message
+		if c.author != "" {
+			label += "\n" + c.author
+		}
+		fmt.Fprintf(&g, "  c%d [label=%q];\n", id, label)
+		for _, parent := range c.parents {
+			fmt.Fprintf(&g, "  c%d -> c%d;\n", parent, id)
+		}
+	}
+
+	refs := []struct {
+		kind, color string
+		refs        map[string]int
+	}{
+		{"branch", "lightblue", t.branches},
+		{"tag", "lightyellow", t.tags},
+	}
+	for _, r := range refs {
+		for _, name := range slices.Sorted(maps.Keys(r.refs)) {
+			node := r.kind + " " + name
+			fmt.Fprintf(&g, "  %q [label=%q, shape=box, style=filled, fillcolor=%s];\n", node, name, r.color)
+			fmt.Fprintf(&g, "  %q -> c%d [style=dashed, arrowhead=none];\n", node, r.refs[name])
+		}
+	}
+
+	g.WriteString("  HEAD [shape=plaintext];\n")
+	switch {
+	case t.head == "":
+		fmt.Fprintf(&g, "  HEAD -> c%d [style=dashed];\n", t.detached)
+	case t.branches[t.head] != 0:
+		fmt.Fprintf(&g, "  HEAD -> %q [style=dashed];\n", "branch "+t.head)
+	}
+	g.WriteString("}\n")
+	return g.String()
+}
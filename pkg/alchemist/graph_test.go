@@ -0,0 +1,294 @@
+package alchemist
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// graphFormula branches, merges, and tags.
+const graphFormula = `
+title: graph
+commands:
+  - init_bare_repo:
+      bare: remotes/graph
+      clone_to: graph
+  - create_add_commit:
+      files:
+        - source.txt => source.txt
+      message: add source
+      author: blue
+  - switch:
+      branch: feature
+      create: true
+  - create_add_commit:
+      files:
+        - source.txt => feature.txt
+      message: add feature
+      author: red
+  - switch:
+      branch: main
+  - create_add_commit:
+      files:
+        - source.txt => plan.txt
+      message: add plan
+      author: blue
+  - merge:
+      source: feature
+      target: main
+      delete_source: true
+  - tag:
+      name: v1
+`
+
+// fastForwardFormula merges a branch with a fast-forward.
+const fastForwardFormula = `
+title: fast forward
+commands:
+  - init_bare_repo:
+      bare: remotes/forward
+      clone_to: forward
+  - create_add_commit:
+      files:
+        - source.txt => source.txt
+      message: add source
+      author: blue
+  - switch:
+      branch: feature
+      create: true
+  - create_add_commit:
+      files:
+        - source.txt => feature.txt
+      message: add feature
+      author: red
+  - merge:
+      source: feature
+      target: main
+  - create_add_commit:
+      files:
+        - source.txt => plan.txt
+      message: add plan
+      author: blue
+`
+
+// TestGraph tests drawing the history of a formula.
+func TestGraph(t *testing.T) {
+
+	testCases := []struct {
+		name    string
+		formula string
+		format  string
+		want    string
+	}{{
+		name:    "mermaid",
+		formula: graphFormula,
+		format:  GraphMermaid,
+		want: `---
+title: "graph"
+---
+gitGraph
+  commit id: "add source"
+  branch feature
+  commit id: "add feature"
+  checkout main
+  commit id: "add plan"
+  merge feature id: "Merge branch 'feature'" tag: "v1"
+`,
+	}, {
+		name:    "dot",
+		formula: graphFormula,
+		format:  GraphDot,
+		want: `digraph "graph" {
+  rankdir=LR;
+  node [shape=box, style=rounded];
+  c1 [label="add source\nBetty Blue"];
+  c2 [label="add feature\nRichard Red"];
+  c1 -> c2;
+  c3 [label="add plan\nBetty Blue"];
+  c1 -> c3;
+  c4 [label="Merge branch 'feature'\nRichard Red"];
+  c3 -> c4;
+  c2 -> c4;
+  "branch main" [label="main", shape=box, style=filled, fillcolor=lightblue];
+  "branch main" -> c4 [style=dashed, arrowhead=none];
+  "tag v1" [label="v1", shape=box, style=filled, fillcolor=lightyellow];
+  "tag v1" -> c4 [style=dashed, arrowhead=none];
+  HEAD [shape=plaintext];
+  HEAD -> "branch main" [style=dashed];
+}
+`,
+	}, {
+		name:    "mermaid fast forward",
+		formula: fastForwardFormula,
+		format:  GraphMermaid,
+		want: `---
+title: "fast forward"
+---
+gitGraph
+  commit id: "add source"
+  branch feature
+  commit id: "add feature"
+  checkout main
+  merge feature id: "Fast-forward main" type: HIGHLIGHT
+  commit id: "add plan"
+`,
+	}, {
+		name:    "dot fast forward",
+		formula: fastForwardFormula,
+		format:  GraphDot,
+		want: `digraph "fast forward" {
+  rankdir=LR;
+  node [shape=box, style=rounded];
+  c1 [label="add source\nBetty Blue"];
+  c2 [label="add feature\nRichard Red"];
+  c1 -> c2;
+  c3 [label="add plan\nBetty Blue"];
+  c2 -> c3;
+  "branch feature" [label="feature", shape=box, style=filled, fillcolor=lightblue];
+  "branch feature" -> c2 [style=dashed, arrowhead=none];
+  "branch main" [label="main", shape=box, style=filled, fillcolor=lightblue];
+  "branch main" -> c3 [style=dashed, arrowhead=none];
+  HEAD [shape=plaintext];
+  HEAD -> "branch main" [style=dashed];
+}
+`,
+	}}
+
+	opt := Options{RepoDir: "cwd", CfgDir: ".", TaskDir: TestDataDir}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			formula, err := readFormula(strings.NewReader(c.formula))
+			if err != nil {
+				t.Fatalf("ERROR: got error: %v", err)
+			}
+
+			var got bytes.Buffer
+			err = Graph(formula, opt, c.format, &got, log.New(io.Discard, "", 0))
+			if err != nil {
+				t.Fatalf("ERROR: got error: %v", err)
+			}
+			if diff := cmp.Diff(got.String(), c.want); diff != "" {
+				t.Errorf("ERROR: got- want+\n%s\n", diff)
+			}
+		})
+	}
+}
+
+// TestGraphFormat tests an unknown format.
+func TestGraphFormat(t *testing.T) {
+	err := Graph(Formula{}, Options{}, "svg", io.Discard, log.New(io.Discard, "", 0))
+	var invalid InvalidValueError
+	if !errors.As(err, &invalid) || invalid.Variable != "format" {
+		t.Errorf("ERROR: got %v, want invalid format", err)
+	}
+}
+
+// TestFollowGit tests following git commands in a timeline.
+func TestFollowGit(t *testing.T) {
+
+	testCases := []struct {
+		name     string
+		commands [][]string
+		want     string // messages of the commits reachable from HEAD
+		wantErr  string
+	}{{
+		name: "commit and amend",
+		commands: [][]string{
+			{"commit", "-m", "first"},
+			{"commit", "-m", "second"},
+			{"commit", "--amend", "-m", "amended"},
+		},
+		want: "amended first",
+	}, {
+		name: "reset",
+		commands: [][]string{
+			{"commit", "-m", "first"},
+			{"commit", "-m", "second"},
+			{"reset", "--hard", "HEAD~1"},
+		},
+		want: "first",
+	}, {
+		name: "unborn branch",
+		commands: [][]string{
+			{"checkout", "-b", "feature"},
+			{"commit", "-m", "first"},
+			{"branch", "-m", "feature", "topic"},
+			{"switch", "topic"},
+		},
+		want: "first",
+	}, {
+		name: "fast forward",
+		commands: [][]string{
+			{"commit", "-m", "first"},
+			{"switch", "-c", "feature"},
+			{"commit", "-m", "second"},
+			{"switch", "main"},
+			{"merge", "feature"},
+			{"branch", "-d", "feature"},
+		},
+		want: "second first",
+	}, {
+		name: "revert by message",
+		commands: [][]string{
+			{"commit", "-m", "first"},
+			{"commit", "-m", "second"},
+			{"revert", ":/first"},
+		},
+		want: `Revert "first" second first`,
+	}, {
+		name: "unmerged branch",
+		commands: [][]string{
+			{"commit", "-m", "first"},
+			{"switch", "-c", "feature"},
+			{"commit", "-m", "second"},
+			{"switch", "main"},
+			{"branch", "-d", "feature"},
+		},
+		wantErr: "branch feature is not fully merged",
+	}, {
+		name: "unknown revision",
+		commands: [][]string{
+			{"commit", "-m", "first"},
+			{"merge", "feature"},
+		},
+		wantErr: "unknown revision feature",
+	}}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			tl := newTimeline(defaultBranch)
+			user := ""
+			var err error
+			for _, args := range c.commands {
+				err = followGit(tl, &user, args)
+				if err != nil {
+					break
+				}
+			}
+			if c.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Errorf("ERROR: got %v, want %q", err, c.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ERROR: got error: %v", err)
+			}
+
+			messages := []string{}
+			for id := tl.current(); id != 0; id = tl.parent(id, 1) {
+				messages = append(messages, tl.commit(id).message)
+			}
+			got := strings.Join(messages, " ")
+			if got != c.want {
+				t.Errorf("ERROR: got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package alchemist
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// rawChange is a changed file of a commit as reported by
+// git diff-tree --raw.
+type rawChange struct {
+	Status  byte   // A, C, D, M, R, or T
+	Score   string // similarity of renames and copies, e.g. 100
+	OldMode string // mode of the old file
+	Mode    string // mode of the new file, e.g. 100644
+	ID      string // blob of the new file
+	Path    string // slash separated
+	OldPath string // renames and copies only
+}
+
+// parseRawDiff parses the output of git diff-tree -r -z.
+// The format is ":<old mode> <new mode> <old id> <new id> <status>\0<path>\0",
+// renames and copies have the old and the new path.
+func parseRawDiff(diff string) []rawChange {
+
+	var changes []rawChange
+	fields := strings.Split(diff, "\x00")
+	for i := 0; i+1 < len(fields); {
+		meta := strings.Fields(strings.TrimPrefix(fields[i], ":"))
+		if len(meta) < 5 || meta[4] == "" {
+			i++
+			continue
+		}
+
+		change := rawChange{Status: meta[4][0], Score: meta[4][1:],
+			OldMode: meta[0], Mode: meta[1], ID: meta[3]}
+		if (change.Status == 'R' || change.Status == 'C') && i+2 < len(fields) {
+			change.OldPath, change.Path = fields[i+1], fields[i+2]
+			i += 3
+		} else {
+			change.Path = fields[i+1]
+			i += 2
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// outputFunc executes git with the arguments and returns its output.
+type outputFunc func(args ...string) (string, error)
+
+// writeBlob writes the content of the blob to the target file.
+// Executables and symbolic links are preserved. It reports false
+// for submodules, they are not written.
+func writeBlob(output outputFunc, target, mode, id string) (bool, error) {
+
+	if mode == "160000" {
+		return false, nil
+	}
+
+	content, err := output("cat-file", "blob", id)
+	if err != nil {
+		return false, err
+	}
+
+	a := newAdept(log.New(io.Discard, "", 0), Options{})
+
+	switch mode {
+	case "120000":
+		err = a.makedir(filepath.Dir(target))
+		if err != nil {
+			return false, err
+		}
+		err = os.Symlink(content, target)
+		if err != nil {
+			return false, IOError{Cmd: "symlink", Arg: target, Err: err}
+		}
+	case "100755":
+		err = a.writeFile(target, []byte(content), executableMode)
+	default:
+		err = a.writeFile(target, []byte(content), fileMode)
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// authorKey returns the key of the author in the registry. The author
+// is matched by name and email, by email, or by name. If the author is
+// unknown, it is returned unchanged.
+func authorKey(nameEmail string) string {
+
+	name, mail, _ := strings.Cut(nameEmail, " <")
+	mail = strings.TrimSuffix(mail, ">")
+
+	var byEmail, byName string
+	for key := range author {
+		switch {
+		case getAuthor(key) == nameEmail:
+			return key
+		case email[key] == mail:
+			byEmail = key
+		case author[key] == name:
+			byName = key
+		}
+	}
+	if byEmail != "" {
+		return byEmail
+	}
+	if byName != "" {
+		return byName
+	}
+	return nameEmail
+}
+
+// checkNoFormula returns an error if the directory contains a formula.
+func checkNoFormula(dir string) error {
+	_, err := os.Stat(filepath.Join(dir, FormulaFileName))
+	if err == nil {
+		return InvalidValueError{Variable: "dir",
+			Reason: dir + " already contains " + FormulaFileName}
+	}
+	return nil
+}
+
+// emptyCommitSpell returns the spell for a commit without changes.
+func emptyCommitSpell(message, author string) gitSpell {
+	return gitSpell{Command: joinArgs([]string{"commit", "--allow-empty",
+		"-m", message, "--author=" + getAuthor(author)})}
+}
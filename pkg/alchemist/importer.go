@@ -0,0 +1,443 @@
+package alchemist
+
+import (
+	"log"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// importer provides turning the history of a repo into a scroll.
+//
+// Each commit belongs to the branch whose first parent chain contains
+// it, the branch of HEAD is considered first. The commits are replayed
+// in topological order. A branch is created at the first parent of its
+// first commit, which is referenced relative to the tip of its branch
+// at that point of the formula, e.g. main~2.
+// Merged branches that were deleted are replayed as branches named
+// after the merge, they are deleted at the end.
+type importer struct {
+	a       assistant
+	output  outputFunc // git in the repo
+	taskDir string
+	scroll  scroll
+
+	label   map[string]string     // commit -> branch
+	chains  map[string][]string   // replayed commits of the branches
+	tags    map[string][]tagSpell // commit -> tags
+	merged  []string              // generated names of deleted branches
+	current string                // current branch of the replay
+	number  int                   // number of the commit, directory of its files
+}
+
+// Import reads the commits of all local branches and the tags of the
+// repo and writes them as formula into the task dir. The files of a
+// commit are written to files/<number of the commit>.
+// The task dir must not contain a formula.
+func Import(repo, taskDir string, logger *log.Logger) error {
+
+	err := checkNoFormula(taskDir)
+	if err != nil {
+		return err
+	}
+	taskDir, err = filepath.Abs(taskDir)
+	if err != nil {
+		return IOError{Cmd: "abs", Arg: taskDir, Err: err}
+	}
+
+	a := newAdept(logger, Options{})
+	a.info("import %s into %s", repo, taskDir)
+
+	title := filepath.Base(taskDir)
+	im := importer{
+		a: a,
+		output: func(args ...string) (string, error) {
+			return a.gitOutput(repo, args...)
+		},
+		taskDir: taskDir,
+		scroll:  scroll{Title: title},
+		label:   map[string]string{},
+		chains:  map[string][]string{},
+		tags:    map[string][]tagSpell{},
+		current: defaultBranch,
+	}
+	im.scroll.add(symbolInit, initRepoSpell{Bare: path.Join(recordBareDir, title), CloneTo: title})
+
+	err = a.makedir(taskDir)
+	if err != nil {
+		return err
+	}
+	err = im.run()
+	if err != nil {
+		return err
+	}
+
+	a.info("write %d commits", im.number)
+	return im.scroll.write(taskDir)
+}
+
+// run replays all commits of the repo.
+func (im *importer) run() error {
+
+	head, _ := im.output("symbolic-ref", "--quiet", "--short", "HEAD")
+	head = strings.TrimSpace(head)
+
+	refs, err := im.output("for-each-ref", "--format=%(refname:short)", "refs/heads")
+	if err != nil {
+		return err
+	}
+	branches := strings.Fields(refs)
+	if len(branches) == 0 {
+		return InvalidValueError{Variable: "repo", Reason: "no branches to import"}
+	}
+	if i := slices.Index(branches, head); i > 0 {
+		branches = append([]string{head}, slices.Delete(branches, i, i+1)...)
+	}
+
+	for _, branch := range branches {
+		chain, err := im.output("rev-list", "--first-parent", "refs/heads/"+branch)
+		if err != nil {
+			return err
+		}
+		for _, commit := range strings.Fields(chain) {
+			if _, ok := im.label[commit]; ok {
+				break
+			}
+			im.label[commit] = branch
+		}
+	}
+
+	args := []string{"rev-list", "--topo-order", "--parents"}
+	for _, branch := range branches {
+		args = append(args, "refs/heads/"+branch)
+	}
+	list, err := im.output(args...)
+	if err != nil {
+		return err
+	}
+	var commits [][]string // commit and parents, children first
+	for _, line := range strings.Split(strings.TrimSpace(list), "\n") {
+		commits = append(commits, strings.Fields(line))
+	}
+	im.labelMerged(commits, branches)
+
+	err = im.readTags()
+	if err != nil {
+		return err
+	}
+
+	for _, ids := range slices.Backward(commits) {
+		err = im.replay(ids[0], ids[1:])
+		if err != nil {
+			return err
+		}
+	}
+
+	// branches without own commits, e.g. after a fast forward merge
+	for _, branch := range branches {
+		if len(im.chains[branch]) > 0 {
+			continue
+		}
+		tip, err := im.output("rev-parse", "refs/heads/"+branch)
+		if err != nil {
+			return err
+		}
+		im.scroll.add(symbolSwitch, switchSpell{Branch: branch, Create: true,
+			Base: im.ref(strings.TrimSpace(tip))})
+		im.current = branch
+	}
+
+	if head != "" && head != im.current {
+		im.scroll.add(symbolSwitch, switchSpell{Branch: head})
+		im.current = head
+	}
+	for _, branch := range im.merged {
+		if branch != im.current {
+			im.scroll.add(symbolGit, gitSpell{Command: joinArgs([]string{"branch", "-D", branch})})
+		}
+	}
+	return nil
+}
+
+// regexpMergeBranch matches the default message of a merge and
+// captures the merged branch.
+var regexpMergeBranch = regexp.MustCompile(`^Merge (?:remote-tracking )?branch '([^']+)'`)
+
+// labelMerged labels the commits that are not on the first parent
+// chain of a branch. They were merged from branches that were deleted
+// afterwards. Each further parent of a merge that is not labeled
+// starts the first parent chain of such a branch. The commits are
+// visited children first, so nested merges are found, too.
+func (im *importer) labelMerged(commits [][]string, branches []string) {
+
+	parents := map[string][]string{}
+	for _, ids := range commits {
+		parents[ids[0]] = ids[1:]
+	}
+
+	for _, ids := range commits {
+		if len(ids) < 3 {
+			continue
+		}
+		for _, parent := range ids[2:] {
+			if _, ok := im.label[parent]; ok {
+				continue
+			}
+			name := im.mergedName(ids[0], slices.Concat(branches, im.merged))
+			im.merged = append(im.merged, name)
+			im.a.info("label deleted branch merged by %s as %s", ids[0], name)
+
+			for commit := parent; ; commit = parents[commit][0] {
+				if _, ok := im.label[commit]; ok {
+					break
+				}
+				im.label[commit] = name
+				if len(parents[commit]) == 0 {
+					break
+				}
+			}
+		}
+	}
+}
+
+// mergedName returns a name for a deleted branch of the merge. The
+// name of the merge message is used if it is valid and not used yet,
+// otherwise merged-<n>.
+func (im *importer) mergedName(merge string, used []string) string {
+
+	subject, err := im.output("log", "-1", "--format=%s", merge)
+	match := regexpMergeBranch.FindStringSubmatch(subject)
+	if err == nil && match != nil && !slices.Contains(used, match[1]) {
+		_, err = im.output("check-ref-format", "--branch", match[1])
+		if err == nil {
+			return match[1]
+		}
+	}
+
+	for n := 1; ; n++ {
+		name := "merged-" + strconv.Itoa(n)
+		if !slices.Contains(used, name) {
+			return name
+		}
+	}
+}
+
+// readTags reads the tags that point to commits.
+// Tags of commits that are not reachable from a branch, e.g. of a
+// deleted branch that was never merged, are skipped. These commits
+// are not replayed.
+func (im *importer) readTags() error {
+
+	names, err := im.output("for-each-ref", "--format=%(refname:short)", "refs/tags")
+	if err != nil {
+		return err
+	}
+
+	for _, name := range strings.Fields(names) {
+		commit, err := im.output("rev-parse", "--verify", "--quiet", name+"^{commit}")
+		commit = strings.TrimSpace(commit)
+		if _, ok := im.label[commit]; err != nil || !ok {
+			im.a.info("skip tag %s, it does not point to a commit of a branch", name)
+			continue
+		}
+
+		tag := tagSpell{Name: name}
+		info, err := im.output("for-each-ref", "--format=%(objecttype)%00%(taggername) "+
+			"%(taggeremail)%00%(contents:subject)%00%(contents:body)", "refs/tags/"+name)
+		if err != nil {
+			return err
+		}
+		fields := strings.SplitN(strings.TrimSuffix(info, "\n"), "\x00", 4)
+		if len(fields) == 4 && fields[0] == "tag" {
+			tag.Author = authorKey(fields[1])
+			tag.Message = strings.TrimRight(fields[2]+"\n\n"+fields[3], "\n")
+		}
+		im.tags[commit] = append(im.tags[commit], tag)
+	}
+	return nil
+}
+
+// replay adds the spells for the commit with its parents.
+func (im *importer) replay(commit string, parents []string) error {
+
+	im.number++
+	branch := im.label[commit]
+
+	switch {
+	case len(im.chains[branch]) > 0:
+		if im.current != branch {
+			im.scroll.add(symbolSwitch, switchSpell{Branch: branch})
+		}
+	case len(parents) == 0:
+		// the clone starts on the unborn default branch
+		if len(im.chains) > 0 || branch != defaultBranch {
+			im.scroll.add(symbolSwitch, switchSpell{Branch: branch, Orphan: true})
+		}
+	default:
+		im.scroll.add(symbolSwitch, switchSpell{Branch: branch, Create: true,
+			Base: im.ref(parents[0])})
+	}
+	im.current = branch
+
+	info, err := im.output("log", "-1", "--format=%an <%ae>%x00%B", commit)
+	if err != nil {
+		return err
+	}
+	author, message, _ := strings.Cut(info, "\x00")
+	author = authorKey(author)
+	message = strings.TrimRight(message, "\n")
+
+	if len(parents) > 1 {
+		err = im.merge(commit, parents, message, author)
+	} else {
+		err = im.commit(commit, parents, message, author)
+	}
+	if err != nil {
+		return err
+	}
+
+	im.chains[branch] = append(im.chains[branch], commit)
+	for _, tag := range im.tags[commit] {
+		im.scroll.add(symbolTag, tag)
+	}
+	return nil
+}
+
+// ref returns a reference to a replayed commit relative to the
+// current tip of its branch.
+func (im *importer) ref(commit string) string {
+	branch := im.label[commit]
+	chain := im.chains[branch]
+	steps := len(chain) - 1 - slices.Index(chain, commit)
+	if steps == 0 {
+		return branch
+	}
+	return branch + "~" + strconv.Itoa(steps)
+}
+
+// commit adds the spells for a commit with at most one parent.
+func (im *importer) commit(commit string, parents []string, message, author string) error {
+
+	args := []string{"diff-tree", "-r", "-z", "-M", "--no-commit-id"}
+	if len(parents) == 0 {
+		args = append(args, "--root", commit)
+	} else {
+		args = append(args, parents[0], commit)
+	}
+	diff, err := im.output(args...)
+	if err != nil {
+		return err
+	}
+
+	return im.changes(parseRawDiff(diff), message, author, false)
+}
+
+// merge adds the spells for a merge commit. A merge without conflicts
+// is replayed with a merge spell. Otherwise the other parents are
+// merged without changes and the result is committed.
+func (im *importer) merge(commit string, parents []string, message, author string) error {
+
+	if len(parents) == 2 && im.cleanMerge(commit, parents[0], parents[1]) {
+		im.scroll.add(symbolMerge, mergeSpell{Source: im.ref(parents[1]), Target: im.current,
+			NoFF: true, Message: message, Author: author})
+		return nil
+	}
+
+	args := []string{"merge", "--no-ff", "--no-commit", "--strategy=ours"}
+	for _, parent := range parents[1:] {
+		args = append(args, im.ref(parent))
+	}
+	im.scroll.add(symbolGit, gitSpell{Command: joinArgs(args)})
+
+	diff, err := im.output("diff-tree", "-r", "-z", "-M", "--no-commit-id", parents[0], commit)
+	if err != nil {
+		return err
+	}
+	return im.changes(parseRawDiff(diff), message, author, true)
+}
+
+// cleanMerge reports if git merges the parents to the tree of the commit.
+func (im *importer) cleanMerge(commit, first, second string) bool {
+	merged, err := im.output("merge-tree", "--write-tree", first, second)
+	if err != nil {
+		return false
+	}
+	tree, err := im.output("rev-parse", commit+"^{tree}")
+	if err != nil {
+		return false
+	}
+	fields := strings.Fields(merged)
+	return len(fields) > 0 && fields[0] == strings.TrimSpace(tree)
+}
+
+// changes adds the spells that commit the changes. The changed files
+// are written to files/<number>. Pure additions and modifications
+// become create_add_commit, pure deletions remove_and_commit, and
+// renames within a directory mv.
+func (im *importer) changes(changes []rawChange, message, author string, merging bool) error {
+
+	var pairs, added, deleted []string
+	var moves []moveSpell
+	for _, change := range changes {
+		switch change.Status {
+		case 'D':
+			deleted = append(deleted, change.Path)
+			continue
+		case 'R':
+			// git mv needs an existing target directory
+			if path.Dir(change.OldPath) != path.Dir(change.Path) {
+				deleted = append(deleted, change.OldPath)
+				break
+			}
+			moves = append(moves, moveSpell{Source: change.OldPath, Target: change.Path})
+			if change.Score == "100" && change.OldMode == change.Mode {
+				continue
+			}
+		}
+
+		source := path.Join(recordFilesDir, strconv.Itoa(im.number), change.Path)
+		ok, err := writeBlob(im.output, filepath.Join(im.taskDir, filepath.FromSlash(source)),
+			change.Mode, change.ID)
+		if err != nil {
+			return err
+		}
+		if ok {
+			pairs = append(pairs, source+" => "+change.Path)
+			added = append(added, change.Path)
+		}
+	}
+
+	switch {
+	case merging:
+	case len(pairs) == 0 && len(deleted) == 0 && len(moves) == 0:
+		im.scroll.add(symbolGit, emptyCommitSpell(message, author))
+		return nil
+	case len(deleted) == 0 && len(moves) == 0:
+		im.scroll.add(symbolCreateAddCommit, createAddCommitSpell{Files: pairs,
+			Message: message, Author: author})
+		return nil
+	case len(pairs) == 0 && len(moves) == 0:
+		im.scroll.add(symbolRemoveCommit, removeAndCommitSpell{Files: deleted,
+			Message: message, Author: author})
+		return nil
+	}
+
+	if len(deleted) > 0 {
+		im.scroll.add(symbolGit, gitSpell{Command: joinArgs(append([]string{"rm", "--quiet", "--"}, deleted...))})
+	}
+	for _, move := range moves {
+		im.scroll.add(symbolMove, move)
+	}
+	for i, pair := range pairs {
+		source, _, _ := strings.Cut(pair, " => ")
+		im.scroll.add(symbolCreateFile, createFileSpell{Source: source, Target: added[i]})
+	}
+	if len(added) > 0 {
+		im.scroll.add(symbolAdd, addSpell{Files: added})
+	}
+	im.scroll.add(symbolCommit, commitSpell{Message: message, Author: author})
+	return nil
+}
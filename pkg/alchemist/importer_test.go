@@ -0,0 +1,332 @@
+package alchemist
+
+import (
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestParseRawDiff tests parsing the output of git diff-tree -z.
+func TestParseRawDiff(t *testing.T) {
+
+	diff := ":000000 100644 0000 1111 A\x00new.txt\x00" +
+		":100644 100755 2222 3333 R090\x00old.sh\x00new.sh\x00" +
+		":100644 000000 4444 0000 D\x00gone.txt\x00"
+
+	want := []rawChange{
+		{Status: 'A', Score: "", OldMode: "000000", Mode: "100644", ID: "1111", Path: "new.txt"},
+		{Status: 'R', Score: "090", OldMode: "100644", Mode: "100755", ID: "3333",
+			Path: "new.sh", OldPath: "old.sh"},
+		{Status: 'D', Score: "", OldMode: "100644", Mode: "000000", ID: "0000", Path: "gone.txt"},
+	}
+
+	got := parseRawDiff(diff)
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("ERROR: got- want+\n%s\n", diff)
+	}
+}
+
+// TestAuthorKey tests mapping authors into the registry.
+func TestAuthorKey(t *testing.T) {
+
+	testCases := []struct {
+		name   string
+		author string
+		want   string
+	}{{
+		name:   "name and email",
+		author: getAuthor("blue"),
+		want:   "blue",
+	}, {
+		name:   "email",
+		author: "Betty <" + email["blue"] + ">",
+		want:   "blue",
+	}, {
+		name:   "name",
+		author: author["green"] + " <garry@example.com>",
+		want:   "green",
+	}, {
+		name:   "unknown",
+		author: "Eve <eve@example.com>",
+		want:   "Eve <eve@example.com>",
+	}}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			got := authorKey(c.author)
+			if got != c.want {
+				t.Errorf("ERROR: got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+// TestImport creates a repo in testdata, imports it, casts the formula
+// and compares the trees, authors, and messages of all refs.
+func TestImport(t *testing.T) {
+
+	_, err := exec.LookPath(gitCmd)
+	if err != nil {
+		t.Skipf("%s not found", gitCmd)
+	}
+
+	baseDir, err := filepath.Abs(filepath.Join(TestDataDir, "import"))
+	if err != nil {
+		t.Fatalf("ERROR: test setup failed: %v", err)
+	}
+	err = os.RemoveAll(baseDir)
+	if err != nil {
+		t.Fatalf("ERROR: test setup failed: %v", err)
+	}
+	if !testing.Verbose() {
+		defer os.RemoveAll(baseDir)
+	}
+
+	source := filepath.Join(baseDir, "source")
+	createImportSource(t, source)
+	replay := importAndCast(t, baseDir, source)
+
+	for _, args := range [][]string{
+		{"symbolic-ref", "HEAD"},
+		{"for-each-ref", "--format=%(refname) %(objecttype) %(taggername) %(contents)"},
+		{"log", "--topo-order", "--format=@%T %an|%p%n%B", "main"},
+		{"log", "--topo-order", "--format=%T %an%n%B", "feature"},
+		{"log", "--topo-order", "--format=%T %an%n%B", "pages"},
+		{"rev-parse", "hotfix^{tree}"},
+		{"ls-tree", "-r", "main"},
+	} {
+		want := importGit(t, source, args...)
+		got := importGit(t, replay, args...)
+		if args[0] == "log" {
+			// parent ids differ, just count them
+			want, got = countParents(want), countParents(got)
+		}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("ERROR: %v: got- want+\n%s\n", args, diff)
+		}
+	}
+}
+
+// TestImportDeletedBranch tests importing branches that were merged
+// and deleted. They are replayed as branches named after the merge
+// and deleted afterwards.
+func TestImportDeletedBranch(t *testing.T) {
+
+	_, err := exec.LookPath(gitCmd)
+	if err != nil {
+		t.Skipf("%s not found", gitCmd)
+	}
+
+	baseDir, err := filepath.Abs(filepath.Join(TestDataDir, "import_deleted"))
+	if err != nil {
+		t.Fatalf("ERROR: test setup failed: %v", err)
+	}
+	err = os.RemoveAll(baseDir)
+	if err != nil {
+		t.Fatalf("ERROR: test setup failed: %v", err)
+	}
+	if !testing.Verbose() {
+		defer os.RemoveAll(baseDir)
+	}
+
+	source := filepath.Join(baseDir, "source")
+	err = os.MkdirAll(source, dirMode)
+	if err != nil {
+		t.Fatalf("ERROR: test setup failed: %v", err)
+	}
+	git := func(args ...string) {
+		t.Helper()
+		config := []string{"-c", "user.name=" + author["red"], "-c", "user.email=" + email["red"]}
+		importGit(t, source, append(config, args...)...)
+	}
+	commit := func(file, message string) {
+		t.Helper()
+		err := os.WriteFile(filepath.Join(source, file), []byte(message+"\n"), fileMode)
+		if err != nil {
+			t.Fatalf("ERROR: test setup failed: %v", err)
+		}
+		git("add", file)
+		git("commit", "--quiet", "-m", message)
+	}
+
+	git("init", "--quiet", "--initial-branch=main")
+	commit("plan.md", "add plan")
+
+	// merged with the default message, a nested branch with another message
+	git("switch", "--quiet", "--create", "feature")
+	commit("feature.md", "add feature")
+	git("tag", "v0.1")
+	git("switch", "--quiet", "--create", "fix")
+	commit("fix.md", "add fix")
+	git("switch", "--quiet", "feature")
+	git("merge", "--quiet", "--no-ff", "-m", "take the fix", "fix")
+	commit("feature.md", "extend feature")
+	git("switch", "--quiet", "main")
+	commit("plan.md", "extend plan")
+	git("merge", "--quiet", "--no-ff", "--no-edit", "feature")
+	git("branch", "--quiet", "-D", "feature", "fix")
+
+	replay := importAndCast(t, baseDir, source)
+
+	for _, args := range [][]string{
+		{"for-each-ref", "--format=%(refname)"},
+		{"log", "--topo-order", "--format=@%T %an|%p%n%B", "main"},
+		{"log", "-1", "--format=%T", "v0.1"},
+	} {
+		want := importGit(t, source, args...)
+		got := importGit(t, replay, args...)
+		if args[0] == "log" {
+			want, got = countParents(want), countParents(got)
+		}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("ERROR: %v: got- want+\n%s\n", args, diff)
+		}
+	}
+
+	// the deleted branches are named after the merge or numbered
+	data, err := os.ReadFile(filepath.Join(baseDir, "tasks", "imported", FormulaFileName))
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+	for _, want := range []string{"command: branch -D feature", "command: branch -D merged-1"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("ERROR: formula does not contain %q", want)
+		}
+	}
+}
+
+// importAndCast imports the source repo into baseDir/tasks/imported,
+// casts the formula, and returns the directory of the clone.
+func importAndCast(t *testing.T, baseDir, source string) string {
+	t.Helper()
+
+	logger := log.New(io.Discard, "", 0)
+	err := Import(source, filepath.Join(baseDir, "tasks", "imported"), logger)
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+
+	formula, err := Read(filepath.Join(baseDir, "tasks", "imported", FormulaFileName))
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+	opt := Options{RepoDir: filepath.Join(baseDir, "repos"),
+		CfgDir: filepath.Join(baseDir, "tasks"), TaskDir: "imported"}
+	err = Transmute(formula, opt, logger)
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+	return filepath.Join(opt.RepoDir, "imported")
+}
+
+// createImportSource creates a repo with branches, renames, a clean
+// and a conflicting merge, an orphan branch, and tags.
+func createImportSource(t *testing.T, dir string) {
+	t.Helper()
+
+	write := func(name, content string, mode os.FileMode) {
+		t.Helper()
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		err := os.MkdirAll(filepath.Dir(file), dirMode)
+		if err == nil {
+			err = os.WriteFile(file, []byte(content), mode)
+		}
+		if err != nil {
+			t.Fatalf("ERROR: test setup failed: %v", err)
+		}
+	}
+	git := func(user string, args ...string) {
+		t.Helper()
+		config := []string{"-c", "user.name=" + author[user], "-c", "user.email=" + email[user]}
+		if _, ok := author[user]; !ok {
+			config = authorConfig(user)
+		}
+		importGit(t, dir, append(config, args...)...)
+	}
+
+	err := os.MkdirAll(dir, dirMode)
+	if err != nil {
+		t.Fatalf("ERROR: test setup failed: %v", err)
+	}
+	git("red", "init", "--quiet", "--initial-branch=main")
+
+	write("plan.md", "# Plan\n", fileMode)
+	write("notes.md", "notes\n", fileMode)
+	write("bin/run.sh", "echo run\n", executableMode)
+	git("red", "add", ".")
+	git("red", "commit", "--quiet", "-m", "add plan")
+	git("red", "tag", "v0")
+
+	git("red", "switch", "--quiet", "--create", "feature")
+	git("blue", "mv", "notes.md", "team.md")
+	write("doc/readme.md", "readme\n", fileMode)
+	git("blue", "add", ".")
+	git("blue", "commit", "--quiet", "-m", "rename notes\n\nand add a readme")
+
+	git("red", "switch", "--quiet", "main")
+	write("plan.md", "# Plan\n\n* one\n", fileMode)
+	git("Eve <eve@example.com>", "commit", "--quiet", "--all", "-m", "extend plan")
+	git("green", "merge", "--quiet", "--no-ff", "-m", "merge feature", "feature")
+	git("green", "tag", "-a", "-m", "first release", "v1")
+
+	git("red", "switch", "--quiet", "feature")
+	write("plan.md", "# Plan\n\n* two\n", fileMode)
+	git("blue", "commit", "--quiet", "--all", "-m", "change plan")
+	git("red", "rm", "--quiet", "bin/run.sh")
+	git("blue", "commit", "--quiet", "-m", "remove script")
+
+	git("red", "switch", "--quiet", "main")
+	importGitError(dir, "-c", "user.name="+author["red"], "-c", "user.email="+email["red"],
+		"merge", "--quiet", "feature")
+	write("plan.md", "# Plan\n\n* one\n* two\n", fileMode)
+	git("red", "commit", "--quiet", "--all", "-m", "merge feature again")
+	git("red", "commit", "--quiet", "--allow-empty", "-m", "empty")
+
+	git("red", "branch", "hotfix", "main~1")
+
+	git("red", "switch", "--quiet", "--orphan", "pages")
+	write("index.html", "<html>\n", fileMode)
+	git("red", "add", "index.html")
+	git("red", "commit", "--quiet", "-m", "publish")
+	git("red", "switch", "--quiet", "main")
+}
+
+// importGit executes git in the directory and returns its output.
+func importGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command(gitCmd, args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("ERROR: git %v: %v", args, err)
+	}
+	return string(out)
+}
+
+// importGitError executes git in the directory and ignores errors,
+// e.g. for merge conflicts.
+func importGitError(dir string, args ...string) {
+	cmd := exec.Command(gitCmd, args...)
+	cmd.Dir = dir
+	_ = cmd.Run()
+}
+
+// countParents replaces the parent ids of the log lines that start
+// with @ by their count.
+func countParents(log string) string {
+	lines := strings.Split(log, "\n")
+	for i, line := range lines {
+		commit, parents, ok := strings.Cut(line, "|")
+		if ok && strings.HasPrefix(commit, "@") {
+			lines[i] = commit + "|" + strconv.Itoa(len(strings.Fields(parents)))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -1,6 +1,11 @@
 // Package alchemist contains all the elements to do alchemistry.
 package alchemist
 
+import (
+	"path/filepath"
+	"strings"
+)
+
 // emails provides dummy email addresses for git.
 var email = map[string]string{
 	"red":       "richard@pw-compa.ny",
@@ -31,6 +36,52 @@ func getAuthor(name string) string {
 	return author + " <" + email[name] + ">"
 }
 
+// authorConfig returns the git options that set the user for commits
+// that are created by git itself, e.g. merge commits.
+// If the name is empty, the configured user of the clone is used.
+// An unknown author can be given as "name <email>".
+func authorConfig(name string) []string {
+	if name == "" {
+		return nil
+	}
+	userName, ok := author[name]
+	if !ok {
+		userName, userEmail, ok := strings.Cut(name, " <")
+		if ok {
+			return []string{"-c", "user.name=" + userName,
+				"-c", "user.email=" + strings.TrimSuffix(userEmail, ">")}
+		}
+		return []string{"-c", "user.name=" + name}
+	}
+	return []string{"-c", "user.name=" + userName, "-c", "user.email=" + email[name]}
+}
+
+// keysDir is the directory in the repo dir that contains the
+// ssh signing keys of the authors and the allowed signers file.
+const keysDir = "keys"
+
+// allowedSignersFile is the name of the allowed signers file in keysDir.
+const allowedSignersFile = "allowed_signers"
+
+// signingKey returns the private ssh key file of the author.
+// The keys are created by signingKeysSpell.
+func signingKey(repoDir, name string) string {
+	return filepath.Join(repoDir, keysDir, name)
+}
+
+// signConfig returns the git options that sign with the ssh key
+// of the author. Only authors of the registry can sign.
+func signConfig(repoDir, name string) ([]string, error) {
+	if _, ok := author[name]; !ok {
+		return nil, InvalidValueError{Variable: "author", Reason: "unknown author " + name + " can not sign"}
+	}
+	key, err := filepath.Abs(signingKey(repoDir, name))
+	if err != nil {
+		return nil, IOError{Cmd: "absolute path", Arg: signingKey(repoDir, name), Err: err}
+	}
+	return []string{"-c", "gpg.format=ssh", "-c", "user.signingKey=" + key}, nil
+}
+
 // defaultUser is used when a new repo is initialzeed.
 const defaultUser = "red"
 
@@ -40,11 +91,31 @@ const FormulaFileName = "gitalchemist.yaml"
 // dirMode defines the access mode for new directories.
 const dirMode = 0755
 
+// access modes for new files.
+const (
+	fileMode       = 0644
+	executableMode = 0755
+)
+
 // defaultBranch is the git default branch name.
 const defaultBranch = "main"
 
+// gitEnvironment is added to the environment of the git commands.
+// It prevents git from waiting for user input.
+var gitEnvironment = []string{
+	"GIT_TERMINAL_PROMPT=0",           // no credential prompts
+	"GIT_EDITOR=true",                 // no editor for messages
+	"FILTER_BRANCH_SQUELCH_WARNING=1", // no warning and delay of filter-branch
+}
+
 // git commands on linux and windows.
 const (
 	linuxGitCmd   = "git"
 	windowsGitCmd = "git.exe"
 )
+
+// ssh-keygen commands on linux and windows.
+const (
+	linuxSSHKeygenCmd   = "ssh-keygen"
+	windowsSSHKeygenCmd = "ssh-keygen.exe"
+)
@@ -1,6 +1,7 @@
 package alchemist
 
 import (
+	"io/fs"
 	"log"
 )
 
@@ -9,7 +10,8 @@ import (
 //
 // It implements the assistant interface.
 //
-// It is used for test mode. All methods return a nil error.
+// It is the base of the assistants that do not execute the commands.
+// All methods return a nil error.
 type novice struct {
 	mortalLogger
 }
@@ -31,6 +33,14 @@ func (n novice) git(dir string, args ...string) error {
 	return nil
 }
 
+// gitOutput emits a debug message with the parameters.
+// It returns an empty output.
+// It implements the assistant interface.
+func (n novice) gitOutput(dir string, args ...string) (string, error) {
+	n.debug("%q: git %#v", dir, args)
+	return "", nil
+}
+
 // copy emits a debug message with the parameters.
 // It implements the assistant interface.
 func (n novice) copy(from, to string) error {
@@ -38,6 +48,36 @@ func (n novice) copy(from, to string) error {
 	return nil
 }
 
+// readFile emits a debug message with the parameters.
+// It returns an empty content.
+// It implements the assistant interface.
+func (n novice) readFile(name string) ([]byte, error) {
+	n.debug("read %q", name)
+	return nil, nil
+}
+
+// writeFile emits a debug message with the parameters.
+// It implements the assistant interface.
+func (n novice) writeFile(name string, data []byte, mode fs.FileMode) error {
+	n.debug("write %q (%d bytes, mode %v)", name, len(data), mode)
+	return nil
+}
+
+// chmod emits a debug message with the parameters.
+// It implements the assistant interface.
+func (n novice) chmod(name string, mode fs.FileMode) error {
+	n.debug("chmod %q to %v", name, mode)
+	return nil
+}
+
+// keygen emits a debug message with the parameters.
+// It returns an empty public key.
+// It implements the assistant interface.
+func (n novice) keygen(file, comment string) (string, error) {
+	n.debug("keygen %q for %q", file, comment)
+	return "", nil
+}
+
 // makedir emits a debug message with the parameters.
 // It implements the assistant interface.
 func (n novice) makedir(dir string) error {
@@ -26,10 +26,35 @@ func TestNovice(t *testing.T) {
 	if err != nil {
 		t.Errorf("ERROR: got error: %v", err)
 	}
+	_, err = novice.gitOutput(dir, "version")
+	if err != nil {
+		t.Errorf("ERROR: got error: %v", err)
+	}
+	_, err = novice.readFile(from)
+	if err != nil {
+		t.Errorf("ERROR: got error: %v", err)
+	}
+	err = novice.writeFile(to, []byte("hello"), 0755)
+	if err != nil {
+		t.Errorf("ERROR: got error: %v", err)
+	}
+	err = novice.chmod(to, 0755)
+	if err != nil {
+		t.Errorf("ERROR: got error: %v", err)
+	}
+	_, err = novice.keygen(to, "red")
+	if err != nil {
+		t.Errorf("ERROR: got error: %v", err)
+	}
 
 	want := `[DEBUG] "dir": git []string{"init"}
 [DEBUG] copy "from" to "to"
 [DEBUG] makedir "dir"
+[DEBUG] "dir": git []string{"version"}
+[DEBUG] read "from"
+[DEBUG] write "to" (5 bytes, mode -rwxr-xr-x)
+[DEBUG] chmod "to" to -rwxr-xr-x
+[DEBUG] keygen "to" for "red"
 `
 	got := buf.String()
 
@@ -52,3 +77,49 @@ func TestNovice(t *testing.T) {
 		t.Errorf("ERROR: got- want+\n%s\n", diff)
 	}
 }
+
+// TestNoviceTransmute tests the log messages of the spells when a
+// formula is cast by the novice, who never fails.
+func TestNoviceTransmute(t *testing.T) {
+
+	testCases := []struct {
+		name    string
+		verbose bool
+		nSteps  int
+		want    string
+	}{{
+		name: "normal",
+		want: wantTestCompleteLog,
+	}, {
+		name:    "verbose",
+		verbose: true,
+		want:    wantTestVerboseLog,
+	}, {
+		name:   "short",
+		nSteps: 2,
+		want:   wantTestShortLog,
+	}}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+
+			opt := Options{
+				TaskDir:       completeFormula.Title,
+				ExecuteSpells: c.nSteps,
+				Verbose:       c.verbose,
+				RepoDir:       "repodir",
+				CfgDir:        "cfgdir",
+			}
+
+			var buf bytes.Buffer
+			err := transmute(completeFormula, opt, newNovice(log.New(&buf, "", 0), opt))
+			if err != nil {
+				t.Fatalf("ERROR: got error: %v", err)
+			}
+
+			if diff := cmp.Diff(buf.String(), c.want); diff != "" {
+				t.Errorf("ERROR: got- want+\n%s\n", diff)
+			}
+		})
+	}
+}
@@ -7,11 +7,39 @@ type Options struct {
 	CfgDir        string // directory of the configuration definitions
 	Verbose       bool   // verbose logging
 	Test          bool   // test mode
+	FastImport    bool   // create the history with git fast-import if possible
 	ExecuteSpells int    // execute only the first # steps
 
+	Variables map[string]string // values of formula variables
+
 	// set during processing
 	taskName       string // name of the task to execute
 	cloneTo        string // directory of the repository clone
 	numberOfSpells int    // number of steps
 	currentSpell   int    // number of the current step (1-based)
 }
+
+// lookup returns a function that provides the values of the variables
+// for when conditions. The git version is retrieved by the assistant,
+// if the assistant does not know it, an UnknownValueError is returned.
+func (o Options) lookup(a assistant) lookupFunc {
+	return func(name string) (string, error) {
+		if name == gitVersionVariable {
+			output, err := a.gitOutput("", "version")
+			if err != nil {
+				return "", err
+			}
+			version := parseGitVersion(output)
+			if version == "" {
+				return "", UnknownValueError(gitVersionVariable)
+			}
+			return version, nil
+		}
+
+		value, ok := o.Variables[name]
+		if !ok {
+			return "", InvalidValueError{Variable: name, Reason: "unknown variable"}
+		}
+		return value, nil
+	}
+}
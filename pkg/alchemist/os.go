@@ -4,3 +4,15 @@ package alchemist
 
 // use the linux git command unless we compile for windows.
 const gitCmd = linuxGitCmd
+
+// use the linux ssh-keygen command unless we compile for windows.
+const sshKeygenCmd = linuxSSHKeygenCmd
+
+// gitShimName is the file name of the git shim of a recording.
+const gitShimName = "git"
+
+// gitShimScript returns the shell script of the git shim that
+// passes all arguments to the recording executable.
+func gitShimScript(exe string) string {
+	return "#!/bin/sh\nexec \"" + exe + "\" " + RecordGitCommand + " \"$@\"\n"
+}
@@ -2,9 +2,28 @@
 
 package alchemist
 
+import (
+	"io/fs"
+	"os"
+	"testing"
+)
+
 // noAccessDir is used for testing access errors.
 const noAccessDir = "/"
 
+// checkMode checks the permission bits of the file.
+func checkMode(t *testing.T, name string, want fs.FileMode) {
+	t.Helper()
+
+	info, err := os.Lstat(name)
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+	if got := info.Mode().Perm(); got != want {
+		t.Errorf("ERROR: mode: got %v, want %v", got, want)
+	}
+}
+
 // wantTestCompleteLog represents the output of the novice in verbose mode.
 var wantTestVerboseLog = `[INFO] execute formula test_workflow
 [INFO] 1/14: make directory repodir/remotes/create_add_commit
@@ -124,3 +143,49 @@ var wantTestShortLog = `[INFO] execute formula test_workflow
 // wantNoviceLog is the log output from the novice.
 var wantNoviceLog = `[DEBUG] copy "testdata/source.txt" to "testdata/new_page/new_dir"
 `
+
+// wantTestSeerLog represents the output of the seer in normal mode.
+var wantTestSeerLog = `[INFO] execute formula test_seer
+[INFO] 1/6: make directory repodir/remotes/test_seer
+[INFO] 1/6: init --bare --initial-branch=main .
+[INFO] 1/6: clone remotes/test_seer test_seer
+[INFO] 1/6: remote set-url origin ../remotes/test_seer
+[INFO] 1/6: config user.name Richard Red
+[INFO] 1/6: config user.email richard@pw-compa.ny
+[INFO] 1/6: config init.defaultBranch main
+[INFO] 2/6: commit --allow-empty -m start
+[INFO] 3/6: switch --create feature
+[INFO] 4/6: commit --allow-empty -m feature
+[INFO] 5/6: merge feature with main  (delete: false)
+[INFO] 5/6: checkout main
+[INFO] 5/6: merge feature
+`
+
+// wantTestSeerVerboseLog represents the output of the seer in verbose mode.
+var wantTestSeerVerboseLog = `[INFO] execute formula test_seer
+[INFO] 1/6: make directory repodir/remotes/test_seer
+[DEBUG] makedir "repodir/remotes/test_seer"
+[INFO] 1/6: init --bare --initial-branch=main .
+[DEBUG] "repodir/remotes/test_seer": git []string{"init", "--bare", "--initial-branch=main", "."}
+[INFO] 1/6: clone remotes/test_seer test_seer
+[DEBUG] "repodir": git []string{"clone", "remotes/test_seer", "test_seer"}
+[INFO] 1/6: remote set-url origin ../remotes/test_seer
+[DEBUG] "repodir/test_seer": git []string{"remote", "set-url", "origin", "../remotes/test_seer"}
+[INFO] 1/6: config user.name Richard Red
+[DEBUG] "repodir/test_seer": git []string{"config", "user.name", "Richard Red"}
+[INFO] 1/6: config user.email richard@pw-compa.ny
+[DEBUG] "repodir/test_seer": git []string{"config", "user.email", "richard@pw-compa.ny"}
+[INFO] 1/6: config init.defaultBranch main
+[DEBUG] "repodir/test_seer": git []string{"config", "init.defaultBranch", "main"}
+[INFO] 2/6: commit --allow-empty -m start
+[DEBUG] "repodir/test_seer": git []string{"commit", "--allow-empty", "-m", "start"}
+[INFO] 3/6: switch --create feature
+[DEBUG] "repodir/test_seer": git []string{"switch", "--create", "feature"}
+[INFO] 4/6: commit --allow-empty -m feature
+[DEBUG] "repodir/test_seer": git []string{"commit", "--allow-empty", "-m", "feature"}
+[INFO] 5/6: merge feature with main  (delete: false)
+[INFO] 5/6: checkout main
+[DEBUG] "repodir/test_seer": git []string{"checkout", "main"}
+[INFO] 5/6: merge feature
+[DEBUG] "repodir/test_seer": git []string{"merge", "feature"}
+`
@@ -2,3 +2,15 @@ package alchemist
 
 // gitCmc is set to the windows git command when compiling for windows.
 const gitCmd = windowsGitCmd
+
+// sshKeygenCmd is set to the windows ssh-keygen command when compiling for windows.
+const sshKeygenCmd = windowsSSHKeygenCmd
+
+// gitShimName is the file name of the git shim of a recording on windows.
+const gitShimName = "git.cmd"
+
+// gitShimScript returns the batch file of the git shim that
+// passes all arguments to the recording executable.
+func gitShimScript(exe string) string {
+	return "@\"" + exe + "\" " + RecordGitCommand + " %*\r\n"
+}
@@ -0,0 +1,526 @@
+package alchemist
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// RecordGitCommand is the first command line argument that makes
+// gitalchemist act as the git shim of a recording.
+const RecordGitCommand = "record-git"
+
+// environment variables that connect the git shim with the recording.
+const (
+	recordDirEnv    = "GITALCHEMIST_RECORD_DIR"     // session directory
+	recordTaskEnv   = "GITALCHEMIST_RECORD_TASKDIR" // directory of the formula
+	recordGitEnv    = "GITALCHEMIST_RECORD_GIT"     // path of the real git
+	recordNestedEnv = "GITALCHEMIST_RECORD_NESTED"  // set for git called by git
+)
+
+const (
+	recordLogFile  = "record.log" // git calls of the session, one json per line
+	recordBinDir   = "bin"        // directory of the git shim
+	recordFilesDir = "files"      // committed files in the task dir
+	recordBareDir  = "remotes"    // directory of the bare repo
+)
+
+// readOnlyCommands are git commands that do not change the repo.
+// They are not part of the formula.
+var readOnlyCommands = []string{"", "blame", "cat-file", "describe", "diff",
+	"grep", "help", "log", "ls-files", "ls-tree", "reflog", "rev-parse",
+	"shortlog", "show", "status", "version", "whatchanged"}
+
+// indexCommands are git commands that change the index. They are not
+// part of the formula, the changes are recorded with the next commit.
+var indexCommands = []string{"add", "init", "mv", "rm", "stage"}
+
+// Recording provides recording the git commands of an instructor's
+// session and turning them into a formula.
+//
+// The session works in the clone of a bare repo in a temporary
+// directory, just like the first init_bare_repo of a formula. A git
+// shim that is first on the PATH logs every git call in the clone.
+// The committed files are written to the task dir.
+type Recording struct {
+	Title   string // title of the formula, the base name of the task dir
+	TaskDir string // absolute directory of the formula
+	dir     string // session directory with bare repo, clone, shim, and log
+	git     string // path of the real git
+}
+
+// recordEntry is one git call of a recording.
+type recordEntry struct {
+	Dir    string        `json:"dir"` // relative to the clone, slash separated
+	Args   []string      `json:"args"`
+	Commit *recordCommit `json:"commit,omitempty"` // commit created by the call
+}
+
+// recordCommit contains the commit created by a git call.
+type recordCommit struct {
+	Message string       `json:"message"`
+	Author  string       `json:"author"` // name <email>
+	Amend   bool         `json:"amend"`
+	Merge   []string     `json:"merge,omitempty"` // merged branches, e.g. feature~1
+	Files   []recordFile `json:"files"`
+}
+
+// recordFile is a file changed by a commit. The content of a changed
+// file is located in the task dir in files/<number of the entry>.
+type recordFile struct {
+	Path    string `json:"path"` // slash separated
+	Deleted bool   `json:"deleted"`
+}
+
+// NewRecording prepares a session for the task dir. The git shim
+// calls exe with RecordGitCommand as first argument.
+// The task dir must not contain a formula.
+func NewRecording(taskDir, exe string, logger *log.Logger) (Recording, error) {
+
+	err := checkNoFormula(taskDir)
+	if err != nil {
+		return Recording{}, err
+	}
+
+	// the git shim is called in the clone
+	taskDir, err = filepath.Abs(taskDir)
+	if err != nil {
+		return Recording{}, IOError{Cmd: "abs", Arg: taskDir, Err: err}
+	}
+
+	git, err := exec.LookPath(gitCmd)
+	if err != nil {
+		return Recording{}, ExecError{Cmd: gitCmd, Err: err}
+	}
+	git, err = filepath.Abs(git)
+	if err != nil {
+		return Recording{}, IOError{Cmd: "abs", Arg: git, Err: err}
+	}
+
+	dir, err := os.MkdirTemp("", "gitalchemist-record-")
+	if err != nil {
+		return Recording{}, IOError{Cmd: "make temp dir", Arg: os.TempDir(), Err: err}
+	}
+
+	r := Recording{Title: filepath.Base(taskDir), TaskDir: taskDir, dir: dir, git: git}
+
+	err = r.setup(exe, logger)
+	if err != nil {
+		_ = r.Close()
+		return Recording{}, err
+	}
+	return r, nil
+}
+
+// setup creates the bare repo and its clone, the git shim, and the
+// task dir.
+func (r Recording) setup(exe string, logger *log.Logger) error {
+
+	formula := Formula{
+		Title: r.Title,
+		Commands: symbols{
+			cloneTo: r.Title,
+			spells:  []caster{r.initSpell()},
+			names:   []string{symbolInit},
+		},
+	}
+	err := Transmute(formula, Options{RepoDir: r.dir}, logger)
+	if err != nil {
+		return err
+	}
+
+	shim := filepath.Join(r.dir, recordBinDir, gitShimName)
+	a := newAdept(log.New(io.Discard, "", 0), Options{})
+	err = a.writeFile(shim, []byte(gitShimScript(exe)), executableMode)
+	if err != nil {
+		return err
+	}
+
+	return a.makedir(r.TaskDir)
+}
+
+// initSpell returns the spell that creates the bare repo and the clone.
+func (r Recording) initSpell() initRepoSpell {
+	return initRepoSpell{Bare: path.Join(recordBareDir, r.Title), CloneTo: r.Title}
+}
+
+// CloneDir returns the directory the instructor works in.
+func (r Recording) CloneDir() string {
+	return filepath.Join(r.dir, r.Title)
+}
+
+// Environ returns the environment variables for the session.
+// They have to be added to the current environment.
+func (r Recording) Environ() []string {
+	bin := filepath.Join(r.dir, recordBinDir)
+	return []string{
+		"PATH=" + bin + string(os.PathListSeparator) + os.Getenv("PATH"),
+		recordDirEnv + "=" + r.dir,
+		recordTaskEnv + "=" + r.TaskDir,
+		recordGitEnv + "=" + r.git,
+	}
+}
+
+// Close removes the session directory.
+func (r Recording) Close() error {
+	err := os.RemoveAll(r.dir)
+	if err != nil {
+		return IOError{Cmd: "remove", Arg: r.dir, Err: err}
+	}
+	return nil
+}
+
+// Write turns the recorded git calls into the formula of the task dir.
+func (r Recording) Write() error {
+
+	entries, err := r.entries()
+	if err != nil {
+		return err
+	}
+
+	s := scroll{Title: r.Title}
+	s.add(symbolInit, r.initSpell())
+	for i, entry := range entries {
+		transcribe(&s, entry, i+1)
+	}
+
+	return s.write(r.TaskDir)
+}
+
+// entries returns the recorded git calls.
+func (r Recording) entries() ([]recordEntry, error) {
+
+	file := filepath.Join(r.dir, recordLogFile)
+	f, err := os.Open(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, IOError{Cmd: "open", Arg: file, Err: err}
+	}
+	defer f.Close()
+
+	var entries []recordEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		var entry recordEntry
+		err = json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			return nil, IOError{Cmd: "json decode", Arg: file, Err: err}
+		}
+		entries = append(entries, entry)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, IOError{Cmd: "read", Arg: file, Err: err}
+	}
+	return entries, nil
+}
+
+// transcribe adds the spells for the recorded git call to the scroll.
+// The number is used for the directory of the committed files.
+func transcribe(s *scroll, entry recordEntry, number int) {
+
+	command := gitSubcommand(entry.Args)
+	switch {
+	case slices.Contains(readOnlyCommands, command),
+		slices.Contains(indexCommands, command):
+		return
+	case command == "commit":
+		if entry.Commit != nil {
+			transcribeCommit(s, *entry.Commit, number)
+		}
+		return
+	}
+
+	args := entry.Args
+	if entry.Dir != "." {
+		args = append([]string{"-C", entry.Dir}, args...)
+	}
+	s.add(symbolGit, gitSpell{Command: joinArgs(args)})
+}
+
+// transcribeCommit adds the spells that recreate the commit.
+// A merge with conflicts is started without changes like in the
+// import, the resolved files are the changes of the commit.
+func transcribeCommit(s *scroll, commit recordCommit, number int) {
+
+	if len(commit.Merge) > 0 {
+		args := append([]string{"merge", "--no-ff", "--no-commit", "--strategy=ours"}, commit.Merge...)
+		s.add(symbolGit, gitSpell{Command: joinArgs(args)})
+	}
+
+	var added, deleted []string
+	for _, file := range commit.Files {
+		if file.Deleted {
+			deleted = append(deleted, file.Path)
+			continue
+		}
+		added = append(added, file.Path)
+		s.add(symbolCreateFile, createFileSpell{
+			Source: path.Join(recordFilesDir, strconv.Itoa(number), file.Path),
+			Target: file.Path,
+		})
+	}
+	if len(added) > 0 {
+		s.add(symbolAdd, addSpell{Files: added})
+	}
+	if len(deleted) > 0 {
+		args := append([]string{"rm", "--quiet", "--"}, deleted...)
+		s.add(symbolGit, gitSpell{Command: joinArgs(args)})
+	}
+
+	author := authorKey(commit.Author)
+	if len(commit.Files) == 0 && !commit.Amend && len(commit.Merge) == 0 {
+		s.add(symbolGit, emptyCommitSpell(commit.Message, author))
+		return
+	}
+	s.add(symbolCommit, commitSpell{Message: commit.Message, Author: author, Amend: commit.Amend})
+}
+
+// gitSubcommand returns the git command of the arguments,
+// e.g. commit for git -c x=y commit -m msg.
+func gitSubcommand(args []string) string {
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case slices.Contains([]string{"-c", "-C", "--git-dir", "--work-tree",
+			"--namespace", "--config-env"}, arg):
+			i++
+		case strings.HasPrefix(arg, "-"):
+		default:
+			return arg
+		}
+	}
+	return ""
+}
+
+// joinArgs joins the arguments for a git spell. Arguments that are
+// empty or contain spaces, quotes, or backslashes are double quoted,
+// quotes and backslashes are escaped. gitSpell.splitArgs splits them.
+func joinArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = arg
+		if arg == "" || strings.ContainsAny(arg, argSpace+`"\`) {
+			quoted[i] = `"` + argEscaper.Replace(arg) + `"`
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// argEscaper escapes quotes and backslashes in double quoted arguments.
+var argEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// RecordGit is the git shim of a recording. It calls git with the
+// arguments and logs the call if it is successful and executed in
+// the clone of the session. It returns the exit code of git.
+func RecordGit(args []string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+
+	r := Recording{
+		TaskDir: os.Getenv(recordTaskEnv),
+		dir:     os.Getenv(recordDirEnv),
+		git:     os.Getenv(recordGitEnv),
+	}
+	if r.git == "" || r.dir == "" || r.TaskDir == "" {
+		return 1, MissingValueError(recordGitEnv)
+	}
+	r.Title = filepath.Base(r.TaskDir)
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return 1, IOError{Cmd: "getwd", Arg: ".", Err: err}
+	}
+	rel, err := relPath(r.CloneDir(), cwd)
+	record := err == nil && os.Getenv(recordNestedEnv) == "" &&
+		rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+
+	// the merged branches of a conflicted merge are lost after the commit
+	var head string
+	var merge []string
+	if record && gitSubcommand(args) == "commit" {
+		head = r.head()
+		merge = r.mergeHeads()
+	}
+
+	cmd := exec.Command(r.git, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = stdin, stdout, stderr
+	cmd.Env = append(os.Environ(), recordNestedEnv+"=1")
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return 1, ExecError{Cmd: r.git, Args: args, Err: err}
+	}
+
+	if !record {
+		return 0, nil
+	}
+	return 0, r.log(recordEntry{Dir: filepath.ToSlash(rel), Args: args}, head, merge)
+}
+
+// relPath returns the path relative to the base directory.
+// Symbolic links like /tmp on macOS are resolved first.
+func relPath(base, target string) (string, error) {
+	base, err := filepath.EvalSymlinks(base)
+	if err != nil {
+		return "", err
+	}
+	target, err = filepath.EvalSymlinks(target)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Rel(base, target)
+}
+
+// output executes the real git in the clone and returns the output.
+func (r Recording) output(args ...string) (string, error) {
+	a := newAdept(log.New(io.Discard, "", 0), Options{})
+	a.exe = r.git
+	return a.gitOutput(r.CloneDir(), args...)
+}
+
+// head returns the commit id of HEAD or an empty string
+// if there is no commit.
+func (r Recording) head() string {
+	head, _ := r.output("rev-parse", "--verify", "--quiet", "HEAD")
+	return strings.TrimSpace(head)
+}
+
+// mergeHeads returns the branches of an unfinished merge, named
+// relative to their tips, e.g. feature~1. It returns nil if there is
+// no merge or a merged commit is not on a branch.
+func (r Recording) mergeHeads() []string {
+
+	file, err := r.output("rev-parse", "--git-path", "MERGE_HEAD")
+	if err != nil {
+		return nil
+	}
+	file = strings.TrimSpace(file)
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(r.CloneDir(), file)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+
+	var merge []string
+	for _, id := range strings.Fields(string(data)) {
+		name, err := r.output("name-rev", "--name-only", "--no-undefined",
+			"--refs=refs/heads/*", id)
+		if err != nil {
+			return nil
+		}
+		merge = append(merge, strings.TrimSpace(name))
+	}
+	return merge
+}
+
+// log appends the entry to the log file. If the call is a commit,
+// the changes since the previous head are added and the files are
+// written to the task dir. The commit of a merge with conflicts
+// keeps the merged branches.
+func (r Recording) log(entry recordEntry, previous string, merge []string) error {
+
+	entries, err := r.entries()
+	if err != nil {
+		return err
+	}
+
+	if gitSubcommand(entry.Args) == "commit" {
+		entry.Commit, err = r.commit(entry.Args, previous, len(entries)+1)
+		if err != nil {
+			return err
+		}
+		if entry.Commit != nil {
+			entry.Commit.Merge = merge
+		}
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return IOError{Cmd: "json encode", Arg: recordLogFile, Err: err}
+	}
+
+	file := filepath.Join(r.dir, recordLogFile)
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, fileMode)
+	if err != nil {
+		return IOError{Cmd: "open", Arg: file, Err: err}
+	}
+	_, err = f.Write(append(data, '\n'))
+	if err != nil {
+		f.Close()
+		return IOError{Cmd: "write", Arg: file, Err: err}
+	}
+	err = f.Close()
+	if err != nil {
+		return IOError{Cmd: "close", Arg: file, Err: err}
+	}
+	return nil
+}
+
+// commit returns the commit created since the previous head
+// and writes the changed files to files/<number> in the task dir.
+// It returns nil if there is no new commit.
+func (r Recording) commit(args []string, previous string, number int) (*recordCommit, error) {
+
+	head := r.head()
+	if head == "" || head == previous {
+		return nil, nil
+	}
+
+	diffArgs := []string{"diff-tree", "-r", "-z", "--no-renames", "--no-commit-id"}
+	if previous == "" {
+		diffArgs = append(diffArgs, "--root", head)
+	} else {
+		diffArgs = append(diffArgs, previous, head)
+	}
+	diff, err := r.output(diffArgs...)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := r.output("log", "-1", "--format=%B", head)
+	if err != nil {
+		return nil, err
+	}
+	author, err := r.output("log", "-1", "--format=%an <%ae>", head)
+	if err != nil {
+		return nil, err
+	}
+	commit := &recordCommit{
+		Message: strings.TrimRight(message, "\n"),
+		Author:  strings.TrimSpace(author),
+		Amend:   slices.Contains(args, "--amend"),
+	}
+
+	for _, change := range parseRawDiff(diff) {
+		file := recordFile{Path: change.Path, Deleted: change.Status == 'D'}
+		if !file.Deleted {
+			target := filepath.Join(r.TaskDir, recordFilesDir, strconv.Itoa(number),
+				filepath.FromSlash(change.Path))
+			ok, err := writeBlob(r.output, target, change.Mode, change.ID)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		commit.Files = append(commit.Files, file)
+	}
+
+	return commit, nil
+}
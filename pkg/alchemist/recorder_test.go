@@ -0,0 +1,301 @@
+package alchemist
+
+import (
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestGitSubcommand tests finding the git command in the arguments.
+func TestGitSubcommand(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		args []string
+		want string
+	}{{
+		name: "simple",
+		args: []string{"commit", "-m", "x"},
+		want: "commit",
+	}, {
+		name: "options with values",
+		args: []string{"-c", "user.name=x", "-C", "dir", "--no-pager", "log"},
+		want: "log",
+	}, {
+		name: "no command",
+		args: []string{"--version"},
+		want: "",
+	}}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			got := gitSubcommand(c.args)
+			if got != c.want {
+				t.Errorf("ERROR: got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+// TestJoinArgs tests that joined arguments are split again.
+func TestJoinArgs(t *testing.T) {
+
+	testCases := []struct {
+		name string
+		args []string
+		want string
+	}{{
+		name: "plain",
+		args: []string{"tag", "v1"},
+		want: "tag v1",
+	}, {
+		name: "spaces and empty",
+		args: []string{"commit", "-m", "add plan", "--author", ""},
+		want: `commit -m "add plan" --author ""`,
+	}, {
+		name: "quotes and backslashes",
+		args: []string{"commit", "-m", `say "hi"`, `C:\temp\`},
+		want: `commit -m "say \"hi\"" "C:\\temp\\"`,
+	}, {
+		name: "newline and non ascii",
+		args: []string{"commit", "-m", "Übersicht\n\ndetails"},
+		want: "commit -m \"Übersicht\n\ndetails\"",
+	}}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			got := joinArgs(c.args)
+			if got != c.want {
+				t.Errorf("ERROR: got %q, want %q", got, c.want)
+			}
+			split := gitSpell{Command: got}.splitArgs()
+			if diff := cmp.Diff(split, c.args); diff != "" {
+				t.Errorf("ERROR: got- want+\n%s\n", diff)
+			}
+		})
+	}
+}
+
+// TestSplitArgs tests splitting hand written git commands.
+func TestSplitArgs(t *testing.T) {
+
+	testCases := []struct {
+		name    string
+		command string
+		want    []string
+	}{{
+		name:    "git prefix",
+		command: `git commit -m "my message"`,
+		want:    []string{"commit", "-m", "my message"},
+	}, {
+		name:    "quoted value",
+		command: `log --format="%h %s"  -1`,
+		want:    []string{"log", "--format=%h %s", "-1"},
+	}, {
+		name:    "windows path",
+		command: `-C C:\repo add "C:\my dir\file.txt"`,
+		want:    []string{"-C", `C:\repo`, "add", `C:\my dir\file.txt`},
+	}}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			got := gitSpell{Command: c.command}.splitArgs()
+			if diff := cmp.Diff(got, c.want); diff != "" {
+				t.Errorf("ERROR: got- want+\n%s\n", diff)
+			}
+		})
+	}
+}
+
+// TestTranscribe tests turning recorded git calls into spells.
+func TestTranscribe(t *testing.T) {
+
+	testCases := []struct {
+		name  string
+		entry recordEntry
+		want  []map[string]caster
+	}{{
+		name:  "read only",
+		entry: recordEntry{Dir: ".", Args: []string{"status"}},
+	}, {
+		name:  "index",
+		entry: recordEntry{Dir: ".", Args: []string{"add", "."}},
+	}, {
+		name:  "commit without new commit",
+		entry: recordEntry{Dir: ".", Args: []string{"commit", "-m", "x"}},
+	}, {
+		name:  "git in sub directory",
+		entry: recordEntry{Dir: "src", Args: []string{"tag", "-m", "first release", "v1"}},
+		want: []map[string]caster{
+			{symbolGit: gitSpell{Command: `-C src tag -m "first release" v1`}},
+		},
+	}, {
+		name: "commit",
+		entry: recordEntry{Dir: ".", Args: []string{"commit", "-a"}, Commit: &recordCommit{
+			Message: "add plan",
+			Author:  getAuthor("blue"),
+			Files:   []recordFile{{Path: "doc/plan.md"}, {Path: "old.md", Deleted: true}},
+		}},
+		want: []map[string]caster{
+			{symbolCreateFile: createFileSpell{Source: "files/4/doc/plan.md", Target: "doc/plan.md"}},
+			{symbolAdd: addSpell{Files: []string{"doc/plan.md"}}},
+			{symbolGit: gitSpell{Command: "rm --quiet -- old.md"}},
+			{symbolCommit: commitSpell{Message: "add plan", Author: "blue"}},
+		},
+	}, {
+		name: "amend by unknown author",
+		entry: recordEntry{Dir: ".", Args: []string{"commit", "--amend"}, Commit: &recordCommit{
+			Message: "fix",
+			Author:  "Eve <eve@example.com>",
+			Amend:   true,
+		}},
+		want: []map[string]caster{
+			{symbolCommit: commitSpell{Message: "fix", Author: "Eve <eve@example.com>", Amend: true}},
+		},
+	}, {
+		name: "merge with conflicts",
+		entry: recordEntry{Dir: ".", Args: []string{"commit", "--no-edit"}, Commit: &recordCommit{
+			Message: "Merge branch 'topic'",
+			Author:  getAuthor("blue"),
+			Merge:   []string{"topic"},
+		}},
+		want: []map[string]caster{
+			{symbolGit: gitSpell{Command: "merge --no-ff --no-commit --strategy=ours topic"}},
+			{symbolCommit: commitSpell{Message: "Merge branch 'topic'", Author: "blue"}},
+		},
+	}, {
+		name: "empty commit",
+		entry: recordEntry{Dir: ".", Args: []string{"commit", "--allow-empty"}, Commit: &recordCommit{
+			Message: "empty",
+			Author:  getAuthor("red"),
+		}},
+		want: []map[string]caster{
+			{symbolGit: gitSpell{Command: `commit --allow-empty -m empty "--author=` + getAuthor("red") + `"`}},
+		},
+	}}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			var s scroll
+			transcribe(&s, c.entry, 4)
+			if diff := cmp.Diff(s.Commands, c.want); diff != "" {
+				t.Errorf("ERROR: got- want+\n%s\n", diff)
+			}
+		})
+	}
+}
+
+// TestRecording records git calls in testdata, writes the formula
+// and casts it again.
+func TestRecording(t *testing.T) {
+
+	_, err := exec.LookPath(gitCmd)
+	if err != nil {
+		t.Skipf("%s not found", gitCmd)
+	}
+
+	baseDir, err := filepath.Abs(filepath.Join(TestDataDir, "record"))
+	if err != nil {
+		t.Fatalf("ERROR: test setup failed: %v", err)
+	}
+	err = os.RemoveAll(baseDir)
+	if err != nil {
+		t.Fatalf("ERROR: test setup failed: %v", err)
+	}
+	if !testing.Verbose() {
+		defer os.RemoveAll(baseDir)
+	}
+
+	logger := log.New(io.Discard, "", 0)
+	rec, err := NewRecording(filepath.Join(baseDir, "recorded"), "gitalchemist", logger)
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+	defer rec.Close()
+
+	// the shim is called directly, the PATH is not changed
+	for _, env := range rec.Environ() {
+		name, value, _ := strings.Cut(env, "=")
+		if name != "PATH" {
+			t.Setenv(name, value)
+		}
+	}
+	t.Chdir(rec.CloneDir())
+
+	// the merge of topic fails with a conflict and is finished with commit
+	for _, step := range []struct {
+		plan string // content of plan.md before the call
+		args []string
+		fail bool
+	}{
+		{plan: "# Plan\n", args: []string{"add", "plan.md"}},
+		{args: []string{"commit", "-m", "add plan"}},
+		{args: []string{"switch", "--create", "feature"}},
+		{args: []string{"commit", "--amend", "-m", "add the plan", "--author=" + getAuthor("blue")}},
+		{args: []string{"switch", "--create", "topic"}},
+		{plan: "# Plan\ntopic\n", args: []string{"commit", "-a", "-m", "plan topic"}},
+		{args: []string{"switch", "feature"}},
+		{plan: "# Plan\nfeature\n", args: []string{"commit", "-a", "-m", "plan feature"}},
+		{args: []string{"merge", "topic"}, fail: true},
+		{plan: "# Plan\ntopic and feature\n", args: []string{"add", "plan.md"}},
+		{args: []string{"commit", "--no-edit"}},
+		{args: []string{"log"}},
+		// failing calls are not recorded
+		{args: []string{"merge", "unknown"}, fail: true},
+	} {
+		if step.plan != "" {
+			err = os.WriteFile("plan.md", []byte(step.plan), fileMode)
+			if err != nil {
+				t.Fatalf("ERROR: test setup failed: %v", err)
+			}
+		}
+		code, err := RecordGit(step.args, nil, io.Discard, io.Discard)
+		if err != nil || (code != 0) != step.fail {
+			t.Fatalf("ERROR: git %v: got %d, %v", step.args, code, err)
+		}
+	}
+
+	err = rec.Write()
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+
+	formula, err := Read(filepath.Join(rec.TaskDir, FormulaFileName))
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+	opt := Options{RepoDir: filepath.Join(baseDir, "repos"), CfgDir: baseDir, TaskDir: "recorded"}
+	err = Transmute(formula, opt, logger)
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+
+	clone := filepath.Join(opt.RepoDir, "recorded")
+	out, err := exec.Command(gitCmd, "-C", clone,
+		"log", "--first-parent", "--format=%D|%an|%s").Output()
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+	want := "HEAD -> feature|" + author["red"] + "|Merge branch 'topic' into feature\n" +
+		"|" + author["red"] + "|plan feature\n" +
+		"|" + author["blue"] + "|add the plan\n"
+	if diff := cmp.Diff(string(out), want); diff != "" {
+		t.Errorf("ERROR: got- want+\n%s\n", diff)
+	}
+
+	// the merge keeps topic as second parent and the resolved file
+	out, err = exec.Command(gitCmd, "-C", clone, "show", "--format=", "HEAD^2:plan.md", "HEAD:plan.md").Output()
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+	want = "# Plan\ntopic\n# Plan\ntopic and feature\n"
+	if diff := cmp.Diff(string(out), want); diff != "" {
+		t.Errorf("ERROR: got- want+\n%s\n", diff)
+	}
+}
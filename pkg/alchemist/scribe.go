@@ -0,0 +1,463 @@
+package alchemist
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Formats of an exported script.
+const (
+	ExportBash       = "bash"
+	ExportPowerShell = "powershell"
+)
+
+// dialects maps the export formats to their script dialect.
+var dialects = map[string]dialect{
+	ExportBash:       bash{},
+	ExportPowerShell: powershell{},
+}
+
+// Variables of an exported script.
+const (
+	scriptRepoDir    = "REPO_DIR"    // RepoDir of the options
+	scriptTaskDir    = "TASK_DIR"    // directory of the formula
+	scriptCommitDate = "COMMIT_DATE" // author date of the commits
+)
+
+// commitDateOffset is the age of the commits, see gitCommitDateFormat.
+// Git does not parse relative dates in the environment, so the script
+// computes the date when it starts.
+const commitDateOffset = 5 * time.Hour
+
+// publicKeyMarker marks the public key of a key file in the content
+// of a file, the key is read when the script runs.
+const publicKeyMarker = "\x00gitalchemist-public-key:"
+
+// scriptWord is an argument of a script command. If the variable is
+// set, the text is a slash separated path relative to the directory
+// in the variable and the prefix is put in front of the variable.
+type scriptWord struct {
+	prefix   string
+	variable string
+	text     string
+}
+
+// literal returns a word that is used as it is.
+func literal(text string) scriptWord {
+	return scriptWord{text: text}
+}
+
+// join returns the word with the slash separated path appended.
+func (w scriptWord) join(elem string) scriptWord {
+	if w.variable != "" && w.text == "" {
+		w.text = elem
+		return w
+	}
+	w.text += "/" + elem
+	return w
+}
+
+// scriptEnv is an environment variable of a single command.
+type scriptEnv struct {
+	name  string
+	value scriptWord
+}
+
+// fileContent is the content of a file written by a script. The public
+// keys are placed between the texts when the script runs.
+type fileContent struct {
+	texts []string     // one more than keys
+	keys  []scriptWord // public key files
+}
+
+// dialect defines the commands of a script language.
+type dialect interface {
+	// header returns the start of the script that sets the variables
+	header(title, repoDir, taskDir string) string
+	// command returns a command with environment variables
+	command(env []scriptEnv, name string, args ...scriptWord) string
+	// makedir returns a command that creates a directory with parents
+	makedir(dir scriptWord) string
+	// copy returns a command that copies a file or the content of a
+	// directory
+	copy(from, to scriptWord, recursive bool) string
+	// writeFile returns a command that writes the content to a file
+	writeFile(name scriptWord, content fileContent, mode fs.FileMode) string
+	// chmod returns a command that changes the mode of a file
+	chmod(name scriptWord, mode fs.FileMode) string
+	// keygen returns a command that creates an ssh key pair unless
+	// it exists
+	keygen(file scriptWord, comment string) string
+}
+
+// scriptDir is a directory that is replaced by a variable of the script.
+type scriptDir struct {
+	variable string
+	paths    []string // cleaned relative and absolute path
+}
+
+// scribe is an assistant that does not execute the commands, it writes
+// them as script. The directories of the repos and the formula become
+// variables of the script, the infos become comments.
+// The logging is delegated to the novice.
+//
+// It implements the assistant interface.
+//
+// Only files of the formula are read, the output of git is only known
+// for the version and the remote urls that were set by the script.
+type scribe struct {
+	novice
+	dialect   dialect
+	dirs      []scriptDir
+	remotes   map[string]string // dir and name -> url
+	worktrees map[string]string // dir of the worktree -> dir of the clone
+	script    strings.Builder
+}
+
+// newScribe returns an initialized scribe object.
+func newScribe(l *log.Logger, opt Options, d dialect) (*scribe, error) {
+
+	s := &scribe{
+		novice:    newNovice(l, opt),
+		dialect:   d,
+		remotes:   map[string]string{},
+		worktrees: map[string]string{},
+	}
+	for _, dir := range []scriptDir{
+		{variable: scriptTaskDir, paths: []string{filepath.Join(opt.CfgDir, opt.TaskDir)}},
+		{variable: scriptRepoDir, paths: []string{filepath.Clean(opt.RepoDir)}},
+	} {
+		abs, err := filepath.Abs(dir.paths[0])
+		if err != nil {
+			return nil, IOError{Cmd: "absolute path", Arg: dir.paths[0], Err: err}
+		}
+		dir.paths = append(dir.paths, abs)
+		s.dirs = append(s.dirs, dir)
+	}
+	return s, nil
+}
+
+// Export writes the formula as script in the format to w instead of
+// executing it. The script uses the RepoDir of the options and the
+// directory of the formula unless REPO_DIR and TASK_DIR are set.
+// Conditions are evaluated when exporting.
+func Export(f Formula, opt Options, format string, w io.Writer, logger *log.Logger) error {
+
+	d, ok := dialects[format]
+	if !ok {
+		return InvalidValueError{Variable: "format", Reason: "unknown format " + format}
+	}
+	s, err := newScribe(logger, opt, d)
+	if err != nil {
+		return err
+	}
+
+	err = transmute(f, opt, s)
+	if err != nil {
+		return err
+	}
+
+	taskDir := filepath.ToSlash(filepath.Join(opt.CfgDir, opt.TaskDir))
+	_, err = io.WriteString(w, d.header(f.Title, filepath.ToSlash(opt.RepoDir), taskDir)+s.script.String())
+	if err != nil {
+		return IOError{Cmd: "write", Arg: format + " script", Err: err}
+	}
+	return nil
+}
+
+// write adds the command to the script.
+func (s *scribe) write(command string) {
+	s.script.WriteString(command)
+	s.script.WriteString("\n")
+}
+
+// word returns the path as word. A path in a directory of the
+// script is relative to the variable of the innermost directory.
+func (s *scribe) word(path string) scriptWord {
+	result, length := literal(path), -1
+	for _, dir := range s.dirs {
+		for _, p := range dir.paths {
+			rest, ok := cutDir(path, p)
+			if ok && len(p) > length {
+				result = scriptWord{variable: dir.variable, text: filepath.ToSlash(rest)}
+				length = len(p)
+			}
+		}
+	}
+	return result
+}
+
+// arg returns the git argument as word. Absolute paths in a directory
+// of the script are replaced, e.g. in -c user.signingKey=/path.
+func (s *scribe) arg(arg string) scriptWord {
+	result, length := literal(arg), -1
+	for _, dir := range s.dirs {
+		p := dir.paths[len(dir.paths)-1]
+		i := strings.Index(arg, p)
+		if i < 0 || (i > 0 && arg[i-1] != '=') {
+			continue
+		}
+		rest, ok := cutDir(arg[i:], p)
+		if ok && len(p) > length {
+			result = scriptWord{prefix: arg[:i], variable: dir.variable, text: filepath.ToSlash(rest)}
+			length = len(p)
+		}
+	}
+	return result
+}
+
+// cutDir returns the path relative to the directory and reports
+// if it is in the directory. Every relative path is in ".".
+func cutDir(path, dir string) (string, bool) {
+	path = filepath.Clean(path)
+	switch {
+	case path == dir:
+		return "", true
+	case dir == "." && !filepath.IsAbs(path):
+		return path, true
+	}
+	return strings.CutPrefix(path, dir+string(filepath.Separator))
+}
+
+// git writes the git command with -C for the directory. The user and
+// author options become environment variables.
+// It implements the assistant interface.
+func (s *scribe) git(dir string, args ...string) error {
+	_ = s.novice.git(dir, args...)
+
+	// remember the remotes for gitOutput
+	if len(args) > 3 && args[0] == "remote" && (args[1] == "add" || args[1] == "set-url") {
+		s.remotes[dir+"\x00"+args[len(args)-2]] = args[len(args)-1]
+	}
+	// remember the worktrees for the hooks in gitOutput
+	if len(args) > 2 && args[0] == "worktree" && args[1] == "add" {
+		s.worktrees[filepath.Join(dir, worktreePath(args[2:]))] = dir
+	}
+
+	env, args := identity(args)
+	var words []scriptWord
+	if dir != "" {
+		words = append(words, literal("-C"), s.word(dir))
+	}
+	for _, arg := range args {
+		words = append(words, s.arg(arg))
+	}
+	s.write(s.dialect.command(env, linuxGitCmd, words...))
+	return nil
+}
+
+// worktreePath returns the path of the arguments of git worktree add.
+func worktreePath(args []string) string {
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "-b" || arg == "-B":
+			i++
+		case !strings.HasPrefix(arg, "-"):
+			return arg
+		}
+	}
+	return ""
+}
+
+// identity moves the user options and the author and date of a commit
+// to environment variables. The author of an amended commit stays
+// an option.
+func identity(args []string) ([]scriptEnv, []string) {
+
+	var authorName, authorEmail, committerName, committerEmail string
+	var date bool
+	var rest []string
+
+	i := 0
+	for ; i+1 < len(args) && args[i] == "-c"; i += 2 {
+		key, value, _ := strings.Cut(args[i+1], "=")
+		switch key {
+		case "user.name":
+			authorName, committerName = value, value
+		case "user.email":
+			authorEmail, committerEmail = value, value
+		default:
+			rest = append(rest, args[i], args[i+1])
+		}
+	}
+	for _, arg := range args[i:] {
+		value, ok := strings.CutPrefix(arg, "--author=")
+		name, email, isIdent := strings.Cut(strings.TrimSuffix(value, ">"), " <")
+		switch {
+		case arg == "--date="+gitCommitDateFormat:
+			date = true
+		case ok && isIdent && strings.HasSuffix(value, ">") && !slices.Contains(args, "--amend"):
+			// an amended commit keeps its author without --author
+			authorName, authorEmail = name, email
+		default:
+			rest = append(rest, arg)
+		}
+	}
+
+	var env []scriptEnv
+	add := func(name string, value scriptWord) {
+		if value.text != "" || value.variable != "" {
+			env = append(env, scriptEnv{name: name, value: value})
+		}
+	}
+	add("GIT_AUTHOR_NAME", literal(authorName))
+	add("GIT_AUTHOR_EMAIL", literal(authorEmail))
+	if date {
+		add("GIT_AUTHOR_DATE", scriptWord{variable: scriptCommitDate})
+	}
+	add("GIT_COMMITTER_NAME", literal(committerName))
+	add("GIT_COMMITTER_EMAIL", literal(committerEmail))
+	return env, rest
+}
+
+// gitOutput returns the git version or the url of a remote that was
+// set by the script. Other output is not known before the script runs.
+// It implements the assistant interface.
+func (s *scribe) gitOutput(dir string, args ...string) (string, error) {
+	_, _ = s.novice.gitOutput(dir, args...)
+
+	switch {
+	case len(args) == 1 && args[0] == "version":
+		// conditions are evaluated with the local git
+		return adept{exe: gitCmd}.gitOutput(dir, args...)
+	case len(args) == 3 && args[0] == "rev-parse" && args[1] == "--git-path" && args[2] == "hooks":
+		// the clones of the script have a .git directory, which is
+		// used without output, worktrees share the hooks of their clone
+		clone, ok := s.worktrees[filepath.Clean(dir)]
+		if !ok {
+			return "", nil
+		}
+		rel, err := filepath.Rel(dir, clone)
+		if err != nil {
+			return "", IOError{Cmd: "relative path", Arg: clone, Err: err}
+		}
+		return filepath.ToSlash(filepath.Join(rel, ".git", "hooks")) + "\n", nil
+	case len(args) == 3 && args[0] == "remote" && args[1] == "get-url":
+		url, ok := s.remotes[dir+"\x00"+args[2]]
+		if ok {
+			return url + "\n", nil
+		}
+	}
+	return "", InvalidValueError{Variable: "export",
+		Reason: "output of git " + strings.Join(args, " ") + " is not known before the script runs"}
+}
+
+// copy writes the copy command. The target is not examined, it does
+// not exist before the script runs.
+// It implements the assistant interface.
+func (s *scribe) copy(from, to string) error {
+	_ = s.novice.copy(from, to)
+
+	info, err := os.Lstat(from)
+	if err != nil {
+		return IOError{Cmd: "stat", Arg: from, Err: err}
+	}
+
+	dir := filepath.Dir(to)
+	switch {
+	case info.IsDir():
+		dir = to
+	case strings.HasSuffix(to, string(os.PathSeparator)):
+		dir = to
+		to = filepath.Join(to, filepath.Base(from))
+	}
+
+	s.write(s.dialect.makedir(s.word(dir)))
+	s.write(s.dialect.copy(s.word(from), s.word(to), info.IsDir()))
+	return nil
+}
+
+// makedir writes the command that creates the directory.
+// It implements the assistant interface.
+func (s *scribe) makedir(dir string) error {
+	_ = s.novice.makedir(dir)
+	s.write(s.dialect.makedir(s.word(dir)))
+	return nil
+}
+
+// readFile returns the content of a file of the formula.
+// It implements the assistant interface.
+func (s *scribe) readFile(name string) ([]byte, error) {
+	_, _ = s.novice.readFile(name)
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, IOError{Cmd: "read", Arg: name, Err: err}
+	}
+	return data, nil
+}
+
+// writeFile writes the command that writes the data to the file.
+// The public keys returned by keygen are read by the script.
+// It implements the assistant interface.
+func (s *scribe) writeFile(name string, data []byte, mode fs.FileMode) error {
+	_ = s.novice.writeFile(name, data, mode)
+
+	var content fileContent
+	text := string(data)
+	for {
+		before, after, ok := strings.Cut(text, publicKeyMarker)
+		if !ok {
+			break
+		}
+		file, rest, _ := strings.Cut(after, "\x00")
+		content.texts = append(content.texts, before)
+		content.keys = append(content.keys, s.word(file+".pub"))
+		text = rest
+	}
+	content.texts = append(content.texts, text)
+
+	s.write(s.dialect.makedir(s.word(filepath.Dir(name))))
+	s.write(s.dialect.writeFile(s.word(name), content, mode))
+	return nil
+}
+
+// chmod writes the command that changes the mode of the file.
+// It implements the assistant interface.
+func (s *scribe) chmod(name string, mode fs.FileMode) error {
+	_ = s.novice.chmod(name, mode)
+	s.write(s.dialect.chmod(s.word(name), mode))
+	return nil
+}
+
+// keygen writes the command that creates the key pair. It returns a
+// marker for the public key that is replaced by writeFile.
+// It implements the assistant interface.
+func (s *scribe) keygen(file, comment string) (string, error) {
+	_, _ = s.novice.keygen(file, comment)
+	s.write(s.dialect.makedir(s.word(filepath.Dir(file))))
+	s.write(s.dialect.keygen(s.word(file), comment))
+	return publicKeyMarker + file + "\x00", nil
+}
+
+// info writes the message as comment and logs it.
+// It implements the assistant interface.
+func (s *scribe) info(msg string, args ...any) {
+	s.novice.info(msg, args...)
+	s.script.WriteString("\n")
+	for _, line := range strings.Split(fmt.Sprintf(msg, args...), "\n") {
+		s.write(strings.TrimRight("# "+line, " "))
+	}
+}
+
+// isText reports if the text consists of printable characters
+// and line breaks.
+func isText(text string) bool {
+	if !utf8.ValidString(text) {
+		return false
+	}
+	for _, r := range text {
+		if r < ' ' && r != '\t' && r != '\n' && r != '\r' || r == 0x7f {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,242 @@
+package alchemist
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/HMS-Analytical-Software/goGitAlchemist/pkg/check"
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestScribe tests the commands that the scribe writes.
+func TestScribe(t *testing.T) {
+
+	opt := Options{RepoDir: "cwd", CfgDir: ".", TaskDir: TestDataDir}
+	s, err := newScribe(nil, opt, bash{})
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+	repo := filepath.Join("cwd", "clone")
+	absKey, err := filepath.Abs(filepath.Join("cwd", "keys", "red"))
+	if err != nil {
+		t.Fatalf("ERROR: test setup failed: %v", err)
+	}
+
+	s.info("1/2: commit")
+	_ = s.git(repo, "remote", "set-url", "origin", "../remotes/clone")
+	_ = s.git(repo, "-c", "user.name="+author["red"], "-c", "user.email="+email["red"],
+		"-c", "user.signingKey="+absKey, "commit", "--date="+gitCommitDateFormat,
+		"-m", "it's done", "--author="+getAuthor("blue"))
+	_ = s.git(repo, "commit", "--amend", "--author="+getAuthor("blue"))
+	_ = s.copy(filepath.Join(TestDataDir, "source.txt"), filepath.Join(repo, "docs")+string(filepath.Separator))
+	_ = s.chmod(filepath.Join(repo, "run.sh"), executableMode)
+	public, _ := s.keygen(filepath.Join("cwd", "keys", "red"), email["red"])
+	_ = s.writeFile(filepath.Join(repo, "signers"), []byte("red "+public+"\n"), fileMode)
+	_ = s.writeFile(filepath.Join(repo, "crlf.txt"), []byte("100%\r\n"), executableMode)
+	_ = s.makedir("/tmp")
+
+	want := `
+# 1/2: commit
+git -C "${REPO_DIR}/clone" remote set-url origin ../remotes/clone
+GIT_AUTHOR_NAME='Betty Blue' GIT_AUTHOR_EMAIL=betty@pw-compa.ny GIT_AUTHOR_DATE="${COMMIT_DATE}" ` +
+		`GIT_COMMITTER_NAME='Richard Red' GIT_COMMITTER_EMAIL=richard@pw-compa.ny ` +
+		`git -C "${REPO_DIR}/clone" -c "user.signingKey=${REPO_DIR}/keys/red" commit -m 'it'\''s done'
+git -C "${REPO_DIR}/clone" commit --amend '--author=Betty Blue <betty@pw-compa.ny>'
+mkdir -p "${REPO_DIR}/clone/docs"
+cp -PR "${TASK_DIR}/source.txt" "${REPO_DIR}/clone/docs/source.txt"
+chmod 755 "${REPO_DIR}/clone/run.sh"
+mkdir -p "${REPO_DIR}/keys"
+[ -f "${REPO_DIR}/keys/red" ] || ssh-keygen -q -t ed25519 -N '' -C richard@pw-compa.ny -f "${REPO_DIR}/keys/red"
+mkdir -p "${REPO_DIR}/clone"
+printf -- 'red %s\n' "$(cat "${REPO_DIR}/keys/red.pub")" > "${REPO_DIR}/clone/signers"
+mkdir -p "${REPO_DIR}/clone"
+printf -- '100%%\015\n' > "${REPO_DIR}/clone/crlf.txt"
+chmod 755 "${REPO_DIR}/clone/crlf.txt"
+mkdir -p /tmp
+`
+	if runtime.GOOS == "windows" {
+		want = strings.ReplaceAll(want, "mkdir -p /tmp", `mkdir -p '\tmp'`)
+	}
+	if diff := cmp.Diff(s.script.String(), want); diff != "" {
+		t.Errorf("ERROR: got- want+\n%s\n", diff)
+	}
+
+	// output of git
+	origin, err := s.gitOutput(repo, "remote", "get-url", "origin")
+	if err != nil || origin != "../remotes/clone\n" {
+		t.Errorf("ERROR: got %q, %v, want remote url", origin, err)
+	}
+	hooks, err := s.gitOutput(repo, "rev-parse", "--git-path", "hooks")
+	if err != nil || hooks != "" {
+		t.Errorf("ERROR: got %q, %v, want hooks of the clone", hooks, err)
+	}
+	_ = s.git(repo, "worktree", "add", "-b", "hotfix", filepath.Join("..", "hotfix"))
+	hooks, err = s.gitOutput(filepath.Join(repo, "..", "hotfix"), "rev-parse", "--git-path", "hooks")
+	if err != nil || hooks != "../clone/.git/hooks\n" {
+		t.Errorf("ERROR: got %q, %v, want shared hooks of the worktree", hooks, err)
+	}
+	_, err = s.gitOutput(repo, "status")
+	check.ErrorString(t, err, "value for export: output of git status is not known before the script runs")
+}
+
+// TestDialect tests the commands of the script dialects.
+func TestDialect(t *testing.T) {
+
+	file := scriptWord{variable: scriptRepoDir, text: "clone/a $b.txt"}
+	key := scriptWord{variable: scriptRepoDir, text: "keys/red.pub"}
+	dir := scriptWord{variable: scriptTaskDir}
+	env := []scriptEnv{
+		{name: "GIT_AUTHOR_NAME", value: literal("red")},
+		{name: "GIT_AUTHOR_DATE", value: scriptWord{variable: scriptCommitDate}},
+	}
+	args := []scriptWord{literal("commit"), literal("-m"), literal("it's „done“"), literal("--author=x")}
+
+	testCases := []struct {
+		name    string
+		dialect dialect
+		want    []string
+	}{{
+		name:    "bash",
+		dialect: bash{},
+		want: []string{
+			`GIT_AUTHOR_NAME=red GIT_AUTHOR_DATE="${COMMIT_DATE}" git commit -m 'it'\''s „done“' --author=x`,
+			`mkdir -p "${TASK_DIR}"`,
+			`cp -PR "${TASK_DIR}/." "${REPO_DIR}/clone/a \$b.txt"`,
+			"cat > \"${REPO_DIR}/clone/a \\$b.txt\" <<'EOF'\nline\nEOF",
+			`printf -- 'key %s\n' "$(cat "${REPO_DIR}/keys/red.pub")" > "${REPO_DIR}/clone/a \$b.txt"`,
+		},
+	}, {
+		name:    "powershell",
+		dialect: powershell{},
+		want: []string{
+			"$env:GIT_AUTHOR_NAME = 'red'\n" +
+				"$env:GIT_AUTHOR_DATE = \"${CommitDate}\"\n" +
+				"git commit -m 'it''s „done“' '--author=x'\n" +
+				"Remove-Item -Path Env:GIT_AUTHOR_NAME, Env:GIT_AUTHOR_DATE",
+			`New-Item -ItemType Directory -Force -Path "${TaskDir}" | Out-Null`,
+			"Get-ChildItem -Force -LiteralPath \"${TaskDir}\" | " +
+				"Copy-Item -Recurse -Force -Destination \"${RepoDir}/clone/a `$b.txt\"\n" +
+				"Get-ChildItem -Recurse -Force -File -LiteralPath \"${RepoDir}/clone/a `$b.txt\" | " +
+				"ForEach-Object { $_.LastWriteTime = Get-Date }",
+			"[IO.File]::WriteAllText((New-Item -Force -Path \"${RepoDir}/clone/a `$b.txt\").FullName, \"line`n\")",
+			"[IO.File]::WriteAllText((New-Item -Force -Path \"${RepoDir}/clone/a `$b.txt\").FullName, " +
+				"\"key \" + (Get-Content -Raw -LiteralPath \"${RepoDir}/keys/red.pub\").Trim() + \"`n\")",
+		},
+	}}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			got := []string{
+				c.dialect.command(env, "git", args...),
+				c.dialect.makedir(dir),
+				c.dialect.copy(dir, file, true),
+				c.dialect.writeFile(file, fileContent{texts: []string{"line\n"}}, fileMode),
+				c.dialect.writeFile(file, fileContent{texts: []string{"key ", "\n"},
+					keys: []scriptWord{key}}, fileMode),
+			}
+			if diff := cmp.Diff(got, c.want); diff != "" {
+				t.Errorf("ERROR: got- want+\n%s\n", diff)
+			}
+		})
+	}
+}
+
+// TestExportFormat tests an unknown script format.
+func TestExportFormat(t *testing.T) {
+	err := Export(Formula{}, Options{}, "cobol", io.Discard, nil)
+	check.ErrorString(t, err, "value for format: unknown format cobol")
+}
+
+// TestExport exports a formula as bash script, runs it, and compares
+// the history with the one of Transmute.
+func TestExport(t *testing.T) {
+
+	formula, err := readFormula(strings.NewReader(`
+title: exported
+commands:
+  - init_bare_repo:
+      bare: remotes/exported
+      clone_to: exported
+  - create_add_commit:
+      files:
+        - source.txt => docs/source.txt
+      message: add source
+      author: blue
+  - switch:
+      branch: feature
+      create: true
+  - create_file:
+      source: source.txt
+      target: docs/source.txt
+      eol: crlf
+  - add:
+      files:
+        - docs/source.txt
+  - commit:
+      message: use crlf
+      author: red
+  - merge:
+      source: feature
+      target: main
+      no_ff: true
+      author: green
+  - tag:
+      name: v1
+      message: first release
+      author: red
+`))
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+
+	_, err = exec.LookPath("bash")
+	if err != nil || runtime.GOOS == "windows" {
+		t.Skip("bash not found")
+	}
+
+	baseDir, err := filepath.Abs(filepath.Join(TestDataDir, "export"))
+	if err != nil {
+		t.Fatalf("ERROR: test setup failed: %v", err)
+	}
+	err = os.RemoveAll(baseDir)
+	if err != nil {
+		t.Fatalf("ERROR: test setup failed: %v", err)
+	}
+	if !testing.Verbose() {
+		defer os.RemoveAll(baseDir)
+	}
+
+	logger := log.New(io.Discard, "", 0)
+	opt := Options{RepoDir: filepath.Join(baseDir, "transmuted"), CfgDir: ".", TaskDir: TestDataDir}
+	err = Transmute(formula, opt, logger)
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+
+	var script bytes.Buffer
+	err = Export(formula, opt, ExportBash, &script, logger)
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+	cmd := exec.Command("bash", "-s")
+	cmd.Stdin = &script
+	cmd.Env = append(os.Environ(), "REPO_DIR="+filepath.Join(baseDir, "exported"))
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("ERROR: script failed: %v\n%s", err, output)
+	}
+
+	args := []string{"log", "--all", "--format=%T %an %cn %s", "--date-order"}
+	want := importGit(t, filepath.Join(opt.RepoDir, "exported"), args...)
+	got := importGit(t, filepath.Join(baseDir, "exported", "exported"), args...)
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("ERROR: got- want+\n%s\n", diff)
+	}
+}
@@ -0,0 +1,167 @@
+package alchemist
+
+import (
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// bash writes scripts for the bash.
+//
+// It implements the dialect interface.
+type bash struct{}
+
+// bashPlain matches words that need no quotes.
+var bashPlain = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// header sets the options of the bash, the directories, the commit
+// date, and the git environment.
+func (b bash) header(title, repoDir, taskDir string) string {
+	var h strings.Builder
+	h.WriteString("#!/usr/bin/env bash\n")
+	h.WriteString("# " + strings.ReplaceAll(title, "\n", " ") + "\n")
+	h.WriteString("# exported by gitalchemist, set REPO_DIR and TASK_DIR to use other directories\n")
+	h.WriteString("set -euo pipefail\n\n")
+	for _, v := range []struct{ name, value string }{
+		{scriptRepoDir, repoDir},
+		{scriptTaskDir, taskDir},
+	} {
+		fmt.Fprintf(&h, "if [ -z \"${%s:-}\" ]; then %[1]s=%s; fi\n", v.name, b.quote(v.value))
+	}
+	fmt.Fprintf(&h, "mkdir -p \"${%s}\"\n", scriptRepoDir)
+	fmt.Fprintf(&h, "%s=\"$(cd \"${%[1]s}\" && pwd)\"\n", scriptRepoDir)
+	fmt.Fprintf(&h, "%s=\"$(cd \"${%[1]s}\" && pwd)\"\n", scriptTaskDir)
+	fmt.Fprintf(&h, "%s=\"@$(( $(date +%%s) - %d )) +0000\"\n\n", scriptCommitDate,
+		int(commitDateOffset.Seconds()))
+	for _, env := range gitEnvironment {
+		name, value, _ := strings.Cut(env, "=")
+		fmt.Fprintf(&h, "export %s=%s\n", name, b.quote(value))
+	}
+	return h.String()
+}
+
+// quote returns the text in single quotes unless it is plain.
+func (b bash) quote(text string) string {
+	if bashPlain.MatchString(text) {
+		return text
+	}
+	return "'" + strings.ReplaceAll(text, "'", `'\''`) + "'"
+}
+
+// word returns the word for the bash. Paths in a directory of the
+// script are in double quotes.
+func (b bash) word(w scriptWord) string {
+	if w.variable == "" {
+		return b.quote(w.text)
+	}
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`").Replace
+	result := `"` + escape(w.prefix) + "${" + w.variable + "}"
+	if w.text != "" {
+		result += "/" + escape(w.text)
+	}
+	return result + `"`
+}
+
+// command returns the command with the environment variables in front.
+func (b bash) command(env []scriptEnv, name string, args ...scriptWord) string {
+	var words []string
+	for _, e := range env {
+		words = append(words, e.name+"="+b.word(e.value))
+	}
+	words = append(words, name)
+	for _, arg := range args {
+		words = append(words, b.word(arg))
+	}
+	return strings.Join(words, " ")
+}
+
+// makedir returns mkdir -p.
+func (b bash) makedir(dir scriptWord) string {
+	return "mkdir -p " + b.word(dir)
+}
+
+// copy returns cp that keeps symbolic links. The time is not kept,
+// git misses changes of files with the same size and time.
+func (b bash) copy(from, to scriptWord, recursive bool) string {
+	if recursive {
+		from = from.join(".")
+	}
+	return "cp -PR " + b.word(from) + " " + b.word(to)
+}
+
+// writeFile returns a here document for text files. Other content
+// and public keys are written with printf.
+func (b bash) writeFile(name scriptWord, content fileContent, mode fs.FileMode) string {
+
+	var result string
+	text := content.texts[0]
+	if len(content.keys) == 0 && isHereDoc(text) {
+		result = "cat > " + b.word(name) + " <<'" + hereDocEnd + "'\n" + text + hereDocEnd
+	} else {
+		var format strings.Builder
+		var keys []string
+		for i, text := range content.texts {
+			if i > 0 {
+				format.WriteString("%s")
+				keys = append(keys, `"$(cat `+b.word(content.keys[i-1])+`)"`)
+			}
+			format.WriteString(printfFormat(text))
+		}
+		result = strings.Join(append([]string{"printf", "--", "'" + format.String() + "'"}, keys...), " ") +
+			" > " + b.word(name)
+	}
+
+	if mode.Perm() != fileMode {
+		result += "\n" + b.chmod(name, mode)
+	}
+	return result
+}
+
+// chmod returns chmod with the octal mode.
+func (b bash) chmod(name scriptWord, mode fs.FileMode) string {
+	return "chmod " + strconv.FormatUint(uint64(mode.Perm()), 8) + " " + b.word(name)
+}
+
+// keygen returns ssh-keygen for an ed25519 key without passphrase.
+func (b bash) keygen(file scriptWord, comment string) string {
+	return "[ -f " + b.word(file) + " ] || ssh-keygen -q -t ed25519 -N '' -C " +
+		b.quote(comment) + " -f " + b.word(file)
+}
+
+// hereDocEnd ends a here document.
+const hereDocEnd = "EOF"
+
+// isHereDoc reports if the text can be written with a here document:
+// it consists of lines of printable characters.
+func isHereDoc(text string) bool {
+	if !strings.HasSuffix(text, "\n") || strings.Contains(text, "\r") || !isText(text) {
+		return false
+	}
+	return !slices.Contains(strings.Split(text, "\n"), hereDocEnd)
+}
+
+// printfFormat returns the text as format of printf in single quotes.
+// Special characters are written as octal escapes.
+func printfFormat(text string) string {
+	var f strings.Builder
+	for _, c := range []byte(text) {
+		switch {
+		case c == '%':
+			f.WriteString("%%")
+		case c == '\\':
+			f.WriteString(`\\`)
+		case c == '\'':
+			f.WriteString(`'\''`)
+		case c == '\n':
+			f.WriteString(`\n`)
+		case c < ' ' || c >= 0x7f:
+			fmt.Fprintf(&f, `\%03o`, c)
+		default:
+			f.WriteByte(c)
+		}
+	}
+	return f.String()
+}
@@ -0,0 +1,176 @@
+package alchemist
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// powershell writes scripts for PowerShell 7.3 or later, which passes
+// the arguments of native commands as they are.
+//
+// It implements the dialect interface.
+type powershell struct{}
+
+// powershellPlain matches words that need no quotes.
+var powershellPlain = regexp.MustCompile(`^([A-Za-z0-9_+./][A-Za-z0-9_+./-]*|--?[A-Za-z][A-Za-z0-9-]*)$`)
+
+// powershellVariables maps the script variables to PowerShell names.
+var powershellVariables = map[string]string{
+	scriptRepoDir:    "RepoDir",
+	scriptTaskDir:    "TaskDir",
+	scriptCommitDate: "CommitDate",
+}
+
+// header sets the error handling, the directories, the commit date,
+// and the git environment.
+func (p powershell) header(title, repoDir, taskDir string) string {
+	var h strings.Builder
+	h.WriteString("#Requires -Version 7.3\n")
+	h.WriteString("# " + strings.ReplaceAll(title, "\n", " ") + "\n")
+	h.WriteString("# exported by gitalchemist, set REPO_DIR and TASK_DIR to use other directories\n")
+	h.WriteString("$ErrorActionPreference = 'Stop'\n")
+	h.WriteString("$PSNativeCommandUseErrorActionPreference = $true\n")
+	h.WriteString("$PSNativeCommandArgumentPassing = 'Standard'\n\n")
+	for _, v := range []struct{ name, value string }{
+		{scriptRepoDir, repoDir},
+		{scriptTaskDir, taskDir},
+	} {
+		fmt.Fprintf(&h, "$%s = if ($env:%s) { $env:%[2]s } else { %s }\n",
+			powershellVariables[v.name], v.name, p.str(v.value))
+	}
+	fmt.Fprintf(&h, "$%s = (New-Item -ItemType Directory -Force -Path $%[1]s).FullName\n",
+		powershellVariables[scriptRepoDir])
+	fmt.Fprintf(&h, "$%s = (Resolve-Path -Path $%[1]s).Path\n", powershellVariables[scriptTaskDir])
+	fmt.Fprintf(&h, "$%s = '@{0} +0000' -f ([DateTimeOffset]::UtcNow.ToUnixTimeSeconds() - %d)\n\n",
+		powershellVariables[scriptCommitDate], int(commitDateOffset.Seconds()))
+	for _, env := range gitEnvironment {
+		name, value, _ := strings.Cut(env, "=")
+		fmt.Fprintf(&h, "$env:%s = %s\n", name, p.str(value))
+	}
+	return h.String()
+}
+
+// quote returns the argument in single quotes unless it is plain.
+func (p powershell) quote(text string) string {
+	if powershellPlain.MatchString(text) {
+		return text
+	}
+	return p.str(text)
+}
+
+// str returns the text in single quotes, e.g. for assignments where
+// a plain word would be a command.
+// The typographic single quotes are quotes in PowerShell, too.
+func (p powershell) str(text string) string {
+	escape := strings.NewReplacer("'", "''", "‘", "‘‘", "’", "’’",
+		"‚", "‚‚", "‛", "‛‛").Replace
+	return "'" + escape(text) + "'"
+}
+
+// escape escapes the text for double quotes.
+func (p powershell) escape(text string) string {
+	return strings.NewReplacer("`", "``", "$", "`$", `"`, "`\"", "“", "`“",
+		"”", "`”", "„", "`„", "\n", "`n", "\r", "`r", "\t", "`t").Replace(text)
+}
+
+// word returns the word for PowerShell. Paths in a directory of the
+// script are in double quotes.
+func (p powershell) word(w scriptWord) string {
+	if w.variable == "" {
+		return p.quote(w.text)
+	}
+	result := `"` + p.escape(w.prefix) + "${" + powershellVariables[w.variable] + "}"
+	if w.text != "" {
+		result += "/" + p.escape(w.text)
+	}
+	return result + `"`
+}
+
+// command returns the command. The environment variables are set
+// before and removed after the command.
+func (p powershell) command(env []scriptEnv, name string, args ...scriptWord) string {
+	var lines, names []string
+	for _, e := range env {
+		value := p.str(e.value.text)
+		if e.value.variable != "" {
+			value = p.word(e.value)
+		}
+		lines = append(lines, "$env:"+e.name+" = "+value)
+		names = append(names, "Env:"+e.name)
+	}
+
+	words := []string{name}
+	for _, arg := range args {
+		words = append(words, p.word(arg))
+	}
+	lines = append(lines, strings.Join(words, " "))
+
+	if len(names) > 0 {
+		lines = append(lines, "Remove-Item -Path "+strings.Join(names, ", "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// makedir returns New-Item for a directory.
+func (p powershell) makedir(dir scriptWord) string {
+	return "New-Item -ItemType Directory -Force -Path " + p.word(dir) + " | Out-Null"
+}
+
+// copy returns Copy-Item, the content of a directory is copied
+// including hidden files. The copied files get the current time,
+// git misses changes of files with the same size and time.
+func (p powershell) copy(from, to scriptWord, recursive bool) string {
+	if recursive {
+		return "Get-ChildItem -Force -LiteralPath " + p.word(from) +
+			" | Copy-Item -Recurse -Force -Destination " + p.word(to) + "\n" +
+			"Get-ChildItem -Recurse -Force -File -LiteralPath " + p.word(to) +
+			" | ForEach-Object { $_.LastWriteTime = Get-Date }"
+	}
+	return "Copy-Item -Force -LiteralPath " + p.word(from) + " -Destination " + p.word(to) + "\n" +
+		"(Get-Item -Force -LiteralPath " + p.word(to) + ").LastWriteTime = Get-Date"
+}
+
+// writeFile returns WriteAllText for text files, public keys are read
+// with Get-Content. Other content is written with WriteAllBytes.
+func (p powershell) writeFile(name scriptWord, content fileContent, mode fs.FileMode) string {
+
+	target := "(New-Item -Force -Path " + p.word(name) + ").FullName"
+
+	var result string
+	if len(content.keys) == 0 && !isText(content.texts[0]) {
+		result = "[IO.File]::WriteAllBytes(" + target + ", [Convert]::FromBase64String('" +
+			base64.StdEncoding.EncodeToString([]byte(content.texts[0])) + "'))"
+	} else {
+		var parts []string
+		for i, text := range content.texts {
+			if i > 0 {
+				parts = append(parts, "(Get-Content -Raw -LiteralPath "+p.word(content.keys[i-1])+").Trim()")
+			}
+			if text != "" || len(content.texts) == 1 {
+				parts = append(parts, `"`+p.escape(text)+`"`)
+			}
+		}
+		result = "[IO.File]::WriteAllText(" + target + ", " + strings.Join(parts, " + ") + ")"
+	}
+
+	if mode.Perm() != fileMode {
+		result += "\n" + p.chmod(name, mode)
+	}
+	return result
+}
+
+// chmod returns chmod, which is skipped on Windows.
+func (p powershell) chmod(name scriptWord, mode fs.FileMode) string {
+	return "if (-not $IsWindows) { chmod " + strconv.FormatUint(uint64(mode.Perm()), 8) +
+		" " + p.word(name) + " }"
+}
+
+// keygen returns ssh-keygen for an ed25519 key without passphrase.
+func (p powershell) keygen(file scriptWord, comment string) string {
+	return "if (-not (Test-Path -LiteralPath " + p.word(file) + ")) { ssh-keygen -q -t ed25519 -N '' -C " +
+		p.quote(comment) + " -f " + p.word(file) + " }"
+}
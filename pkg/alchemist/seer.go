@@ -0,0 +1,692 @@
+package alchemist
+
+import (
+	"io/fs"
+	"log"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// seer is an assistant that does not execute the commands, it foresees
+// their results. For each repo it keeps the branches, HEAD, the index,
+// and the paths of the worktree in memory, without the content of the
+// files. A command that git would reject, e.g. a merge of an unknown
+// branch or a move of a file that was never created, returns an error.
+//
+// A repo becomes blurred when a command changes it in a way the seer
+// can not foresee, e.g. a pull or a rebase. The commands of a blurred
+// repo are not checked anymore.
+// The logging is delegated to the novice.
+//
+// It implements the assistant interface.
+//
+// It is used for test mode.
+type seer struct {
+	novice
+	visions map[string]*vision // directory -> repo or worktree
+}
+
+// foresight is the foreseen history of a repo. It is shared by the
+// worktrees of the repo.
+type foresight struct {
+	timeline *timeline
+	trees    map[int]map[string]bool // commit -> tracked paths
+	remotes  map[string]string       // remote -> directory
+	user     string
+	bare     bool
+	blurred  bool
+}
+
+// vision is the foreseen state of a repo or one of its worktrees.
+// The paths are slash separated and relative to the worktree. A path
+// can be a directory that was copied, its files are not known.
+type vision struct {
+	*foresight
+	head     string          // current branch, empty if HEAD is detached
+	detached int             // commit of the detached HEAD
+	index    map[string]bool // tracked and staged paths
+	files    map[string]bool // paths in the worktree
+	modified map[string]bool // paths written after they were staged
+	staged   bool            // the index differs from HEAD
+}
+
+// gitReadOnly are the git commands that do not change the state that
+// the seer foresees.
+var gitReadOnly = []string{
+	"log", "show", "status", "diff", "rev-parse", "rev-list", "ls-files",
+	"ls-tree", "cat-file", "for-each-ref", "show-ref", "describe",
+	"blame", "shortlog", "grep", "version", "notes", "sparse-checkout",
+	"bundle", "gc", "reflog", "fsck", "update-ref",
+}
+
+// newSeer returns an initialized seer object.
+func newSeer(l *log.Logger, opt Options) *seer {
+	return &seer{
+		novice:  newNovice(l, opt),
+		visions: map[string]*vision{},
+	}
+}
+
+// newVision returns the vision of an empty repo on the branch.
+func newVision(branch string, bare bool) *vision {
+	return &vision{
+		foresight: &foresight{
+			timeline: newTimeline(branch),
+			trees:    map[int]map[string]bool{},
+			remotes:  map[string]string{},
+			bare:     bare,
+		},
+		head:     branch,
+		index:    map[string]bool{},
+		files:    map[string]bool{},
+		modified: map[string]bool{},
+	}
+}
+
+// git foresees the result of the git command. If git would fail, an
+// ExecError is returned.
+// It implements the assistant interface.
+func (s *seer) git(dir string, args ...string) error {
+	_ = s.novice.git(dir, args...)
+
+	dir = filepath.Clean(dir)
+	cmd := args
+	for len(cmd) > 1 && cmd[0] == "-c" {
+		cmd = cmd[2:]
+	}
+	if len(cmd) == 0 {
+		return nil
+	}
+
+	var err error
+	switch v := s.visions[dir]; {
+	case cmd[0] == "init":
+		s.init(dir, cmd[1:])
+	case cmd[0] == "clone":
+		s.clone(dir, cmd[1:])
+	case v == nil || v.blurred:
+		// unknown repo or not foreseeable
+	case v.bare:
+		// only the history of clones is foreseen
+	default:
+		err = s.foresee(v, dir, cmd, args)
+	}
+
+	if err != nil {
+		return ExecError{Cmd: gitCmd, Args: args, Err: err}
+	}
+	return nil
+}
+
+// init foresees a new empty repo.
+func (s *seer) init(dir string, args []string) {
+	flags, names := splitArgs(args)
+	if len(names) > 0 {
+		dir = filepath.Join(dir, names[0])
+	}
+	branch := flags["--initial-branch"]
+	if branch == "" {
+		branch = defaultBranch
+	}
+	s.visions[dir] = newVision(branch, hasFlag(flags, "--bare"))
+}
+
+// clone foresees the clone of a repo. The clone of an unknown or a
+// blurred repo is blurred.
+func (s *seer) clone(dir string, args []string) {
+
+	flags, names := splitArgs(args, "-o", "--origin", "-b", "--branch",
+		"--depth", "--filter", "--reference", "-c", "--config")
+	if len(names) == 0 {
+		return
+	}
+	from := filepath.Join(dir, names[0])
+	to := filepath.Join(dir, strings.TrimSuffix(filepath.Base(names[0]), ".git"))
+	if len(names) > 1 {
+		to = filepath.Join(dir, names[1])
+	}
+	origin := flags["-o"] + flags["--origin"]
+	if origin == "" {
+		origin = "origin"
+	}
+
+	source := s.visions[from]
+	if source == nil || source.blurred {
+		v := newVision(defaultBranch, false)
+		v.blurred = true
+		s.visions[to] = v
+		return
+	}
+
+	head := flags["-b"] + flags["--branch"]
+	if head == "" {
+		head = source.head
+	}
+	bare := hasFlag(flags, "--bare", "--mirror")
+	v := newVision(head, bare)
+	t := v.timeline
+	t.commits = slices.Clone(source.timeline.commits)
+	t.tags = maps.Clone(source.timeline.tags)
+	maps.Copy(v.trees, source.trees)
+	for branch, id := range source.timeline.branches {
+		if bare {
+			t.branches[branch] = id
+		} else {
+			t.branches[origin+"/"+branch] = id
+		}
+	}
+	if id, ok := source.timeline.branches[head]; ok && !bare {
+		t.branches[head] = id
+		v.checkout(0, id)
+	}
+	v.remotes[origin] = from
+	s.visions[to] = v
+}
+
+// foresee foresees the result of a git command in a repo or worktree.
+// The args contain the options before the command, e.g. -c user.name=.
+func (s *seer) foresee(v *vision, dir string, cmd, args []string) error {
+
+	t := v.timeline
+	t.head, t.detached = v.head, v.detached
+	defer func() { v.head, v.detached = t.head, t.detached }()
+
+	before := t.current()
+	commits := len(t.commits)
+	flags, names := splitArgs(cmd[1:], "-m", "-F", "--message", "--file",
+		"--trailer", "-b", "-B", "-o", "--origin")
+
+	switch cmd[0] {
+	case "add":
+		return v.add(flags, names)
+	case "rm":
+		return v.remove(flags, names)
+	case "mv":
+		return v.move(names)
+	case "clean":
+		if !hasFlag(flags, "-n", "--dry-run") {
+			v.clean()
+		}
+		return nil
+	case "commit":
+		if hasFlag(flags, "-a", "--all") {
+			v.stageModified()
+		}
+		if len(names) > 0 {
+			v.staged = true
+		}
+		if !v.staged && !hasFlag(flags, "--allow-empty", "--amend") {
+			return InvalidValueError{Variable: "commit", Reason: "nothing to commit on " + t.headName()}
+		}
+	case "switch", "checkout":
+		v.trackRemote(flags, names)
+	case "merge":
+		if hasFlag(flags, "--squash") && len(names) > 0 {
+			id, err := t.resolve(names[0])
+			if err != nil {
+				return err
+			}
+			v.merge(v.trees[id])
+			v.staged = true
+			return nil
+		}
+	case "cherry-pick":
+		for _, name := range names {
+			id, err := t.resolve(name)
+			if err != nil {
+				return err
+			}
+			v.merge(v.trees[id])
+		}
+	case "worktree":
+		return s.worktree(v, dir, cmd[1:])
+	case "remote":
+		v.remote(dir, names)
+		return nil
+	case "push":
+		remote := "origin"
+		if len(names) > 0 {
+			remote = names[0]
+		}
+		if target := s.visions[v.remotes[remote]]; target != nil {
+			target.blurred = true
+		}
+		return nil
+	case "update-index":
+		v.staged = true
+		return nil
+	case "submodule":
+		if len(names) > 2 && names[0] == "add" {
+			for _, file := range []string{".gitmodules", filepath.ToSlash(names[2])} {
+				v.files[file] = true
+				v.index[file] = true
+			}
+			v.staged = true
+			return nil
+		}
+		s.blur(v, dir, cmd)
+		return nil
+	case "config", "branch", "tag", "reset", "revert":
+	default:
+		if !slices.Contains(gitReadOnly, cmd[0]) {
+			s.blur(v, dir, cmd)
+		}
+		return nil
+	}
+
+	err := followGit(t, &v.user, args)
+	if err != nil {
+		return err
+	}
+	after := t.current()
+
+	switch {
+	case cmd[0] == "switch" && hasFlag(flags, "--orphan"):
+		v.checkout(before, 0)
+	case cmd[0] == "reset" && hasFlag(flags, "--hard"):
+		v.checkout(before, after)
+		for file := range v.index {
+			if !v.trees[after][file] {
+				delete(v.index, file)
+				delete(v.files, file)
+			}
+		}
+		clear(v.modified)
+		v.staged = false
+	case cmd[0] == "reset" && hasFlag(flags, "--soft"):
+		v.staged = v.staged || after != before
+	case cmd[0] == "reset":
+		// the index is reset or files are unstaged
+		if after != before {
+			v.index = maps.Clone(v.trees[after])
+			if v.index == nil {
+				v.index = map[string]bool{}
+			}
+		}
+		v.staged = true
+	case len(t.commits) > commits:
+		// new commits, merges get the paths of both parents
+		for id := commits + 1; id <= len(t.commits); id++ {
+			if parents := t.commit(id).parents; len(parents) > 1 {
+				v.merge(v.trees[parents[1]])
+			}
+			v.trees[id] = maps.Clone(v.index)
+		}
+		v.staged = false
+	case after != before:
+		v.checkout(before, after)
+	}
+	return nil
+}
+
+// unforeseeable stops foreseeing all repos. Repos that are created
+// afterwards are foreseen.
+// It implements the foreseer interface.
+func (s *seer) unforeseeable(reason string) {
+	s.info("test mode: %s, the repos are not checked anymore", reason)
+	for _, v := range s.visions {
+		v.blurred = true
+	}
+}
+
+// blur stops foreseeing the repo.
+func (s *seer) blur(v *vision, dir string, cmd []string) {
+	s.info("test mode: can not foresee git %s, %s is not checked anymore", cmd[0], dir)
+	v.blurred = true
+}
+
+// add stages the paths. A path that is neither in the worktree nor in
+// the index is an error.
+func (v *vision) add(flags map[string]string, names []string) error {
+
+	if hasFlag(flags, "--renormalize", "-u", "--update") {
+		v.staged = true
+	}
+	if len(names) == 0 && hasFlag(flags, "-A", "--all", "-u", "--update") {
+		names = []string{"."}
+	}
+
+	for _, name := range names {
+		p := slashPath(name)
+		if !matchAny(v.files, p) && !matchAny(v.index, p) {
+			return InvalidValueError{Variable: "file", Reason: "pathspec " + name + " did not match any files"}
+		}
+		for file := range v.files {
+			if !within(file, p) && !within(p, file) {
+				continue
+			}
+			if !v.index[file] || v.modified[file] {
+				v.staged = true
+			}
+			v.index[file] = true
+			delete(v.modified, file)
+		}
+	}
+	return nil
+}
+
+// remove removes the paths from the index and, without --cached, from
+// the worktree. A path that is not in the index is an error.
+func (v *vision) remove(flags map[string]string, names []string) error {
+
+	for _, name := range names {
+		p := slashPath(name)
+		if !matchAny(v.index, p) {
+			return InvalidValueError{Variable: "file", Reason: "pathspec " + name + " did not match any files"}
+		}
+		for file := range v.index {
+			if !within(file, p) {
+				continue
+			}
+			delete(v.index, file)
+			if !hasFlag(flags, "--cached") {
+				delete(v.files, file)
+				delete(v.modified, file)
+			}
+		}
+		v.staged = true
+	}
+	return nil
+}
+
+// move moves the paths to the last name. A path that is not in the
+// worktree or not in the index is an error.
+func (v *vision) move(names []string) error {
+
+	if len(names) < 2 {
+		return nil
+	}
+	target := slashPath(names[len(names)-1])
+
+	for _, name := range names[:len(names)-1] {
+		source := slashPath(name)
+		switch {
+		case !matchAny(v.files, source):
+			return InvalidValueError{Variable: "file",
+				Reason: "bad source, source=" + name + ", destination=" + names[len(names)-1]}
+		case !matchAny(v.index, source):
+			return InvalidValueError{Variable: "file",
+				Reason: "not under version control, source=" + name + ", destination=" + names[len(names)-1]}
+		}
+
+		to := target
+		if v.isDir(target) {
+			to = path.Join(target, path.Base(source))
+		}
+		for _, paths := range []map[string]bool{v.files, v.index, v.modified} {
+			for file := range paths {
+				if within(file, source) {
+					delete(paths, file)
+					paths[to+strings.TrimPrefix(file, source)] = true
+				}
+			}
+		}
+		if !matchAny(v.index, to) {
+			// a file of a copied directory
+			v.files[to] = true
+			v.index[to] = true
+		}
+		v.staged = true
+	}
+	return nil
+}
+
+// clean removes the untracked paths from the worktree. The ignored
+// paths are not known, so nothing is removed if there is a .gitignore.
+func (v *vision) clean() {
+	for file := range v.files {
+		if path.Base(file) == ".gitignore" {
+			return
+		}
+	}
+	for file := range v.files {
+		if !matchAny(v.index, file) {
+			delete(v.files, file)
+			delete(v.modified, file)
+		}
+	}
+}
+
+// stageModified stages the tracked paths that were written.
+func (v *vision) stageModified() {
+	for file := range v.modified {
+		if v.index[file] {
+			v.staged = true
+			delete(v.modified, file)
+		}
+	}
+}
+
+// checkout replaces the tracked paths of commit from with the tracked
+// paths of commit to in the index and the worktree. Staged paths and
+// untracked paths are kept.
+func (v *vision) checkout(from, to int) {
+	for file := range v.trees[from] {
+		if !v.trees[to][file] {
+			delete(v.index, file)
+			delete(v.files, file)
+			delete(v.modified, file)
+		}
+	}
+	v.merge(v.trees[to])
+}
+
+// merge adds the paths to the index and the worktree. The paths are
+// kept even if a merge or a cherry-pick deletes them.
+func (v *vision) merge(paths map[string]bool) {
+	for file := range paths {
+		v.index[file] = true
+		v.files[file] = true
+	}
+}
+
+// trackRemote creates the branch of a switch from the remote-tracking
+// branch with the same name, like git does.
+func (v *vision) trackRemote(flags map[string]string, names []string) {
+
+	t := v.timeline
+	if len(names) != 1 || hasFlag(flags, "-c", "--create", "-b", "--orphan", "--detach", "--") {
+		return
+	}
+	if _, ok := t.branches[names[0]]; ok {
+		return
+	}
+	for _, remote := range slices.Sorted(maps.Keys(v.remotes)) {
+		if id, ok := t.branches[remote+"/"+names[0]]; ok {
+			t.branches[names[0]] = id
+			return
+		}
+	}
+}
+
+// remote foresees the changes of the remotes.
+func (v *vision) remote(dir string, names []string) {
+
+	if len(names) == 0 {
+		return
+	}
+	switch {
+	case (names[0] == "add" || names[0] == "set-url") && len(names) > 2:
+		url := names[2]
+		if !filepath.IsAbs(url) {
+			url = filepath.Join(dir, url)
+		}
+		v.remotes[names[1]] = filepath.Clean(url)
+	case names[0] == "rename" && len(names) > 2:
+		v.remotes[names[2]] = v.remotes[names[1]]
+		delete(v.remotes, names[1])
+	case (names[0] == "remove" || names[0] == "rm") && len(names) > 1:
+		delete(v.remotes, names[1])
+	}
+}
+
+// worktree foresees the worktrees of a repo. A worktree shares the
+// history with the repo, but has its own HEAD, index, and files.
+func (s *seer) worktree(v *vision, dir string, args []string) error {
+
+	t := v.timeline
+	flags, names := splitArgs(args[1:], "-b", "-B")
+	if len(names) == 0 {
+		return nil
+	}
+	worktree := filepath.Join(dir, names[0])
+
+	switch args[0] {
+	case "remove":
+		delete(s.visions, worktree)
+		return nil
+	case "add":
+	default:
+		return nil
+	}
+
+	w := &vision{
+		foresight: v.foresight,
+		index:     map[string]bool{},
+		files:     map[string]bool{},
+		modified:  map[string]bool{},
+	}
+	branch := flags["-b"] + flags["-B"]
+	rev := ""
+	if len(names) > 1 {
+		rev = names[1]
+	}
+	switch {
+	case branch == "" && rev == "":
+		// git creates a branch with the name of the directory
+		branch = filepath.Base(worktree)
+		fallthrough
+	case branch != "":
+		err := t.create(branch, rev)
+		if err != nil {
+			return err
+		}
+		w.head = branch
+	case hasFlag(flags, "--detach", "-d") || t.branches[rev] == 0:
+		id, err := t.resolve(rev)
+		if err != nil {
+			return err
+		}
+		w.detached = id
+	default:
+		w.head = rev
+	}
+
+	if w.head != "" {
+		w.checkout(0, t.branches[w.head])
+	} else {
+		w.checkout(0, w.detached)
+	}
+	s.visions[worktree] = w
+	return nil
+}
+
+// isDir reports if the path is a directory with known files.
+func (v *vision) isDir(p string) bool {
+	for file := range v.files {
+		if strings.HasPrefix(file, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// slashPath returns the cleaned slash separated path.
+func slashPath(name string) string {
+	return path.Clean(filepath.ToSlash(name))
+}
+
+// matchAny reports if one of the paths is the path p, is in it, or
+// contains it.
+func matchAny(paths map[string]bool, p string) bool {
+	for file := range paths {
+		if within(file, p) || within(p, file) {
+			return true
+		}
+	}
+	return false
+}
+
+// locate returns the repo or worktree of the file and the path of the
+// file in it.
+func (s *seer) locate(name string) (*vision, string) {
+
+	name = filepath.Clean(name)
+	var found *vision
+	var rel string
+	longest := -1
+	for dir, v := range s.visions {
+		if len(dir) <= longest || !strings.HasPrefix(name, dir+string(filepath.Separator)) {
+			continue
+		}
+		found, rel, longest = v, filepath.ToSlash(name[len(dir)+1:]), len(dir)
+	}
+	if found == nil || found.bare || rel == ".git" || strings.HasPrefix(rel, ".git/") {
+		return nil, ""
+	}
+	return found, rel
+}
+
+// touch adds the file to the worktree of its repo.
+func (s *seer) touch(name string) {
+	v, file := s.locate(name)
+	if v == nil {
+		return
+	}
+	if v.isDir(file) {
+		return
+	}
+	v.files[file] = true
+	v.modified[file] = true
+}
+
+// copy foresees the copied file. The source in the task directory
+// must exist, it is not part of a repo.
+// It implements the assistant interface.
+func (s *seer) copy(from, to string) error {
+	_ = s.novice.copy(from, to)
+
+	_, err := os.Lstat(from)
+	if err != nil {
+		return IOError{Cmd: "stat", Arg: from, Err: err}
+	}
+
+	if v, file := s.locate(to); v != nil && v.isDir(file) {
+		to = filepath.Join(to, filepath.Base(from))
+	}
+	s.touch(to)
+	return nil
+}
+
+// readFile reads the file in the task directory, it is not part
+// of a repo.
+// It implements the assistant interface.
+func (s *seer) readFile(name string) ([]byte, error) {
+	_, _ = s.novice.readFile(name)
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, IOError{Cmd: "read", Arg: name, Err: err}
+	}
+	return data, nil
+}
+
+// writeFile foresees the written file.
+// It implements the assistant interface.
+func (s *seer) writeFile(name string, data []byte, mode fs.FileMode) error {
+	_ = s.novice.writeFile(name, data, mode)
+	s.touch(name)
+	return nil
+}
+
+// chmod foresees the changed file.
+// It implements the assistant interface.
+func (s *seer) chmod(name string, mode fs.FileMode) error {
+	_ = s.novice.chmod(name, mode)
+	s.touch(name)
+	return nil
+}
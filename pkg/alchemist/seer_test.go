@@ -0,0 +1,215 @@
+package alchemist
+
+import (
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestSeer tests the errors that are foreseen in test mode.
+func TestSeer(t *testing.T) {
+
+	const setup = `
+title: seer
+commands:
+  - init_bare_repo:
+      bare: remotes/seer
+      clone_to: seer
+  - create_add_commit:
+      files:
+        - source.txt => source.txt
+        - source.txt => docs/source.txt
+      message: add source
+      author: blue
+`
+
+	missing := filepath.Join(TestDataDir, "missing.txt")
+	_, errMissing := os.Lstat(missing)
+
+	testCases := []struct {
+		name    string
+		spells  string
+		wantErr string
+	}{{
+		name: "valid",
+		spells: `
+  - switch:
+      branch: feature
+      create: true
+  - mv:
+      source: docs
+      target: manual
+  - commit:
+      message: rename docs
+      author: red
+  - switch:
+      branch: main
+  - mv:
+      source: source.txt
+      target: readme.txt
+  - commit:
+      message: add readme
+      author: blue
+  - merge:
+      source: feature
+      target: main
+      delete_source: true
+  - remove_and_commit:
+      files:
+        - manual/source.txt
+      message: remove manual
+      author: red
+  - switch:
+      branch: pages
+      orphan: true
+  - create_add_commit:
+      files:
+        - source.txt => index.html
+      message: publish
+      author: red
+`,
+	}, {
+		name: "merge unknown branch",
+		spells: `
+  - merge:
+      source: feature
+      target: main
+`,
+		wantErr: "merge (3): git merge feature: value for revision: unknown revision feature",
+	}, {
+		name: "move missing file",
+		spells: `
+  - mv:
+      source: plan.txt
+      target: docs/plan.txt
+`,
+		wantErr: "mv (3): git mv plan.txt docs/plan.txt: value for file: " +
+			"bad source, source=plan.txt, destination=docs/plan.txt",
+	}, {
+		name: "move untracked file",
+		spells: `
+  - create_file:
+      source: source.txt
+      target: plan.txt
+  - mv:
+      source: plan.txt
+      target: docs/plan.txt
+`,
+		wantErr: "mv (4): git mv plan.txt docs/plan.txt: value for file: " +
+			"not under version control, source=plan.txt, destination=docs/plan.txt",
+	}, {
+		name: "add missing file",
+		spells: `
+  - add:
+      files:
+        - plan.txt
+`,
+		wantErr: "add (3): git add plan.txt: value for file: pathspec plan.txt did not match any files",
+	}, {
+		name: "remove file of other branch",
+		spells: `
+  - switch:
+      branch: feature
+      create: true
+  - create_add_commit:
+      files:
+        - source.txt => plan.txt
+      message: add plan
+      author: red
+  - switch:
+      branch: main
+  - remove_and_commit:
+      files:
+        - plan.txt
+      message: remove plan
+      author: red
+`,
+		wantErr: "remove_and_commit (6): git rm plan.txt: value for file: pathspec plan.txt did not match any files",
+	}, {
+		name: "nothing to commit",
+		spells: `
+  - commit:
+      message: empty
+      author: red
+`,
+		wantErr: "commit (3): git commit --date=format:relative:5.hours.ago -m empty " +
+			"--author=Richard Red <richard@pw-compa.ny>: value for commit: nothing to commit on main",
+	}, {
+		name: "delete unmerged branch",
+		spells: `
+  - switch:
+      branch: feature
+      create: true
+  - create_add_commit:
+      files:
+        - source.txt => plan.txt
+      message: add plan
+      author: red
+  - switch:
+      branch: main
+  - git:
+      command: branch -d feature
+`,
+		wantErr: "git (6): git branch -d feature: value for branch: branch feature is not fully merged",
+	}, {
+		name: "missing source",
+		spells: `
+  - create_file:
+      source: missing.txt
+      target: missing.txt
+`,
+		wantErr: "create_file (3): " + IOError{Cmd: "stat", Arg: missing, Err: errMissing}.Error(),
+	}, {
+		name: "missing source with line endings",
+		spells: `
+  - create_file:
+      source: missing.txt
+      target: missing.txt
+      eol: crlf
+`,
+		wantErr: "create_file (3): " + IOError{Cmd: "read", Arg: missing,
+			Err: &fs.PathError{Op: "open", Path: missing, Err: errMissing.(*fs.PathError).Err}}.Error(),
+	}, {
+		name: "git version condition",
+		spells: `
+  - switch:
+      branch: feature
+      create: true
+      when: git_version < 2.0
+  - switch:
+      branch: feature
+      create: true
+`,
+	}, {
+		name: "not foreseeable",
+		spells: `
+  - git:
+      command: stash
+  - add:
+      files:
+        - plan.txt
+`,
+	}}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			formula, err := readFormula(strings.NewReader(setup + c.spells))
+			if err != nil {
+				t.Fatalf("ERROR: got error: %v", err)
+			}
+
+			opt := Options{RepoDir: "cwd", CfgDir: ".", TaskDir: TestDataDir, Test: true}
+			err = Transmute(formula, opt, log.New(io.Discard, "", 0))
+			switch {
+			case c.wantErr == "" && err != nil:
+				t.Errorf("ERROR: got error: %v", err)
+			case c.wantErr != "" && (err == nil || err.Error() != c.wantErr):
+				t.Errorf("ERROR: got %v, want %q", err, c.wantErr)
+			}
+		})
+	}
+}
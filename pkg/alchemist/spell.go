@@ -1,5 +1,10 @@
 package alchemist
 
+import (
+	"errors"
+	"fmt"
+)
+
 // spellHint provides the information for casting some spells.
 // It can be used to create lists of arguments and process them
 // in a loop.
@@ -7,3 +12,80 @@ type spellHint struct {
 	dir  string
 	args []string
 }
+
+// spellModifiers contains the settings that can be used with every spell.
+type spellModifiers struct {
+	When      string `yaml:"when"`      // condition, the spell is skipped if it is false
+	Workspace string `yaml:"workspace"` // clone directory the spell is cast in
+}
+
+// isSet reports if at least one modifier is set.
+func (m spellModifiers) isSet() bool {
+	return m.When != "" || m.Workspace != ""
+}
+
+// foreseer is implemented by assistants that foresee the results of
+// the spells instead of executing them, e.g. the seer.
+type foreseer interface {
+	// unforeseeable stops foreseeing the repos, e.g. because it is not
+	// known whether a spell is cast.
+	unforeseeable(reason string)
+}
+
+// modifiedSpell is a spell with modifiers.
+// It implements the caster interface.
+type modifiedSpell struct {
+	caster
+	spellModifiers
+	when condition // parsed When expression
+}
+
+// newModifiedSpell wraps the spell with the modifiers.
+// It returns an error if a modifier is not valid.
+func newModifiedSpell(spell caster, mod spellModifiers) (modifiedSpell, error) {
+	result := modifiedSpell{caster: spell, spellModifiers: mod}
+	if mod.When != "" {
+		when, err := parseCondition(mod.When)
+		if err != nil {
+			return result, err
+		}
+		result.when = when
+	}
+	return result, nil
+}
+
+// cast casts the wrapped spell if the condition is true.
+// If it is false, the spell is skipped. If it can not be evaluated,
+// e.g. without the git version, the spell is cast, so it is not
+// silently left out. A foreseer can not know the result of the
+// spell, it stops foreseeing.
+// If a workspace is set, the spell is cast in this clone.
+func (s modifiedSpell) cast(a assistant, opt Options) error {
+
+	if s.Workspace != "" {
+		opt.cloneTo = s.Workspace
+	}
+
+	if s.when != nil {
+		ok, err := isTrue(s.when, opt.lookup(a))
+		var unknown UnknownValueError
+		switch {
+		case errors.As(err, &unknown):
+			a.info("%d/%d: condition can not be evaluated, %v: %s",
+				opt.currentSpell, opt.numberOfSpells, err, s.When)
+			if f, ok := a.(foreseer); ok {
+				f.unforeseeable("the condition " + s.When + " can not be evaluated")
+				return nil
+			}
+			return s.caster.cast(a, opt)
+		case err != nil:
+			return fmt.Errorf("when %q: %w", s.When, err)
+		case !ok:
+			a.info("%d/%d: skip, condition is false: %s",
+				opt.currentSpell, opt.numberOfSpells, s.When)
+			return nil
+		}
+	}
+
+	return s.caster.cast(a, opt)
+}
@@ -1,7 +1,13 @@
 package alchemist
 
 import (
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/HMS-Analytical-Software/goGitAlchemist/pkg/check"
@@ -20,6 +26,22 @@ func TestSpellCast(t *testing.T) {
 
 	repoBareDir := filepath.Join(repoDir, bareDir)
 	repoCloneDir := filepath.Join(repoDir, cloneDir)
+	bareURL, err := fileURL(repoBareDir)
+	if err != nil {
+		t.Fatalf("ERROR: test setup failed: %v", err)
+	}
+	redKey, err := filepath.Abs(signingKey(repoDir, "red"))
+	if err != nil {
+		t.Fatalf("ERROR: test setup failed: %v", err)
+	}
+	signersFile, err := filepath.Abs(filepath.Join(repoDir, keysDir, allowedSignersFile))
+	if err != nil {
+		t.Fatalf("ERROR: test setup failed: %v", err)
+	}
+	messageFile, err := filepath.Abs(filepath.Join("messages", "plan.txt"))
+	if err != nil {
+		t.Fatalf("ERROR: test setup failed: %v", err)
+	}
 
 	testCases := []struct {
 		name    string        // test case name
@@ -62,6 +84,20 @@ func TestSpellCast(t *testing.T) {
 			[]string{repoBareDir, gitCmd, "init", "--bare", "--initial-branch=main", "."},
 		},
 		wantErr: gitCmd + " clone " + bareDir + " " + cloneDir + ": spy error: 3",
+	}, {
+		name: "initRepoSpell fork",
+		spell: initRepoSpell{Bare: bareDir, CloneTo: cloneDir, ForkOf: "upstream",
+			Remote: "fork"},
+		spy: &assistantSpy{},
+		want: [][]string{
+			[]string{"makedir", repoBareDir},
+			[]string{repoDir, gitCmd, "clone", "--bare", "upstream", bareDir},
+			[]string{repoDir, gitCmd, "clone", "--origin", "fork", bareDir, cloneDir},
+			[]string{repoCloneDir, gitCmd, "remote", "set-url", "fork", filepath.Join("..", bareDir)},
+			[]string{repoCloneDir, gitCmd, "config", "user.name", author[defaultUser]},
+			[]string{repoCloneDir, gitCmd, "config", "user.email", email[defaultUser]},
+			[]string{repoCloneDir, gitCmd, "config", "init.defaultBranch", defaultBranch},
+		},
 	}, {
 		name:  "createFileSpell ok",
 		spell: createFileSpell{Source: fromFile, Target: toFile},
@@ -75,6 +111,27 @@ func TestSpellCast(t *testing.T) {
 		spy:   &assistantSpy{errorAt: 1},
 		wantErr: "copy " + fromFile + " " + filepath.Join(repoDir, toFile) +
 			": spy error: 1",
+	}, {
+		name:  "createFileSpell crlf",
+		spell: createFileSpell{Source: fromFile, Target: toFile, EOL: "crlf"},
+		spy:   &assistantSpy{content: "one\ntwo\r\nthree"},
+		want: [][]string{
+			[]string{"read", fromFile},
+			[]string{"write", filepath.Join(repoDir, toFile), "-rw-r--r--", "one\r\ntwo\r\nthree"},
+		},
+	}, {
+		name:  "createFileSpell lf",
+		spell: createFileSpell{Source: fromFile, Target: toFile, EOL: "lf"},
+		spy:   &assistantSpy{content: "one\r\ntwo\n"},
+		want: [][]string{
+			[]string{"read", fromFile},
+			[]string{"write", filepath.Join(repoDir, toFile), "-rw-r--r--", "one\ntwo\n"},
+		},
+	}, {
+		name:    "createFileSpell read error",
+		spell:   createFileSpell{Source: fromFile, Target: toFile, EOL: "lf"},
+		spy:     &assistantSpy{errorAt: 1},
+		wantErr: "read " + fromFile + ": spy error: 1",
 	}, {
 		name:  "addSpell ok",
 		spell: addSpell{Files: []string{fromFile, toFile}},
@@ -117,6 +174,51 @@ func TestSpellCast(t *testing.T) {
 		spy:   &assistantSpy{errorAt: 1},
 		wantErr: gitCmd + " commit --date=" + gitCommitDateFormat +
 			" -m hello --author=skywalker: spy error: 1",
+	}, {
+		name: "commitSpell body and trailers",
+		spell: commitSpell{Author: "red", Message: "feat: add plan", Body: "first line\nsecond line",
+			Trailers: []string{"Co-authored-by: blue", "Signed-off-by:red", "Fixes: #12"}},
+		spy: &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "commit", "--date=" + gitCommitDateFormat,
+				"-m", "feat: add plan", "-m", "first line\nsecond line", "--author=" + getAuthor("red"),
+				"--trailer", "Co-authored-by: " + getAuthor("blue"),
+				"--trailer", "Signed-off-by: " + getAuthor("red"),
+				"--trailer", "Fixes: #12"},
+		},
+	}, {
+		name:  "commitSpell message file",
+		spell: commitSpell{Author: "red", MessageFile: "messages/plan.txt"},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "commit", "--date=" + gitCommitDateFormat,
+				"-F", messageFile, "--author=" + getAuthor("red")},
+		},
+	}, {
+		name:  "commitSpell amend no edit",
+		spell: commitSpell{Amend: true, Files: []string{"notes.md"}},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "add", "notes.md"},
+			[]string{repoDir, gitCmd, "commit", "--amend", "--no-edit"},
+		},
+	}, {
+		name:  "commitSpell amend message and author",
+		spell: commitSpell{Amend: true, Message: "better message", Author: "blue"},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "commit", "--amend", "-m", "better message",
+				"--author=" + getAuthor("blue")},
+		},
+	}, {
+		name:  "commitSpell sign",
+		spell: commitSpell{Author: "red", Message: "hello", Sign: true},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "-c", "gpg.format=ssh", "-c", "user.signingKey=" + redKey,
+				"commit", "--date=" + gitCommitDateFormat,
+				"-m", "hello", "--author=" + getAuthor("red"), "--gpg-sign"},
+		},
 	}, {
 		name: "createAddCommitSpell ok",
 		spell: createAddCommitSpell{
@@ -185,6 +287,32 @@ func TestSpellCast(t *testing.T) {
 			[]string{repoDir, gitCmd, "checkout", "main"},
 			[]string{repoDir, gitCmd, "merge", "develop"},
 		},
+	}, {
+		name: "mergeSpell author",
+		spell: mergeSpell{
+			Source: "develop",
+			Target: "main",
+			Author: "green",
+		},
+		spy: &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "checkout", "main"},
+			[]string{repoDir, gitCmd, "-c", "user.name=" + author["green"],
+				"-c", "user.email=" + email["green"], "merge", "develop"},
+		},
+	}, {
+		name: "mergeSpell no fast forward with message",
+		spell: mergeSpell{
+			Source:  "develop~1",
+			Target:  "main",
+			NoFF:    true,
+			Message: "merge develop",
+		},
+		spy: &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "checkout", "main"},
+			[]string{repoDir, gitCmd, "merge", "--no-ff", "-m", "merge develop", "develop~1"},
+		},
 	}, {
 		name: "mergeSpell checkout error",
 		spell: mergeSpell{
@@ -287,6 +415,510 @@ func TestSpellCast(t *testing.T) {
 		},
 		wantErr: gitCmd + " commit --date=" + gitCommitDateFormat +
 			" -m hello --author=" + getAuthor("red") + ": spy error: 3",
+	}, {
+		name: "bisectHistorySpell ok",
+		spell: bisectHistorySpell{
+			File:         "app.txt",
+			Commits:      3,
+			RegressionAt: 2,
+			Author:       "red",
+		},
+		spy: &assistantSpy{},
+		want: [][]string{
+			[]string{"write", filepath.Join(repoDir, "app.txt"), "-rw-r--r--",
+				"value_1 = 1\n"},
+			[]string{"write", filepath.Join(repoDir, defaultBisectTestScript), "-rwxr-xr-x",
+				string(bisectHistorySpell{File: "app.txt"}.testScript())},
+			[]string{repoDir, gitCmd, "add", "app.txt"},
+			[]string{repoDir, gitCmd, "add", defaultBisectTestScript},
+			[]string{repoDir, gitCmd, "commit", "--date=" + gitCommitDateFormat,
+				"-m", "update app.txt (1)", "--author=" + getAuthor("red")},
+			[]string{"write", filepath.Join(repoDir, "app.txt"), "-rw-r--r--",
+				"value_1 = 1\nvalue_2 = 2\n" + bisectRegression + "\n"},
+			[]string{repoDir, gitCmd, "add", "app.txt"},
+			[]string{repoDir, gitCmd, "commit", "--date=" + gitCommitDateFormat,
+				"-m", "update app.txt (2)", "--author=" + getAuthor("red")},
+			[]string{"write", filepath.Join(repoDir, "app.txt"), "-rw-r--r--",
+				"value_1 = 1\nvalue_2 = 2\n" + bisectRegression + "\nvalue_3 = 3\n"},
+			[]string{repoDir, gitCmd, "add", "app.txt"},
+			[]string{repoDir, gitCmd, "commit", "--date=" + gitCommitDateFormat,
+				"-m", "update app.txt (3)", "--author=" + getAuthor("red")},
+		},
+	}, {
+		name: "bisectHistorySpell write error",
+		spell: bisectHistorySpell{
+			File:         "app.txt",
+			Commits:      3,
+			RegressionAt: 2,
+			Author:       "red",
+		},
+		spy: &assistantSpy{errorAt: 2},
+		want: [][]string{
+			[]string{"write", filepath.Join(repoDir, "app.txt"), "-rw-r--r--",
+				"value_1 = 1\n"},
+		},
+		wantErr: "write " + filepath.Join(repoDir, defaultBisectTestScript) +
+			": spy error: 2",
+	}, {
+		name: "bisectHistorySpell unknown seed variable",
+		spell: bisectHistorySpell{
+			File:     "app.txt",
+			Commits:  3,
+			SeedFrom: "participant",
+			Author:   "red",
+		},
+		spy:     &assistantSpy{},
+		wantErr: "value for participant: unknown variable",
+	}, {
+		name: "rewriteHistorySpell ok",
+		spell: rewriteHistorySpell{
+			Branches:   []string{"main", "develop"},
+			RemovePath: "secret's.env",
+			Replace:    replacement{From: "hunter2"},
+			Push:       true,
+		},
+		spy: &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "filter-branch", "--force", "--prune-empty", "--tag-name-filter", "cat",
+				"--index-filter",
+				`git rm -r -q --cached --ignore-unmatch -- 'secret'\''s.env'`,
+				"--tree-filter",
+				`GITALCHEMIST_FROM='hunter2' GITALCHEMIST_TO='***REMOVED***'` +
+					` find . -type f -exec perl -pi -e` +
+					` 's/\Q$ENV{GITALCHEMIST_FROM}\E/$ENV{GITALCHEMIST_TO}/g' {} +`,
+				"--", "main", "develop"},
+			[]string{repoDir, gitCmd, "update-ref", "-d", "refs/original/refs/heads/main"},
+			[]string{repoDir, gitCmd, "update-ref", "-d", "refs/original/refs/heads/develop"},
+			[]string{repoDir, gitCmd, "reflog", "expire", "--expire=now", "--all"},
+			[]string{repoDir, gitCmd, "gc", "--quiet", "--prune=now"},
+			[]string{repoDir, gitCmd, "push", "--force", "--tags", "origin", "main", "develop"},
+		},
+	}, {
+		name: "rewriteHistorySpell no push",
+		spell: rewriteHistorySpell{
+			Branches:   []string{"main"},
+			RemovePath: "secret.env",
+		},
+		spy: &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "filter-branch", "--force", "--prune-empty", "--tag-name-filter", "cat",
+				"--index-filter",
+				`git rm -r -q --cached --ignore-unmatch -- 'secret.env'`,
+				"--", "main"},
+			[]string{repoDir, gitCmd, "update-ref", "-d", "refs/original/refs/heads/main"},
+			[]string{repoDir, gitCmd, "reflog", "expire", "--expire=now", "--all"},
+			[]string{repoDir, gitCmd, "gc", "--quiet", "--prune=now"},
+		},
+	}, {
+		name: "rewriteHistorySpell error",
+		spell: rewriteHistorySpell{
+			Branches:   []string{"main"},
+			RemovePath: "secret.env",
+		},
+		spy: &assistantSpy{errorAt: 1},
+		wantErr: gitCmd + " filter-branch --force --prune-empty --tag-name-filter cat --index-filter " +
+			"git rm -r -q --cached --ignore-unmatch -- 'secret.env' -- main: spy error: 1",
+	}, {
+		name:  "cloneSpell ok",
+		spell: cloneSpell{Bare: bareDir, CloneTo: cloneDir, User: "blue"},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "clone", bareDir, cloneDir},
+			[]string{repoCloneDir, gitCmd, "remote", "set-url", "origin", filepath.Join("..", bareDir)},
+			[]string{repoCloneDir, gitCmd, "config", "user.name", author["blue"]},
+			[]string{repoCloneDir, gitCmd, "config", "user.email", email["blue"]},
+			[]string{repoCloneDir, gitCmd, "config", "init.defaultBranch", defaultBranch},
+		},
+	}, {
+		name:  "cloneSpell default user",
+		spell: cloneSpell{Bare: bareDir, CloneTo: cloneDir},
+		spy:   &assistantSpy{errorAt: 4},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "clone", bareDir, cloneDir},
+			[]string{repoCloneDir, gitCmd, "remote", "set-url", "origin", filepath.Join("..", bareDir)},
+			[]string{repoCloneDir, gitCmd, "config", "user.name", author[defaultUser]},
+		},
+		wantErr: gitCmd + " config user.email " + email[defaultUser] + ": spy error: 4",
+	}, {
+		name: "cloneSpell partial",
+		spell: cloneSpell{Bare: bareDir, CloneTo: cloneDir, Depth: 1, Filter: "blob:none",
+			SingleBranch: true},
+		spy: &assistantSpy{},
+		want: [][]string{
+			[]string{repoBareDir, gitCmd, "config", "uploadpack.allowFilter", "true"},
+			[]string{repoDir, gitCmd, "clone", "--depth", "1", "--filter", "blob:none",
+				"--single-branch", bareURL, cloneDir},
+			[]string{repoCloneDir, gitCmd, "remote", "set-url", "origin", filepath.Join("..", bareDir)},
+			[]string{repoCloneDir, gitCmd, "config", "user.name", author[defaultUser]},
+			[]string{repoCloneDir, gitCmd, "config", "user.email", email[defaultUser]},
+			[]string{repoCloneDir, gitCmd, "config", "init.defaultBranch", defaultBranch},
+		},
+	}, {
+		name:  "cloneSpell bundle",
+		spell: cloneSpell{Bundle: "main.bundle", CloneTo: cloneDir},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "clone", "main.bundle", cloneDir},
+			[]string{repoCloneDir, gitCmd, "config", "user.name", author[defaultUser]},
+			[]string{repoCloneDir, gitCmd, "config", "user.email", email[defaultUser]},
+			[]string{repoCloneDir, gitCmd, "config", "init.defaultBranch", defaultBranch},
+		},
+	}, {
+		name:  "fetchSpell ok",
+		spell: fetchSpell{},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "fetch", "origin"},
+		},
+	}, {
+		name:  "fetchSpell prune tags",
+		spell: fetchSpell{Remote: "upstream", Prune: true, Tags: true},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "fetch", "--prune", "--tags", "upstream"},
+		},
+	}, {
+		name:    "fetchSpell error",
+		spell:   fetchSpell{},
+		spy:     &assistantSpy{errorAt: 1},
+		wantErr: gitCmd + " fetch origin: spy error: 1",
+	}, {
+		name:  "pullSpell merge",
+		spell: pullSpell{Author: "blue"},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "-c", "user.name=" + author["blue"],
+				"-c", "user.email=" + email["blue"], "pull", "--no-rebase", "origin"},
+		},
+	}, {
+		name:  "pullSpell rebase",
+		spell: pullSpell{Remote: "upstream", Branch: "main", Rebase: true},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "pull", "--rebase", "upstream", "main"},
+		},
+	}, {
+		name:  "pullSpell ff only unknown author",
+		spell: pullSpell{FFOnly: true, Author: "skywalker"},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "-c", "user.name=skywalker",
+				"pull", "--ff-only", "origin"},
+		},
+	}, {
+		name:    "pullSpell error",
+		spell:   pullSpell{},
+		spy:     &assistantSpy{errorAt: 1},
+		wantErr: gitCmd + " pull --no-rebase origin: spy error: 1",
+	}, {
+		name:  "remoteSpell add bare",
+		spell: remoteSpell{Name: "upstream", Bare: bareDir, Fetch: true},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "remote", "add", "--fetch", "upstream",
+				filepath.Join("..", bareDir)},
+		},
+	}, {
+		name:  "remoteSpell set-url",
+		spell: remoteSpell{Action: "set-url", Name: "upstream", URL: "https://example.com/x.git"},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "remote", "set-url", "upstream", "https://example.com/x.git"},
+		},
+	}, {
+		name:  "remoteSpell rename",
+		spell: remoteSpell{Action: "rename", Name: "origin", NewName: "fork"},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "remote", "rename", "origin", "fork"},
+		},
+	}, {
+		name:    "remoteSpell remove error",
+		spell:   remoteSpell{Action: "remove", Name: "upstream"},
+		spy:     &assistantSpy{errorAt: 1},
+		wantErr: gitCmd + " remote remove upstream: spy error: 1",
+	}, {
+		name:  "serverHookSpell ok",
+		spell: serverHookSpell{Bare: bareDir, Hook: "update", Source: fromFile},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{"copy", fromFile, filepath.Join(repoBareDir, "hooks", "update")},
+			[]string{"chmod", filepath.Join(repoBareDir, "hooks", "update"), "-rwxr-xr-x"},
+		},
+	}, {
+		name:  "serverHookSpell chmod error",
+		spell: serverHookSpell{Bare: bareDir, Hook: "update", Source: fromFile},
+		spy:   &assistantSpy{errorAt: 2},
+		want: [][]string{
+			[]string{"copy", fromFile, filepath.Join(repoBareDir, "hooks", "update")},
+		},
+		wantErr: "chmod " + filepath.Join(repoBareDir, "hooks", "update") + ": spy error: 2",
+	}, {
+		name:  "hookSpell ok",
+		spell: hookSpell{Hook: "commit-msg", Source: fromFile},
+		spy:   &assistantSpy{output: ".git/hooks\n"},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "rev-parse", "--git-path", "hooks"},
+			[]string{"copy", fromFile, filepath.Join(repoDir, ".git", "hooks", "commit-msg")},
+			[]string{"chmod", filepath.Join(repoDir, ".git", "hooks", "commit-msg"), "-rwxr-xr-x"},
+		},
+	}, {
+		name:  "hookSpell submodule",
+		spell: hookSpell{Hook: "commit-msg", Source: fromFile},
+		spy:   &assistantSpy{output: "../.git/modules/repodir/hooks\n"},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "rev-parse", "--git-path", "hooks"},
+			[]string{"copy", fromFile, filepath.Join(".git", "modules", "repodir", "hooks", "commit-msg")},
+			[]string{"chmod", filepath.Join(".git", "modules", "repodir", "hooks", "commit-msg"), "-rwxr-xr-x"},
+		},
+	}, {
+		name:    "hookSpell git error",
+		spell:   hookSpell{Hook: "commit-msg", Source: fromFile},
+		spy:     &assistantSpy{errorAt: 1},
+		wantErr: gitCmd + " rev-parse --git-path hooks: spy error: 1",
+	}, {
+		name:  "hookSpell without output",
+		spell: hookSpell{Hook: "commit-msg", Source: fromFile},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "rev-parse", "--git-path", "hooks"},
+			[]string{"copy", fromFile, filepath.Join(repoDir, ".git", "hooks", "commit-msg")},
+			[]string{"chmod", filepath.Join(repoDir, ".git", "hooks", "commit-msg"), "-rwxr-xr-x"},
+		},
+	}, {
+		name:  "hookSpell copy error",
+		spell: hookSpell{Hook: "commit-msg", Source: fromFile},
+		spy:   &assistantSpy{output: ".git/hooks\n", errorAt: 2},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "rev-parse", "--git-path", "hooks"},
+		},
+		wantErr: "copy " + fromFile + " " +
+			filepath.Join(repoDir, ".git", "hooks", "commit-msg") + ": spy error: 2",
+	}, {
+		name:  "configSpell set",
+		spell: configSpell{Key: "pull.rebase", Value: "true"},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "config", "--local", "pull.rebase", "true"},
+		},
+	}, {
+		name:  "configSpell unset",
+		spell: configSpell{Key: "pull.rebase", Unset: true},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "config", "--local", "--unset", "pull.rebase"},
+		},
+	}, {
+		name: "submoduleAddSpell ok",
+		spell: submoduleAddSpell{Bare: bareDir, Path: "lib", Branch: "main",
+			Message: "add lib", Author: "red"},
+		spy: &assistantSpy{output: filepath.Join("..", bareDir) + "\n"},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "remote", "get-url", "origin"},
+			[]string{repoDir, gitCmd, "-c", "protocol.file.allow=always", "submodule", "add",
+				"-b", "main", "../" + repoDir + "/" + bareDir, "lib"},
+			[]string{repoDir, gitCmd, "commit", "--date=" + gitCommitDateFormat,
+				"-m", "add lib", "--author=" + getAuthor("red")},
+		},
+	}, {
+		name:    "submoduleAddSpell origin error",
+		spell:   submoduleAddSpell{Bare: bareDir, Path: "lib", Message: "add lib", Author: "red"},
+		spy:     &assistantSpy{errorAt: 1},
+		wantErr: gitCmd + " remote get-url origin: spy error: 1",
+	}, {
+		name: "subtreeAddSpell ok",
+		spell: subtreeAddSpell{Bare: bareDir, Prefix: "vendor/lib", Squash: true,
+			Message: "add lib", Author: "blue"},
+		spy: &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "-c", "user.name=" + author["blue"],
+				"-c", "user.email=" + email["blue"], "subtree", "add", "--prefix=vendor/lib",
+				"--squash", "-m", "add lib", filepath.Join("..", bareDir), defaultBranch},
+		},
+	}, {
+		name:  "subtreePullSpell ok",
+		spell: subtreePullSpell{Bare: bareDir, Prefix: "lib", Branch: "develop"},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "subtree", "pull", "--prefix=lib",
+				filepath.Join("..", bareDir), "develop"},
+		},
+	}, {
+		name:  "worktreeSpell add new branch",
+		spell: worktreeSpell{Path: "hotfix", Branch: "hotfix/1", Create: true, Base: "main"},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "worktree", "add", "-b", "hotfix/1", "hotfix", "main"},
+		},
+	}, {
+		name:  "worktreeSpell add existing branch",
+		spell: worktreeSpell{Path: "review", Branch: "feature/x"},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "worktree", "add", "review", "feature/x"},
+		},
+	}, {
+		name:  "worktreeSpell remove",
+		spell: worktreeSpell{Action: "remove", Path: "review", Force: true},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "worktree", "remove", "--force", "review"},
+		},
+	}, {
+		name:  "sparseCheckoutSpell set",
+		spell: sparseCheckoutSpell{Patterns: []string{"docs", "src/app"}},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "sparse-checkout", "set", "--cone", "docs", "src/app"},
+		},
+	}, {
+		name:  "sparseCheckoutSpell disable",
+		spell: sparseCheckoutSpell{Action: "disable"},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "sparse-checkout", "disable"},
+		},
+	}, {
+		name:  "bundleSpell all refs",
+		spell: bundleSpell{File: filepath.Join("bundles", "main.bundle")},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{"makedir", filepath.Join(repoDir, "bundles")},
+			[]string{repoDir, gitCmd, "bundle", "create", "bundles/main.bundle", "--all"},
+		},
+	}, {
+		name:  "bundleSpell refs",
+		spell: bundleSpell{File: "main.bundle", Refs: []string{"main", "v1.0"}},
+		spy:   &assistantSpy{errorAt: 2},
+		want: [][]string{
+			[]string{"makedir", repoDir},
+		},
+		wantErr: gitCmd + " bundle create main.bundle main v1.0 HEAD: spy error: 2",
+	}, {
+		name:  "signingKeysSpell ok",
+		spell: signingKeysSpell{Authors: []string{"red", "blue"}, Trusted: []string{"red"}},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{"keygen", signingKey(repoDir, "red"), email["red"]},
+			[]string{"keygen", signingKey(repoDir, "blue"), email["blue"]},
+			[]string{"write", signersFile, "-rw-r--r--",
+				email["red"] + ` namespaces="git" ssh-ed25519 SPY ` + email["red"] + "\n"},
+			[]string{repoDir, gitCmd, "config", "gpg.format", "ssh"},
+			[]string{repoDir, gitCmd, "config", "gpg.ssh.allowedSignersFile", signersFile},
+		},
+	}, {
+		name:    "signingKeysSpell keygen error",
+		spell:   signingKeysSpell{Authors: []string{"red"}},
+		spy:     &assistantSpy{errorAt: 1},
+		wantErr: "keygen " + signingKey(repoDir, "red") + ": spy error: 1",
+	}, {
+		name:  "tagSpell lightweight",
+		spell: tagSpell{Name: "v1.0", Ref: "main~1"},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "tag", "v1.0", "main~1"},
+		},
+	}, {
+		name:  "tagSpell annotated",
+		spell: tagSpell{Name: "v1.0", Message: "release", Author: "blue"},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "-c", "user.name=" + author["blue"],
+				"-c", "user.email=" + email["blue"], "tag", "-a", "-m", "release", "v1.0"},
+		},
+	}, {
+		name:  "tagSpell signed",
+		spell: tagSpell{Name: "v1.0", Message: "release", Author: "red", Sign: true},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "-c", "user.name=" + author["red"],
+				"-c", "user.email=" + email["red"], "-c", "gpg.format=ssh",
+				"-c", "user.signingKey=" + redKey, "tag", "-s", "-m", "release", "v1.0"},
+		},
+	}, {
+		name:  "chmodSpell executable staged",
+		spell: chmodSpell{Files: []string{"run.sh", filepath.Join("bin", "x")}, Mode: "+x", Stage: true},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{"chmod", filepath.Join(repoDir, "run.sh"), "-rwxr-xr-x"},
+			[]string{"chmod", filepath.Join(repoDir, "bin", "x"), "-rwxr-xr-x"},
+			[]string{repoDir, gitCmd, "update-index", "--chmod=+x", "--", "run.sh", "bin/x"},
+		},
+	}, {
+		name:  "chmodSpell not executable",
+		spell: chmodSpell{Files: []string{"run.sh"}, Mode: "-x"},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{"chmod", filepath.Join(repoDir, "run.sh"), "-rw-r--r--"},
+		},
+	}, {
+		name:  "generateFileSpell text",
+		spell: generateFileSpell{File: "data.txt", Kind: "text", Lines: 2, Seed: 7},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{"write", filepath.Join(repoDir, "data.txt"), "-rw-r--r--",
+				"line 1: 0e42d4cae8864908e0b8c7520e360aa404427c9053d9a667\n" +
+					"line 2: be544c14e69fa48233703b7158a07611ce9337376b0e4da8\n"},
+		},
+	}, {
+		name:  "switchSpell existing branch",
+		spell: switchSpell{Branch: "main"},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "switch", "main"},
+		},
+	}, {
+		name:  "switchSpell create",
+		spell: switchSpell{Branch: "feature/x", Create: true, Base: "v1.0"},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "switch", "--create", "feature/x", "v1.0"},
+		},
+	}, {
+		name:  "switchSpell orphan",
+		spell: switchSpell{Branch: "gh-pages", Orphan: true},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "clean", "-d", "--force", "--quiet"},
+			[]string{repoDir, gitCmd, "switch", "--orphan", "gh-pages"},
+		},
+	}, {
+		name:    "switchSpell detach error",
+		spell:   switchSpell{Detach: ":/add plan"},
+		spy:     &assistantSpy{errorAt: 1},
+		wantErr: gitCmd + " switch --detach :/add plan: spy error: 1",
+	}, {
+		name:  "notesSpell add",
+		spell: notesSpell{Message: "reviewed", Author: "blue"},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "-c", "user.name=" + author["blue"],
+				"-c", "user.email=" + email["blue"], "notes", "add", "-m", "reviewed"},
+		},
+	}, {
+		name:  "notesSpell append with ref",
+		spell: notesSpell{Action: "append", Commit: "HEAD~1", Message: "ok", NotesRef: "review"},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "notes", "--ref", "review", "append", "-m", "ok", "HEAD~1"},
+		},
+	}, {
+		name:    "notesSpell remove error",
+		spell:   notesSpell{Action: "remove"},
+		spy:     &assistantSpy{errorAt: 1},
+		wantErr: gitCmd + " notes remove: spy error: 1",
+	}, {
+		name:  "renormalizeSpell all files",
+		spell: renormalizeSpell{},
+		spy:   &assistantSpy{},
+		want: [][]string{
+			[]string{repoDir, gitCmd, "add", "--renormalize", "--", "."},
+		},
+	}, {
+		name:    "renormalizeSpell error",
+		spell:   renormalizeSpell{Files: []string{"a.txt", "b.txt"}},
+		spy:     &assistantSpy{errorAt: 1},
+		wantErr: gitCmd + " add --renormalize -- a.txt b.txt: spy error: 1",
 	}}
 
 	for _, c := range testCases {
@@ -305,3 +937,287 @@ func TestSpellCast(t *testing.T) {
 		})
 	}
 }
+
+// TestModifiedSpellCast tests casting spells with modifiers.
+func TestModifiedSpellCast(t *testing.T) {
+
+	repoDir := "repodir"
+	commit := commitSpell{Author: "red", Message: "hello"}
+	wantCommit := [][]string{
+		[]string{repoDir, gitCmd, "commit", "--date=" + gitCommitDateFormat,
+			"-m", "hello", "--author=" + getAuthor("red")},
+	}
+
+	testCases := []struct {
+		name    string         // test case name
+		mod     spellModifiers // modifiers of the commit spell
+		spy     *assistantSpy  // test double assistant, records the calls
+		want    [][]string     // wanted call recordings by the spy
+		wantErr string         // wanted error message ("" if no error is expected)
+	}{{
+		name: "no modifiers",
+		spy:  &assistantSpy{},
+		want: wantCommit,
+	}, {
+		name: "when true",
+		mod:  spellModifiers{When: `level == "advanced"`},
+		spy:  &assistantSpy{},
+		want: wantCommit,
+	}, {
+		name: "when false",
+		mod:  spellModifiers{When: `level == "beginner"`},
+		spy:  &assistantSpy{},
+	}, {
+		name: "when git version",
+		mod:  spellModifiers{When: `git_version >= 2.38`},
+		spy:  &assistantSpy{output: "git version 2.39.5\n"},
+		want: append([][]string{[]string{"", gitCmd, "version"}}, wantCommit...),
+	}, {
+		name: "when git version unknown",
+		mod:  spellModifiers{When: `git_version >= 2.38`},
+		spy:  &assistantSpy{},
+		want: append([][]string{[]string{"", gitCmd, "version"}}, wantCommit...),
+	}, {
+		name:    "when git version error",
+		mod:     spellModifiers{When: `git_version >= 2.38`},
+		spy:     &assistantSpy{errorAt: 1},
+		wantErr: `when "git_version >= 2.38": ` + gitCmd + " version: spy error: 1",
+	}, {
+		name: "workspace",
+		mod:  spellModifiers{Workspace: "betty"},
+		spy:  &assistantSpy{},
+		want: [][]string{
+			[]string{filepath.Join(repoDir, "betty"), gitCmd, "commit",
+				"--date=" + gitCommitDateFormat, "-m", "hello",
+				"--author=" + getAuthor("red")},
+		},
+	}, {
+		name: "workspace when false",
+		mod:  spellModifiers{Workspace: "betty", When: "!level"},
+		spy:  &assistantSpy{},
+	}, {
+		name:    "when unknown variable",
+		mod:     spellModifiers{When: `unknown`},
+		spy:     &assistantSpy{},
+		wantErr: `when "unknown": value for unknown: unknown variable`,
+	}}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+
+			spell, err := newModifiedSpell(commit, c.mod)
+			if err != nil {
+				t.Fatalf("ERROR: test setup failed: %v", err)
+			}
+
+			err = spell.cast(c.spy, Options{
+				RepoDir:   repoDir,
+				Variables: map[string]string{"level": "advanced"},
+			})
+
+			got := c.spy.calls
+			if diff := cmp.Diff(got, c.want); diff != "" {
+				t.Errorf("ERROR: got-, want+\n%v\n", diff)
+			}
+
+			check.ErrorString(t, err, c.wantErr)
+		})
+	}
+}
+
+// TestSubmoduleURL tests the submodule url relative to the origin.
+func TestSubmoduleURL(t *testing.T) {
+
+	testCases := []struct {
+		name   string
+		dir    string
+		origin string
+		bare   string
+		want   string
+	}{{
+		name:   "same remotes dir",
+		dir:    filepath.Join("cwd", "super"),
+		origin: filepath.Join("..", "remotes", "super"),
+		bare:   filepath.Join("cwd", "remotes", "lib"),
+		want:   "../lib",
+	}, {
+		name:   "other dir",
+		dir:    filepath.Join("cwd", "super"),
+		origin: filepath.Join("..", "remotes", "super"),
+		bare:   filepath.Join("cwd", "libs", "lib"),
+		want:   "../../libs/lib",
+	}}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := submoduleURL(c.dir, c.origin, c.bare)
+			if err != nil {
+				t.Fatalf("ERROR: got error: %v", err)
+			}
+			if got != c.want {
+				t.Errorf("ERROR: got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+// TestGenerateFileContent tests the generated content.
+func TestGenerateFileContent(t *testing.T) {
+
+	testCases := []struct {
+		name      string
+		spell     generateFileSpell
+		wantSize  int
+		wantLines int
+	}{{
+		name:     "binary",
+		spell:    generateFileSpell{File: "x", Size: "64KiB", Seed: 1},
+		wantSize: 64 * 1024,
+	}, {
+		name:     "text size",
+		spell:    generateFileSpell{File: "x", Kind: "text", Size: "1000", Seed: 1},
+		wantSize: 1000,
+	}, {
+		name:      "text lines",
+		spell:     generateFileSpell{File: "x", Kind: "text", Lines: 50, Seed: 1},
+		wantLines: 50,
+	}}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+
+			got, err := c.spell.content()
+			if err != nil {
+				t.Fatalf("ERROR: got error: %v", err)
+			}
+			if c.wantSize != 0 && len(got) != c.wantSize {
+				t.Errorf("ERROR: got size %d, want %d", len(got), c.wantSize)
+			}
+			if lines := strings.Count(string(got), "\n"); c.wantLines != 0 && lines != c.wantLines {
+				t.Errorf("ERROR: got %d lines, want %d", lines, c.wantLines)
+			}
+			if c.spell.Kind == kindText && !bytes.HasSuffix(got, []byte("\n")) {
+				t.Errorf("ERROR: got %q at the end, want a newline", got[len(got)-1:])
+			}
+
+			// the same seed must result in the same content
+			again, _ := c.spell.content()
+			if !bytes.Equal(got, again) {
+				t.Errorf("ERROR: content differs for the same seed")
+			}
+
+			// another seed must result in another content
+			c.spell.Seed++
+			other, _ := c.spell.content()
+			if bytes.Equal(got, other) {
+				t.Errorf("ERROR: same content for different seeds")
+			}
+		})
+	}
+}
+
+// TestBisectRegressionCommit tests the choice of the regression commit.
+func TestBisectRegressionCommit(t *testing.T) {
+
+	opt := Options{Variables: map[string]string{"alice": "alice", "bob": "bob"}}
+	spell := bisectHistorySpell{File: "x", Commits: 20, Seed: 42, Author: "red"}
+
+	// the same seed must always return the same commit
+	want, err := spell.regressionCommit(opt)
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+	for range 10 {
+		got, _ := spell.regressionCommit(opt)
+		if got != want {
+			t.Fatalf("ERROR: got %d, want %d", got, want)
+		}
+	}
+
+	// all commits must be in the valid range
+	results := map[int]bool{}
+	for seed := range uint64(200) {
+		spell.Seed = seed
+		got, _ := spell.regressionCommit(opt)
+		if got < 2 || got > spell.Commits {
+			t.Fatalf("ERROR: seed %d: got %d, not in range", seed, got)
+		}
+		results[got] = true
+	}
+	if len(results) < spell.Commits/2 {
+		t.Errorf("ERROR: only %d different commits", len(results))
+	}
+
+	// different variable values should result in different commits
+	spell.Seed = 0
+	spell.SeedFrom = "alice"
+	alice, _ := spell.regressionCommit(opt)
+	spell.SeedFrom = "bob"
+	bob, _ := spell.regressionCommit(opt)
+	if alice == bob {
+		t.Errorf("ERROR: same commit for different variables: %d", alice)
+	}
+}
+
+// TestSwitchOrphan tests that an orphan switch removes the tracked
+// and the untracked files, but keeps the ignored files.
+func TestSwitchOrphan(t *testing.T) {
+
+	_, err := exec.LookPath(gitCmd)
+	if err != nil {
+		t.Skipf("%s not found", gitCmd)
+	}
+
+	formula, err := readFormula(strings.NewReader(`
+title: orphan
+commands:
+  - init_bare_repo:
+      bare: remotes/orphan
+      clone_to: orphan
+  - create_add_commit:
+      files:
+        - gitignore => .gitignore
+        - plan.txt => plan.txt
+      message: add plan
+      author: blue
+  - create_file:
+      source: plan.txt
+      target: untracked.txt
+  - create_file:
+      source: plan.txt
+      target: .env
+  - switch:
+      branch: pages
+      orphan: true
+`))
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+
+	taskDir, repoDir := t.TempDir(), t.TempDir()
+	for file, content := range map[string]string{"gitignore": ".env\n", "plan.txt": "plan\n"} {
+		err = os.WriteFile(filepath.Join(taskDir, file), []byte(content), fileMode)
+		if err != nil {
+			t.Fatalf("ERROR: test setup failed: %v", err)
+		}
+	}
+
+	opt := Options{RepoDir: repoDir, CfgDir: taskDir, TaskDir: "."}
+	err = Transmute(formula, opt, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(repoDir, "orphan"))
+	if err != nil {
+		t.Fatalf("ERROR: got error: %v", err)
+	}
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.Name())
+	}
+	want := []string{".env", ".git"}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("ERROR: got- want+\n%s\n", diff)
+	}
+}
@@ -0,0 +1,160 @@
+package alchemist
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"path/filepath"
+	"strings"
+)
+
+// bisectHistorySpell provides creating a history of commits with
+// a regression for git bisect exercises.
+//
+// Each commit changes the source file. The regression is introduced
+// at commit RegressionAt. The test script is added with the first
+// commit, it fails if the source file contains the regression.
+type bisectHistorySpell struct {
+	File         string `yaml:"file"`          // source file that is changed
+	Commits      int    `yaml:"commits"`       // number of commits
+	RegressionAt int    `yaml:"regression_at"` // commit with the regression (1-based)
+	Seed         uint64 `yaml:"seed"`          // seed for a random regression commit
+	SeedFrom     string `yaml:"seed_from"`     // variable that is used as seed
+	TestScript   string `yaml:"test_script"`   // name of the test script
+	Message      string `yaml:"message"`       // commit message prefix
+	Author       string `yaml:"author"`
+}
+
+// defaults of the bisect history spell.
+const (
+	defaultBisectTestScript = "bisect_test.sh"
+	defaultBisectMessage    = "update"
+)
+
+// bisectRegression is the line that marks the regression in the source file.
+const bisectRegression = "status = broken"
+
+// validate checks the values and reports an error if something is missing.
+func (s bisectHistorySpell) validate() error {
+	if s.File == "" {
+		return MissingValueError("file")
+	}
+	if s.Commits < 2 {
+		return InvalidValueError{Variable: "commits", Reason: "at least 2 commits are needed"}
+	}
+	if s.RegressionAt != 0 && (s.RegressionAt < 2 || s.RegressionAt > s.Commits) {
+		return InvalidValueError{Variable: "regression_at",
+			Reason: fmt.Sprintf("must be between 2 and %d", s.Commits)}
+	}
+	if s.RegressionAt != 0 && (s.Seed != 0 || s.SeedFrom != "") {
+		return InvalidValueError{Variable: "regression_at",
+			Reason: "can not be combined with seed"}
+	}
+	if s.Author == "" {
+		return MissingValueError("author")
+	}
+	return nil
+}
+
+// regressionCommit returns the number of the commit with the regression.
+// If it is not defined, it is chosen randomly with the seed.
+// The first commit is never the regression commit, so it can be used
+// as the good commit.
+func (s bisectHistorySpell) regressionCommit(opt Options) (int, error) {
+	if s.RegressionAt != 0 {
+		return s.RegressionAt, nil
+	}
+
+	seed := s.Seed
+	if s.SeedFrom != "" {
+		value, ok := opt.Variables[s.SeedFrom]
+		if !ok {
+			return 0, InvalidValueError{Variable: s.SeedFrom, Reason: "unknown variable"}
+		}
+		hash := fnv.New64a()
+		_, _ = hash.Write([]byte(value))
+		seed ^= hash.Sum64()
+	}
+
+	r := rand.New(rand.NewPCG(seed, seed))
+	return 2 + r.IntN(s.Commits-1), nil
+}
+
+// content returns the content of the source file for commit n.
+func (s bisectHistorySpell) content(n, regression int) []byte {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, "value_%d = %d\n", i, i)
+		if i == regression {
+			b.WriteString(bisectRegression + "\n")
+		}
+	}
+	return []byte(b.String())
+}
+
+// testScript returns the content of the test script.
+// It exits with 0 before and with 1 after the regression.
+func (s bisectHistorySpell) testScript() []byte {
+	return []byte(`#!/bin/sh
+# test for git bisect run: fails if the regression is found
+if grep -q "` + bisectRegression + `" "` + filepath.ToSlash(s.File) + `"; then
+    echo "regression found"
+    exit 1
+fi
+echo "ok"
+exit 0
+`)
+}
+
+// cast creates the commits and the test script.
+func (s bisectHistorySpell) cast(a assistant, opt Options) error {
+
+	regression, err := s.regressionCommit(opt)
+	if err != nil {
+		return err
+	}
+
+	a.info("%d/%d: bisect history with %d commits, regression at commit %d",
+		opt.currentSpell, opt.numberOfSpells, s.Commits, regression)
+
+	script, message := s.TestScript, s.Message
+	if script == "" {
+		script = defaultBisectTestScript
+	}
+	if message == "" {
+		message = defaultBisectMessage
+	}
+
+	dir := filepath.Join(opt.RepoDir, opt.cloneTo)
+	for n := 1; n <= s.Commits; n++ {
+
+		files := []string{s.File}
+		err := a.writeFile(filepath.Join(dir, s.File), s.content(n, regression), fileMode)
+		if err != nil {
+			return err
+		}
+
+		if n == 1 {
+			files = append(files, script)
+			err = a.writeFile(filepath.Join(dir, script), s.testScript(), executableMode)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = addSpell{Files: files}.cast(a, opt)
+		if err != nil {
+			return err
+		}
+
+		err = commitSpell{
+			Message: fmt.Sprintf("%s %s (%d)", message, filepath.ToSlash(s.File), n),
+			Author:  s.Author,
+		}.cast(a, opt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package alchemist
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// bundleSpell provides writing refs of the clone to a bundle file.
+// Participants can clone from the bundle without any server.
+//
+// The file is relative to the repo dir like the clone directory.
+type bundleSpell struct {
+	File string   `yaml:"file"`
+	Refs []string `yaml:"refs"` // defaults to all refs
+}
+
+// bundleHead is added to the refs, so clones of the bundle check out a branch.
+const bundleHead = "HEAD"
+
+// validate checks the values and reports an error if something is missing.
+func (s bundleSpell) validate() error {
+	if s.File == "" {
+		return MissingValueError("file")
+	}
+	return nil
+}
+
+// cast executes git bundle create.
+func (s bundleSpell) cast(a assistant, opt Options) error {
+
+	dir := filepath.Join(opt.RepoDir, opt.cloneTo)
+	file := filepath.Join(opt.RepoDir, s.File)
+	rel, err := filepath.Rel(dir, file)
+	if err != nil {
+		return InvalidValueError{Variable: "file", Reason: err.Error()}
+	}
+
+	err = a.makedir(filepath.Dir(file))
+	if err != nil {
+		return err
+	}
+
+	refs := []string{"--all"}
+	if len(s.Refs) > 0 {
+		refs = s.Refs
+		if !slices.Contains(refs, bundleHead) {
+			refs = append(slices.Clone(refs), bundleHead)
+		}
+	}
+	args := append([]string{"bundle", "create", filepath.ToSlash(rel)}, refs...)
+
+	a.info("%d/%d: %s", opt.currentSpell, opt.numberOfSpells, strings.Join(args, " "))
+
+	return a.git(dir, args...)
+}
@@ -0,0 +1,71 @@
+package alchemist
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// chmodSpell provides setting or removing the executable bit of files.
+//
+// With stage, the mode change is also written to the index with
+// git update-index, which works even if core.fileMode is false,
+// e.g. on windows.
+type chmodSpell struct {
+	Files []string `yaml:"files"`
+	Mode  string   `yaml:"mode"`  // +x or -x
+	Stage bool     `yaml:"stage"` // stage the mode change
+}
+
+// modes of the chmod spell.
+const (
+	modeExecutable    = "+x"
+	modeNotExecutable = "-x"
+)
+
+// validate checks the values and reports an error if something is missing.
+func (s chmodSpell) validate() error {
+	if len(s.Files) == 0 {
+		return MissingValueError("files")
+	}
+	if s.Mode == "" {
+		return MissingValueError("mode")
+	}
+	if s.Mode != modeExecutable && s.Mode != modeNotExecutable {
+		return InvalidValueError{Variable: "mode", Reason: "must be +x or -x"}
+	}
+	return nil
+}
+
+// cast changes the mode of the files in the working tree and
+// optionally in the index.
+func (s chmodSpell) cast(a assistant, opt Options) error {
+
+	var mode fs.FileMode = fileMode
+	if s.Mode == modeExecutable {
+		mode = executableMode
+	}
+
+	dir := filepath.Join(opt.RepoDir, opt.cloneTo)
+	a.info("%d/%d: chmod %s %d files", opt.currentSpell, opt.numberOfSpells,
+		s.Mode, len(s.Files))
+
+	for _, file := range s.Files {
+		err := a.chmod(filepath.Join(dir, file), mode)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !s.Stage {
+		return nil
+	}
+
+	args := []string{"update-index", "--chmod=" + s.Mode, "--"}
+	for _, file := range s.Files {
+		args = append(args, filepath.ToSlash(file))
+	}
+	a.info("%d/%d: %s", opt.currentSpell, opt.numberOfSpells, strings.Join(args, " "))
+
+	return a.git(dir, args...)
+}
@@ -0,0 +1,135 @@
+package alchemist
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// cloneSpell provides an additional clone of a bare repo or a bundle
+// with its own user identity.
+//
+// Shallow, partial, and single branch clones use a file:// url,
+// because git ignores these options for local paths.
+type cloneSpell struct {
+	Bare         string `yaml:"bare"`
+	Bundle       string `yaml:"bundle"` // bundle file in the repo dir, alternative to bare
+	CloneTo      string `yaml:"clone_to"`
+	User         string `yaml:"user"`          // defaults to defaultUser
+	Depth        int    `yaml:"depth"`         // create a shallow clone
+	Filter       string `yaml:"filter"`        // create a partial clone, e.g. blob:none
+	SingleBranch bool   `yaml:"single_branch"` // clone only the default branch
+}
+
+// validate checks the values and reports an error if something is missing.
+func (s cloneSpell) validate() error {
+	if s.Bare == "" && s.Bundle == "" {
+		return MissingValueError("bare or bundle")
+	}
+	if s.Bare != "" && s.Bundle != "" {
+		return InvalidValueError{Variable: "bare", Reason: "can not be combined with bundle"}
+	}
+	if s.Depth < 0 {
+		return InvalidValueError{Variable: "depth", Reason: "must not be negative"}
+	}
+	if s.Bundle != "" && s.Filter != "" {
+		return InvalidValueError{Variable: "filter", Reason: "can not be used with bundle"}
+	}
+	if s.CloneTo == "" {
+		return MissingValueError("clone_to")
+	}
+	if _, ok := author[s.User]; s.User != "" && !ok {
+		return InvalidValueError{Variable: "user", Reason: "unknown user " + s.User}
+	}
+	return nil
+}
+
+// cast clones the bare repo and configures the user.
+func (s cloneSpell) cast(a assistant, opt Options) error {
+
+	user := s.User
+	if user == "" {
+		user = defaultUser
+	}
+
+	source := s.Bare
+	if s.Bundle != "" {
+		source = s.Bundle
+	}
+
+	cloneDir := filepath.Join(opt.RepoDir, s.CloneTo)
+	a.info("%d/%d: clone %s to %s for %s", opt.currentSpell, opt.numberOfSpells,
+		source, s.CloneTo, author[user])
+
+	var hints []spellHint
+	cloneArgs := []string{"clone"}
+	if s.Depth > 0 {
+		cloneArgs = append(cloneArgs, "--depth", strconv.Itoa(s.Depth))
+	}
+	if s.Filter != "" {
+		// the bare repo acts as server, which must allow filters
+		hints = append(hints, spellHint{
+			dir:  filepath.Join(opt.RepoDir, s.Bare),
+			args: []string{"config", "uploadpack.allowFilter", "true"},
+		})
+		cloneArgs = append(cloneArgs, "--filter", s.Filter)
+	}
+	if s.SingleBranch {
+		cloneArgs = append(cloneArgs, "--single-branch")
+	}
+	if s.Bare != "" && len(cloneArgs) > 1 {
+		url, err := fileURL(filepath.Join(opt.RepoDir, s.Bare))
+		if err != nil {
+			return err
+		}
+		source = url
+	}
+	hints = append(hints, spellHint{
+		dir:  opt.RepoDir,
+		args: append(cloneArgs, source, s.CloneTo),
+	})
+
+	// the origin of a bundle clone is the bundle file
+	if s.Bare != "" {
+		hints = append(hints, spellHint{
+			dir:  cloneDir,
+			args: []string{"remote", "set-url", "origin", filepath.Join("..", s.Bare)},
+		})
+	}
+
+	hints = append(hints, []spellHint{{
+		dir:  cloneDir,
+		args: []string{"config", "user.name", author[user]},
+	}, {
+		dir:  cloneDir,
+		args: []string{"config", "user.email", email[user]},
+	}, {
+		dir:  cloneDir,
+		args: []string{"config", "init.defaultBranch", defaultBranch},
+	}}...)
+
+	for _, hint := range hints {
+		a.info("%d/%d: %s", opt.currentSpell, opt.numberOfSpells,
+			strings.Join(hint.args, " "))
+		err := a.git(hint.dir, hint.args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fileURL returns the file:// url of the local path.
+func fileURL(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", IOError{Cmd: "absolute path", Arg: path, Err: err}
+	}
+	abs = filepath.ToSlash(abs)
+	// windows paths start with the drive letter
+	if !strings.HasPrefix(abs, "/") {
+		abs = "/" + abs
+	}
+	return "file://" + abs, nil
+}
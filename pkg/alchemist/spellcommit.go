@@ -1,21 +1,56 @@
 package alchemist
 
-import "path/filepath"
+import (
+	"path/filepath"
+	"strings"
+)
 
 // commitSpell provides committing the index to the repo.
+//
+// The message is either given by message and body or read from the
+// message file in the task directory. Trailers are appended as
+// "Key: value", a value of a *-by trailer that is a known author is
+// replaced by name and email.
+//
+// With amend, the last commit is replaced. The message and the author
+// are kept unless they are set, the files are added before.
 type commitSpell struct {
-	Message string `yaml:"message"`
-	Author  string `yaml:"author"`
+	Message     string   `yaml:"message"`
+	Body        string   `yaml:"body,omitempty"`         // optional, further paragraphs
+	MessageFile string   `yaml:"message_file,omitempty"` // alternative to message and body
+	Trailers    []string `yaml:"trailers,omitempty"`     // e.g. "Co-authored-by: blue"
+	Author      string   `yaml:"author"`
+	Sign        bool     `yaml:"sign,omitempty"`  // sign with the ssh key of the author
+	Amend       bool     `yaml:"amend,omitempty"` // replace the last commit
+	Files       []string `yaml:"files,omitempty"` // amend only, files to add
 }
 
 // validate checks the values and reports an error if something is missing.
 func (s commitSpell) validate() error {
-	if s.Message == "" {
+	if s.Message == "" && s.MessageFile == "" && !s.Amend {
 		return MissingValueError("message")
 	}
-	if s.Author == "" {
+	if s.MessageFile != "" && (s.Message != "" || s.Body != "") {
+		return InvalidValueError{Variable: "message_file", Reason: "can not be used with message or body"}
+	}
+	if s.Body != "" && s.Message == "" {
+		return InvalidValueError{Variable: "body", Reason: "can only be used with message"}
+	}
+	for _, trailer := range s.Trailers {
+		key, value, ok := strings.Cut(trailer, ":")
+		if !ok || strings.TrimSpace(key) == "" || strings.TrimSpace(value) == "" {
+			return InvalidValueError{Variable: "trailers", Reason: "invalid trailer " + trailer + ", want 'key: value'"}
+		}
+	}
+	if s.Author == "" && !s.Amend {
 		return MissingValueError("author")
 	}
+	if len(s.Files) > 0 && !s.Amend {
+		return InvalidValueError{Variable: "files", Reason: "can only be used with amend"}
+	}
+	if _, ok := author[s.Author]; s.Sign && !ok {
+		return InvalidValueError{Variable: "author", Reason: "unknown author " + s.Author + " can not sign"}
+	}
 	return nil
 }
 
@@ -24,11 +59,55 @@ const gitCommitDateFormat = "format:relative:5.hours.ago"
 // incant executes git commit.
 func (s commitSpell) cast(a assistant, opt Options) error {
 
+	if len(s.Files) > 0 {
+		err := addSpell{Files: s.Files}.cast(a, opt)
+		if err != nil {
+			return err
+		}
+	}
+
 	a.info("%d/%d: commit", opt.currentSpell, opt.numberOfSpells)
 
 	dir := filepath.Join(opt.RepoDir, opt.cloneTo)
-	args := []string{"commit", "--date=" + gitCommitDateFormat,
-		"-m", s.Message, "--author=" + getAuthor(s.Author)}
+	args := []string{"commit"}
+	if s.Amend {
+		// the author date is kept
+		args = append(args, "--amend")
+	} else {
+		args = append(args, "--date="+gitCommitDateFormat)
+	}
+
+	switch {
+	case s.MessageFile != "":
+		// git runs in the clone, the file has to be absolute
+		file, err := filepath.Abs(filepath.Join(opt.CfgDir, opt.TaskDir, s.MessageFile))
+		if err != nil {
+			return IOError{Cmd: "abs", Arg: s.MessageFile, Err: err}
+		}
+		args = append(args, "-F", file)
+	case s.Message != "":
+		args = append(args, "-m", s.Message)
+		if s.Body != "" {
+			args = append(args, "-m", s.Body)
+		}
+	default:
+		args = append(args, "--no-edit")
+	}
+
+	if s.Author != "" {
+		args = append(args, "--author="+getAuthor(s.Author))
+	}
+	for _, trailer := range s.Trailers {
+		args = append(args, "--trailer", resolveTrailer(trailer))
+	}
+
+	if s.Sign {
+		sign, err := signConfig(opt.RepoDir, s.Author)
+		if err != nil {
+			return err
+		}
+		args = append(append(sign, args...), "--gpg-sign")
+	}
 
 	err := a.git(dir, args...)
 	if err != nil {
@@ -37,3 +116,15 @@ func (s commitSpell) cast(a assistant, opt Options) error {
 
 	return nil
 }
+
+// resolveTrailer normalizes a "key: value" trailer. The value of a
+// person trailer like Co-authored-by or Signed-off-by is replaced by
+// name and email if it is a known author.
+func resolveTrailer(trailer string) string {
+	key, value, _ := strings.Cut(trailer, ":")
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+	if strings.HasSuffix(strings.ToLower(key), "-by") {
+		value = getAuthor(value)
+	}
+	return key + ": " + value
+}
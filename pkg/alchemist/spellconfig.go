@@ -0,0 +1,44 @@
+package alchemist
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// configSpell provides setting or removing a git config key
+// of the clone (local scope).
+type configSpell struct {
+	Key   string `yaml:"key"` // e.g. pull.rebase
+	Value string `yaml:"value"`
+	Unset bool   `yaml:"unset"` // remove the key instead of setting it
+}
+
+// validate checks the values and reports an error if something is missing.
+func (s configSpell) validate() error {
+	if s.Key == "" {
+		return MissingValueError("key")
+	}
+	if !strings.Contains(strings.Trim(s.Key, "."), ".") {
+		return InvalidValueError{Variable: "key", Reason: "must be section.name"}
+	}
+	if s.Unset && s.Value != "" {
+		return InvalidValueError{Variable: "unset", Reason: "can not be combined with value"}
+	}
+	return nil
+}
+
+// cast executes git config.
+func (s configSpell) cast(a assistant, opt Options) error {
+
+	args := []string{"config", "--local"}
+	if s.Unset {
+		args = append(args, "--unset", s.Key)
+	} else {
+		args = append(args, s.Key, s.Value)
+	}
+
+	dir := filepath.Join(opt.RepoDir, opt.cloneTo)
+	a.info("%d/%d: %s", opt.currentSpell, opt.numberOfSpells, strings.Join(args, " "))
+
+	return a.git(dir, args...)
+}
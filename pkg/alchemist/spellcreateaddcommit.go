@@ -6,9 +6,14 @@ import (
 
 // createAddCommitSpell
 type createAddCommitSpell struct {
-	Files   []string `yaml:"files"`
-	Message string   `yaml:"message"`
-	Author  string   `yaml:"author"`
+	Files       []string `yaml:"files"`
+	Message     string   `yaml:"message"`
+	Body        string   `yaml:"body,omitempty"`
+	MessageFile string   `yaml:"message_file,omitempty"`
+	Trailers    []string `yaml:"trailers,omitempty"`
+	Author      string   `yaml:"author"`
+	Sign        bool     `yaml:"sign,omitempty"` // sign the commit with the ssh key of the author
+	EOL         string   `yaml:"eol,omitempty"`  // optional, line endings of the files, lf or crlf
 }
 
 // regexpSplitCreateAddCommit defines the regular  expression for
@@ -20,11 +25,13 @@ func (s createAddCommitSpell) validate() error {
 	if len(s.Files) == 0 {
 		return MissingValueError("files")
 	}
-	if s.Message == "" {
-		return MissingValueError("message")
+	err := validateEOL(s.EOL)
+	if err != nil {
+		return err
 	}
-	if s.Author == "" {
-		return MissingValueError("author")
+	err = s.commit().validate()
+	if err != nil {
+		return err
 	}
 
 	for _, file := range s.Files {
@@ -52,13 +59,10 @@ func (s createAddCommitSpell) cast(a assistant, opt Options) error {
 	for _, filePair := range s.Files {
 		elements := regexpSplitCreateAddCommit.Split(filePair, -1)
 		spells = append(spells,
-			createFileSpell{Source: elements[0], Target: elements[1]})
+			createFileSpell{Source: elements[0], Target: elements[1], EOL: s.EOL})
 	}
 	spells = append(spells, addSpell{Files: []string{"."}})
-	spells = append(spells, commitSpell{
-		Message: s.Message,
-		Author:  s.Author,
-	})
+	spells = append(spells, s.commit())
 
 	for _, spell := range spells {
 		err := spell.cast(a, opt)
@@ -69,3 +73,15 @@ func (s createAddCommitSpell) cast(a assistant, opt Options) error {
 
 	return nil
 }
+
+// commit returns the commitSpell of the values.
+func (s createAddCommitSpell) commit() commitSpell {
+	return commitSpell{
+		Message:     s.Message,
+		Body:        s.Body,
+		MessageFile: s.MessageFile,
+		Trailers:    s.Trailers,
+		Author:      s.Author,
+		Sign:        s.Sign,
+	}
+}
@@ -1,15 +1,26 @@
 package alchemist
 
 import (
+	"bytes"
 	"path/filepath"
 )
 
 // createFileSpell provides copying a file to the git repo directory.
+//
+// With eol, the line endings are converted to lf or crlf and the file
+// is written with the default file mode.
 type createFileSpell struct {
 	Source string `yaml:"source"`
 	Target string `yaml:"target"`
+	EOL    string `yaml:"eol,omitempty"` // optional, lf or crlf
 }
 
+// line endings of the create file spell.
+const (
+	eolLF   = "lf"
+	eolCRLF = "crlf"
+)
+
 // validate checks the values and reports an error if something is missing.
 func (s createFileSpell) validate() error {
 	if s.Source == "" {
@@ -18,7 +29,17 @@ func (s createFileSpell) validate() error {
 	if s.Target == "" {
 		return MissingValueError("target")
 	}
-	return nil
+	return validateEOL(s.EOL)
+}
+
+// validateEOL reports an error if the line ending is neither empty,
+// lf, nor crlf.
+func validateEOL(eol string) error {
+	switch eol {
+	case "", eolLF, eolCRLF:
+		return nil
+	}
+	return InvalidValueError{Variable: "eol", Reason: "unknown line ending " + eol}
 }
 
 // cast copies the file to the repo.
@@ -28,9 +49,26 @@ func (s createFileSpell) cast(a assistant, opt Options) error {
 	to := filepath.Join(opt.RepoDir, opt.cloneTo, s.Target)
 	a.info("%d/%d: copy %s to %s", opt.currentSpell, opt.numberOfSpells, from, to)
 
+	if s.EOL != "" {
+		data, err := a.readFile(from)
+		if err != nil {
+			return err
+		}
+		return a.writeFile(to, convertEOL(data, s.EOL), fileMode)
+	}
+
 	err := a.copy(from, to)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// convertEOL returns the data with all line endings converted to lf or crlf.
+func convertEOL(data []byte, eol string) []byte {
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	if eol == eolCRLF {
+		data = bytes.ReplaceAll(data, []byte("\n"), []byte("\r\n"))
+	}
+	return data
+}
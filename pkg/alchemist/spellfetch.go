@@ -0,0 +1,44 @@
+package alchemist
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// fetchSpell provides fetching from a remote repository.
+type fetchSpell struct {
+	Remote string `yaml:"remote"` // defaults to origin
+	Prune  bool   `yaml:"prune"`
+	Tags   bool   `yaml:"tags"`
+}
+
+// defaultRemote is the name of the remote if none is specified.
+const defaultRemote = "origin"
+
+// validate checks the values and reports an error if something is missing.
+func (s fetchSpell) validate() error {
+	return nil
+}
+
+// cast executes git fetch.
+func (s fetchSpell) cast(a assistant, opt Options) error {
+
+	remote := s.Remote
+	if remote == "" {
+		remote = defaultRemote
+	}
+
+	args := []string{"fetch"}
+	if s.Prune {
+		args = append(args, "--prune")
+	}
+	if s.Tags {
+		args = append(args, "--tags")
+	}
+	args = append(args, remote)
+
+	dir := filepath.Join(opt.RepoDir, opt.cloneTo)
+	a.info("%d/%d: %s", opt.currentSpell, opt.numberOfSpells, strings.Join(args, " "))
+
+	return a.git(dir, args...)
+}
@@ -0,0 +1,157 @@
+package alchemist
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/rand/v2"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// generateFileSpell provides writing deterministic pseudo-random
+// binary or text files of a given size into the clone.
+//
+// The same seed always results in the same content, so big files
+// don't have to be stored in the task dir.
+type generateFileSpell struct {
+	File  string `yaml:"file"`
+	Kind  string `yaml:"kind"`  // binary or text, defaults to binary
+	Size  string `yaml:"size"`  // e.g. 512, 64KiB, 10MB
+	Lines int    `yaml:"lines"` // text only, number of lines
+	Seed  uint64 `yaml:"seed"`
+}
+
+// kinds of generated files.
+const (
+	kindBinary = "binary"
+	kindText   = "text"
+)
+
+// maxGeneratedSize is the maximum size of a generated file,
+// because the content is created in memory.
+const maxGeneratedSize = 1 << 30
+
+// regexpSize matches sizes like 512, 64KiB or 10 MB.
+var regexpSize = regexp.MustCompile(`^(\d+)\s*([KMG]i?B|B)?$`)
+
+// sizeUnits are the multipliers of the size units.
+var sizeUnits = map[string]int64{
+	"":    1,
+	"B":   1,
+	"KB":  1000,
+	"MB":  1000 * 1000,
+	"GB":  1000 * 1000 * 1000,
+	"KiB": 1 << 10,
+	"MiB": 1 << 20,
+	"GiB": 1 << 30,
+}
+
+// parseSize returns the number of bytes of a size like 64KiB.
+func parseSize(size string) (int64, error) {
+	match := regexpSize.FindStringSubmatch(strings.TrimSpace(size))
+	if match == nil {
+		return 0, InvalidValueError{Variable: "size", Reason: "invalid size " + size}
+	}
+	n, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return 0, InvalidValueError{Variable: "size", Reason: err.Error()}
+	}
+	bytes := n * sizeUnits[match[2]]
+	if bytes/sizeUnits[match[2]] != n || bytes > maxGeneratedSize {
+		return 0, InvalidValueError{Variable: "size", Reason: "must not exceed 1GiB"}
+	}
+	return bytes, nil
+}
+
+// validate checks the values and reports an error if something is missing.
+func (s generateFileSpell) validate() error {
+	if s.File == "" {
+		return MissingValueError("file")
+	}
+	if s.Lines < 0 {
+		return InvalidValueError{Variable: "lines", Reason: "must not be negative"}
+	}
+
+	switch s.Kind {
+	case "", kindBinary:
+		if s.Size == "" {
+			return MissingValueError("size")
+		}
+		if s.Lines != 0 {
+			return InvalidValueError{Variable: "lines", Reason: "can only be used with text"}
+		}
+	case kindText:
+		if s.Size == "" && s.Lines == 0 {
+			return MissingValueError("size or lines")
+		}
+		if s.Size != "" && s.Lines != 0 {
+			return InvalidValueError{Variable: "size", Reason: "can not be combined with lines"}
+		}
+	default:
+		return InvalidValueError{Variable: "kind", Reason: "unknown kind " + s.Kind}
+	}
+
+	if s.Size != "" {
+		_, err := parseSize(s.Size)
+		return err
+	}
+	return nil
+}
+
+// content returns the generated content.
+func (s generateFileSpell) content() ([]byte, error) {
+
+	var size int64
+	if s.Size != "" {
+		var err error
+		size, err = parseSize(s.Size)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var seed [32]byte
+	binary.LittleEndian.PutUint64(seed[:], s.Seed)
+	r := rand.NewChaCha8(seed)
+
+	if s.Kind != kindText {
+		data := make([]byte, size)
+		_, _ = r.Read(data)
+		return data, nil
+	}
+
+	// text: lines with a number and random hex digits,
+	// the last line is truncated to the size and ends with a newline
+	var b strings.Builder
+	random := make([]byte, 24)
+	for n := 1; s.Lines == 0 || n <= s.Lines; n++ {
+		if s.Lines == 0 && int64(b.Len()) >= size {
+			break
+		}
+		_, _ = r.Read(random)
+		fmt.Fprintf(&b, "line %d: %s\n", n, hex.EncodeToString(random))
+	}
+	data := []byte(b.String())
+	if s.Lines == 0 && size > 0 {
+		data = data[:size]
+		data[size-1] = '\n'
+	}
+	return data, nil
+}
+
+// cast writes the generated file to the clone.
+func (s generateFileSpell) cast(a assistant, opt Options) error {
+
+	data, err := s.content()
+	if err != nil {
+		return err
+	}
+
+	to := filepath.Join(opt.RepoDir, opt.cloneTo, s.File)
+	a.info("%d/%d: generate %s (%d bytes)", opt.currentSpell, opt.numberOfSpells, to, len(data))
+
+	return a.writeFile(to, data, fileMode)
+}
@@ -5,6 +5,10 @@ import (
 	"strings"
 )
 
+// argSpace contains the characters that separate the arguments
+// of a git spell.
+const argSpace = " \t\n"
+
 // gitSpell provides arbitrary git commands.
 type gitSpell struct {
 	Command string `yaml:"command"`
@@ -33,18 +37,45 @@ func (s gitSpell) cast(a assistant, opt Options) error {
 	return nil
 }
 
-// splitArgs splits args like the shell: double quoted text
-// can contain spaces.
+// splitArgs splits args like the shell: double quoted text can
+// contain spaces and is joined with adjacent text, "" is an empty
+// argument. Within double quotes, \" and \\ are a quote and a
+// backslash, other backslashes are kept, e.g. in Windows paths.
 func (s gitSpell) splitArgs() []string {
 
-	var quoted bool
-	args := strings.FieldsFunc(s.Command, func(r rune) bool {
-		if r == '"' {
+	var args []string
+	var arg strings.Builder
+	var quoted, escaped, started bool
+	for _, r := range s.Command {
+		switch {
+		case escaped:
+			if r != '"' && r != '\\' {
+				arg.WriteRune('\\')
+			}
+			arg.WriteRune(r)
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
 			quoted = !quoted
-			return true
+			started = true
+		case !quoted && strings.ContainsRune(argSpace, r):
+			if started {
+				args = append(args, arg.String())
+				arg.Reset()
+			}
+			started = false
+		default:
+			arg.WriteRune(r)
+			started = true
 		}
-		return !quoted && r == ' '
-	})
+	}
+	if escaped {
+		arg.WriteRune('\\')
+	}
+	if started {
+		args = append(args, arg.String())
+	}
 
 	// strip prefixed git command
 	if len(args) > 0 && (args[0] == linuxGitCmd || args[0] == windowsGitCmd) {
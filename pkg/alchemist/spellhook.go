@@ -0,0 +1,78 @@
+package alchemist
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// hookSpell provides installing a hook script from the task
+// directory into the clone, e.g. a pre-commit or commit-msg hook.
+type hookSpell struct {
+	Hook   string `yaml:"hook"`   // name of the hook, e.g. pre-commit
+	Source string `yaml:"source"` // script in the task directory
+}
+
+// clientHooks are the hooks that are called in the clone.
+var clientHooks = []string{
+	"applypatch-msg",
+	"pre-applypatch",
+	"post-applypatch",
+	"pre-commit",
+	"pre-merge-commit",
+	"prepare-commit-msg",
+	"commit-msg",
+	"post-commit",
+	"pre-rebase",
+	"post-checkout",
+	"post-merge",
+	"pre-push",
+	"post-rewrite",
+	"pre-auto-gc",
+}
+
+// validate checks the values and reports an error if something is missing.
+func (s hookSpell) validate() error {
+	if s.Hook == "" {
+		return MissingValueError("hook")
+	}
+	if !slices.Contains(clientHooks, s.Hook) {
+		return InvalidValueError{Variable: "hook", Reason: "unknown client hook " + s.Hook}
+	}
+	if s.Source == "" {
+		return MissingValueError("source")
+	}
+	return nil
+}
+
+// cast copies the script to the hooks directory of the clone
+// and makes it executable. The hooks directory is resolved by git,
+// .git is a file in worktrees and submodules. Without output, e.g. in
+// test mode, the hooks directory of the .git directory is used.
+func (s hookSpell) cast(a assistant, opt Options) error {
+
+	dir := filepath.Join(opt.RepoDir, opt.cloneTo)
+	hooks, err := a.gitOutput(dir, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return err
+	}
+	hooks = filepath.FromSlash(strings.TrimSpace(hooks))
+	switch {
+	case hooks == "":
+		hooks = filepath.Join(dir, ".git", "hooks")
+	case !filepath.IsAbs(hooks):
+		hooks = filepath.Join(dir, hooks)
+	}
+
+	from := filepath.Join(opt.CfgDir, opt.TaskDir, s.Source)
+	to := filepath.Join(hooks, s.Hook)
+	a.info("%d/%d: install %s hook %s to %s", opt.currentSpell, opt.numberOfSpells,
+		s.Hook, from, to)
+
+	err = a.copy(from, to)
+	if err != nil {
+		return err
+	}
+
+	return a.chmod(to, executableMode)
+}
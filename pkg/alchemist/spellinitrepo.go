@@ -6,9 +6,14 @@ import (
 )
 
 // initRepoSpell provides initializing a bare repo and cloning it.
+//
+// If ForkOf is set, the bare repo is created as a copy of this
+// bare repo, e.g. to simulate forks.
 type initRepoSpell struct {
 	Bare    string `yaml:"bare"`
 	CloneTo string `yaml:"clone_to"`
+	ForkOf  string `yaml:"fork_of,omitempty"` // optional, bare repo to fork
+	Remote  string `yaml:"remote,omitempty"`  // optional, name of the remote, defaults to origin
 }
 
 // validate checks the values and reports an error if something is missing.
@@ -19,6 +24,9 @@ func (s initRepoSpell) validate() error {
 	if s.CloneTo == "" {
 		return MissingValueError("clone_to")
 	}
+	if s.ForkOf == s.Bare {
+		return InvalidValueError{Variable: "fork_of", Reason: "can not fork itself"}
+	}
 	return nil
 }
 
@@ -34,15 +42,33 @@ func (s initRepoSpell) cast(a assistant, opt Options) error {
 		return err
 	}
 
+	remote := s.Remote
+	if remote == "" {
+		remote = defaultRemote
+	}
+
 	hints := []spellHint{{
 		dir:  bareDir,
 		args: []string{"init", "--bare", "--initial-branch=" + defaultBranch, "."},
-	}, {
+	}}
+	if s.ForkOf != "" {
+		hints = []spellHint{{
+			dir:  opt.RepoDir,
+			args: []string{"clone", "--bare", s.ForkOf, s.Bare},
+		}}
+	}
+
+	cloneArgs := []string{"clone", s.Bare, s.CloneTo}
+	if remote != defaultRemote {
+		cloneArgs = []string{"clone", "--origin", remote, s.Bare, s.CloneTo}
+	}
+
+	hints = append(hints, []spellHint{{
 		dir:  opt.RepoDir,
-		args: []string{"clone", s.Bare, s.CloneTo},
+		args: cloneArgs,
 	}, {
 		dir:  filepath.Join(opt.RepoDir, s.CloneTo),
-		args: []string{"remote", "set-url", "origin", filepath.Join("..", s.Bare)},
+		args: []string{"remote", "set-url", remote, filepath.Join("..", s.Bare)},
 	}, {
 		dir:  filepath.Join(opt.RepoDir, s.CloneTo),
 		args: []string{"config", "user.name", author["red"]},
@@ -52,7 +78,7 @@ func (s initRepoSpell) cast(a assistant, opt Options) error {
 	}, {
 		dir:  filepath.Join(opt.RepoDir, s.CloneTo),
 		args: []string{"config", "init.defaultBranch", defaultBranch},
-	}}
+	}}...)
 
 	for _, hint := range hints {
 		a.info("%d/%d: %s", opt.currentSpell, opt.numberOfSpells,
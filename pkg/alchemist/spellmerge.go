@@ -9,7 +9,10 @@ import (
 type mergeSpell struct {
 	Source       string `yaml:"source"`
 	Target       string `yaml:"target"`
-	DeleteSource bool   `yaml:"delete_source"`
+	DeleteSource bool   `yaml:"delete_source,omitempty"`
+	Author       string `yaml:"author,omitempty"`  // optional, user of the merge commit
+	NoFF         bool   `yaml:"no_ff,omitempty"`   // always create a merge commit
+	Message      string `yaml:"message,omitempty"` // optional, message of the merge commit
 }
 
 // validate checks the values and reports an error if something is missing.
@@ -30,12 +33,20 @@ func (s mergeSpell) cast(a assistant, opt Options) error {
 	a.info("%d/%d: merge %s with %s  (delete: %v)",
 		opt.currentSpell, opt.numberOfSpells, s.Source, s.Target, s.DeleteSource)
 
+	mergeArgs := append(authorConfig(s.Author), "merge")
+	if s.NoFF {
+		mergeArgs = append(mergeArgs, "--no-ff")
+	}
+	if s.Message != "" {
+		mergeArgs = append(mergeArgs, "-m", s.Message)
+	}
+
 	hints := []spellHint{{
 		dir:  dir,
 		args: []string{"checkout", s.Target},
 	}, {
 		dir:  dir,
-		args: []string{"merge", s.Source},
+		args: append(mergeArgs, s.Source),
 	}}
 
 	if s.DeleteSource {
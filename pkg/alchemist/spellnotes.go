@@ -0,0 +1,65 @@
+package alchemist
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// notesSpell provides adding, appending, and removing git notes.
+type notesSpell struct {
+	Action   string `yaml:"action"`    // add, append, or remove, defaults to add
+	Commit   string `yaml:"commit"`    // defaults to HEAD
+	Message  string `yaml:"message"`   // add and append only
+	NotesRef string `yaml:"notes_ref"` // optional, defaults to refs/notes/commits
+	Author   string `yaml:"author"`    // optional, defaults to the user of the clone
+}
+
+// actions of the notes spell.
+const (
+	notesAdd    = "add"
+	notesAppend = "append"
+	notesRemove = "remove"
+)
+
+// validate checks the values and reports an error if something is missing.
+func (s notesSpell) validate() error {
+	switch s.Action {
+	case "", notesAdd, notesAppend:
+		if s.Message == "" {
+			return MissingValueError("message")
+		}
+	case notesRemove:
+		if s.Message != "" {
+			return InvalidValueError{Variable: "message", Reason: "can not be used with remove"}
+		}
+	default:
+		return InvalidValueError{Variable: "action", Reason: "unknown action " + s.Action}
+	}
+	return nil
+}
+
+// cast executes git notes.
+func (s notesSpell) cast(a assistant, opt Options) error {
+
+	action := s.Action
+	if action == "" {
+		action = notesAdd
+	}
+
+	args := append(authorConfig(s.Author), "notes")
+	if s.NotesRef != "" {
+		args = append(args, "--ref", s.NotesRef)
+	}
+	args = append(args, action)
+	if s.Message != "" {
+		args = append(args, "-m", s.Message)
+	}
+	if s.Commit != "" {
+		args = append(args, s.Commit)
+	}
+
+	dir := filepath.Join(opt.RepoDir, opt.cloneTo)
+	a.info("%d/%d: %s", opt.currentSpell, opt.numberOfSpells, strings.Join(args, " "))
+
+	return a.git(dir, args...)
+}
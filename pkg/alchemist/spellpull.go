@@ -0,0 +1,52 @@
+package alchemist
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// pullSpell provides pulling from a remote repository with merge or rebase.
+type pullSpell struct {
+	Remote string `yaml:"remote"` // defaults to origin
+	Branch string `yaml:"branch"` // optional, remote branch
+	Rebase bool   `yaml:"rebase"`
+	FFOnly bool   `yaml:"ff_only"`
+	Author string `yaml:"author"` // optional, user of the created commits
+}
+
+// validate checks the values and reports an error if something is missing.
+func (s pullSpell) validate() error {
+	if s.Rebase && s.FFOnly {
+		return InvalidValueError{Variable: "rebase", Reason: "can not be combined with ff_only"}
+	}
+	return nil
+}
+
+// cast executes git pull.
+func (s pullSpell) cast(a assistant, opt Options) error {
+
+	remote := s.Remote
+	if remote == "" {
+		remote = defaultRemote
+	}
+
+	args := authorConfig(s.Author)
+	args = append(args, "pull")
+	switch {
+	case s.Rebase:
+		args = append(args, "--rebase")
+	case s.FFOnly:
+		args = append(args, "--ff-only")
+	default:
+		args = append(args, "--no-rebase")
+	}
+	args = append(args, remote)
+	if s.Branch != "" {
+		args = append(args, s.Branch)
+	}
+
+	dir := filepath.Join(opt.RepoDir, opt.cloneTo)
+	a.info("%d/%d: %s", opt.currentSpell, opt.numberOfSpells, strings.Join(args, " "))
+
+	return a.git(dir, args...)
+}
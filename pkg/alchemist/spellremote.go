@@ -0,0 +1,86 @@
+package alchemist
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// remoteSpell provides managing the remotes of the clone.
+//
+// The url of the remote can be another bare repo of the formula.
+type remoteSpell struct {
+	Action  string `yaml:"action"` // add, remove, rename, or set-url; defaults to add
+	Name    string `yaml:"name"`
+	NewName string `yaml:"new_name"` // rename only
+	Bare    string `yaml:"bare"`     // bare repo in the repo dir, used as url
+	URL     string `yaml:"url"`      // url of the remote, alternative to bare
+	Fetch   bool   `yaml:"fetch"`    // add only, fetch the remote immediately
+}
+
+// actions of the remote spell.
+const (
+	remoteAdd    = "add"
+	remoteRemove = "remove"
+	remoteRename = "rename"
+	remoteSetURL = "set-url"
+)
+
+// validate checks the values and reports an error if something is missing.
+func (s remoteSpell) validate() error {
+	if s.Name == "" {
+		return MissingValueError("name")
+	}
+
+	switch s.Action {
+	case "", remoteAdd, remoteSetURL:
+		if s.Bare == "" && s.URL == "" {
+			return MissingValueError("bare or url")
+		}
+		if s.Bare != "" && s.URL != "" {
+			return InvalidValueError{Variable: "bare", Reason: "can not be combined with url"}
+		}
+	case remoteRename:
+		if s.NewName == "" {
+			return MissingValueError("new_name")
+		}
+	case remoteRemove:
+	default:
+		return InvalidValueError{Variable: "action", Reason: "unknown action " + s.Action}
+	}
+
+	return nil
+}
+
+// url returns the url of the remote. A bare repo is referenced
+// relative to the clone directory.
+func (s remoteSpell) url() string {
+	if s.Bare != "" {
+		return filepath.Join("..", s.Bare)
+	}
+	return s.URL
+}
+
+// cast executes git remote.
+func (s remoteSpell) cast(a assistant, opt Options) error {
+
+	var args []string
+	switch s.Action {
+	case "", remoteAdd:
+		args = []string{"remote", "add"}
+		if s.Fetch {
+			args = append(args, "--fetch")
+		}
+		args = append(args, s.Name, s.url())
+	case remoteSetURL:
+		args = []string{"remote", "set-url", s.Name, s.url()}
+	case remoteRename:
+		args = []string{"remote", "rename", s.Name, s.NewName}
+	case remoteRemove:
+		args = []string{"remote", "remove", s.Name}
+	}
+
+	dir := filepath.Join(opt.RepoDir, opt.cloneTo)
+	a.info("%d/%d: %s", opt.currentSpell, opt.numberOfSpells, strings.Join(args, " "))
+
+	return a.git(dir, args...)
+}
@@ -0,0 +1,32 @@
+package alchemist
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// renormalizeSpell provides applying the line ending rules of
+// .gitattributes and core.autocrlf to files that are already tracked.
+type renormalizeSpell struct {
+	Files []string `yaml:"files"` // optional, defaults to all files
+}
+
+// validate checks the values and reports an error if something is missing.
+func (s renormalizeSpell) validate() error {
+	return nil
+}
+
+// cast executes git add --renormalize.
+func (s renormalizeSpell) cast(a assistant, opt Options) error {
+
+	files := s.Files
+	if len(files) == 0 {
+		files = []string{"."}
+	}
+	args := append([]string{"add", "--renormalize", "--"}, files...)
+
+	dir := filepath.Join(opt.RepoDir, opt.cloneTo)
+	a.info("%d/%d: %s", opt.currentSpell, opt.numberOfSpells, strings.Join(args, " "))
+
+	return a.git(dir, args...)
+}
@@ -0,0 +1,135 @@
+package alchemist
+
+import (
+	"maps"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// repeatSpell provides repeating a list of spells.
+//
+// It is not a caster: the body is expanded into single spells
+// when the formula is read. Each scalar value in the body can
+// use the index and item variable in go template syntax,
+// e.g. "files/file_{{.index}}.txt".
+type repeatSpell struct {
+	Count int       `yaml:"count"`
+	Over  []string  `yaml:"over"`
+	Index string    `yaml:"index"` // name of the index variable (0-based)
+	Item  string    `yaml:"item"`  // name of the item variable (only with over)
+	Body  yaml.Node `yaml:"body"`
+}
+
+// default names of the repeat variables.
+const (
+	defaultRepeatIndex = "index"
+	defaultRepeatItem  = "item"
+)
+
+// validate checks the values and reports an error if something is missing.
+func (s repeatSpell) validate() error {
+	if s.Count == 0 && len(s.Over) == 0 {
+		return MissingValueError("count or over")
+	}
+	if s.Count != 0 && len(s.Over) > 0 {
+		return InvalidValueError{Variable: "count", Reason: "can not be combined with over"}
+	}
+	if s.Count < 0 {
+		return InvalidValueError{Variable: "count", Reason: "must not be negative"}
+	}
+	if s.Body.Kind != yaml.SequenceNode || len(s.Body.Content) == 0 {
+		return MissingValueError("body")
+	}
+	return nil
+}
+
+// iterations returns the variables for each iteration of the body.
+// The variables of the enclosing repeat spells are inherited.
+func (s repeatSpell) iterations(vars map[string]any) []map[string]any {
+
+	index, item := s.Index, s.Item
+	if index == "" {
+		index = defaultRepeatIndex
+	}
+	if item == "" {
+		item = defaultRepeatItem
+	}
+
+	n := s.Count
+	if len(s.Over) > 0 {
+		n = len(s.Over)
+	}
+
+	result := make([]map[string]any, 0, n)
+	for i := range n {
+		iterVars := maps.Clone(vars)
+		if iterVars == nil {
+			iterVars = map[string]any{}
+		}
+		iterVars[index] = i
+		if len(s.Over) > 0 {
+			iterVars[item] = s.Over[i]
+		}
+		result = append(result, iterVars)
+	}
+
+	return result
+}
+
+// repeatFuncs are the functions that can be used in the body
+// of a repeat spell in addition to the go template builtins.
+//
+//   - add: adds two numbers, e.g. {{add .index 1}}
+//   - mod: modulo of two numbers, e.g. {{mod .index 2}}
+//   - pick: rotates through a list, e.g. {{pick .index "red" "blue"}}
+var repeatFuncs = template.FuncMap{
+	"add": func(a, b int) int { return a + b },
+	"mod": func(a, b int) int {
+		if b == 0 {
+			return 0
+		}
+		return a % b
+	},
+	"pick": func(i int, values ...string) string {
+		if len(values) == 0 {
+			return ""
+		}
+		return values[i%len(values)]
+	},
+}
+
+// render returns a deep copy of the node where all scalar values
+// are executed as go templates with the provided variables.
+func render(node *yaml.Node, vars map[string]any) (*yaml.Node, error) {
+
+	result := *node
+	if node.Kind == yaml.ScalarNode && strings.Contains(node.Value, "{{") {
+		tmpl, err := template.New("repeat").Funcs(repeatFuncs).
+			Option("missingkey=error").Parse(node.Value)
+		if err != nil {
+			return nil, InvalidValueError{Variable: node.Value, Reason: err.Error()}
+		}
+		var buf strings.Builder
+		err = tmpl.Execute(&buf, vars)
+		if err != nil {
+			return nil, InvalidValueError{Variable: node.Value, Reason: err.Error()}
+		}
+		result.Value = buf.String()
+		// let the decoder resolve the type of the rendered value
+		result.Tag = ""
+		result.Style = 0
+	}
+
+	result.Content = make([]*yaml.Node, 0, len(node.Content))
+	for _, child := range node.Content {
+		rendered, err := render(child, vars)
+		if err != nil {
+			return nil, err
+		}
+		result.Content = append(result.Content, rendered)
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,120 @@
+package alchemist
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// rewriteHistorySpell provides removing a path or replacing a string
+// in all commits of the branches with git filter-branch. Tags of the
+// rewritten commits are rewritten, too.
+//
+// Replacing a string runs find and perl in the tree filter. Both are
+// part of the shell that comes with git, also on Windows.
+type rewriteHistorySpell struct {
+	Branches   []string    `yaml:"branches"`
+	RemovePath string      `yaml:"remove_path"`
+	Replace    replacement `yaml:"replace"`
+	Push       bool        `yaml:"push"` // force push the branches and the tags to origin
+}
+
+// replacement defines a string that is replaced in all files.
+type replacement struct {
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+}
+
+// defaultReplacement is used if replace.to is not defined.
+const defaultReplacement = "***REMOVED***"
+
+// validate checks the values and reports an error if something is missing.
+func (s rewriteHistorySpell) validate() error {
+	if len(s.Branches) == 0 {
+		return MissingValueError("branches")
+	}
+	if s.RemovePath == "" && s.Replace.From == "" {
+		return MissingValueError("remove_path or replace")
+	}
+	if s.Replace.To != "" && s.Replace.From == "" {
+		return MissingValueError("replace.from")
+	}
+	return nil
+}
+
+// indexFilter returns the shell command that removes the path.
+func (s rewriteHistorySpell) indexFilter() string {
+	return "git rm -r -q --cached --ignore-unmatch -- " +
+		shellQuote(filepath.ToSlash(s.RemovePath))
+}
+
+// treeFilter returns the shell command that replaces the string in all files.
+// The strings are passed as environment variables to perl, so they
+// are not interpreted as regular expressions.
+func (s rewriteHistorySpell) treeFilter() string {
+	to := s.Replace.To
+	if to == "" {
+		to = defaultReplacement
+	}
+	return "GITALCHEMIST_FROM=" + shellQuote(s.Replace.From) +
+		" GITALCHEMIST_TO=" + shellQuote(to) +
+		` find . -type f -exec perl -pi -e` +
+		` 's/\Q$ENV{GITALCHEMIST_FROM}\E/$ENV{GITALCHEMIST_TO}/g' {} +`
+}
+
+// cast rewrites the history of the branches.
+// The backup refs of filter-branch and the reflog are removed, so the
+// old commits are really gone.
+func (s rewriteHistorySpell) cast(a assistant, opt Options) error {
+
+	dir := filepath.Join(opt.RepoDir, opt.cloneTo)
+	a.info("%d/%d: rewrite history of %s", opt.currentSpell, opt.numberOfSpells,
+		strings.Join(s.Branches, ", "))
+
+	filterArgs := []string{"filter-branch", "--force", "--prune-empty", "--tag-name-filter", "cat"}
+	if s.RemovePath != "" {
+		filterArgs = append(filterArgs, "--index-filter", s.indexFilter())
+	}
+	if s.Replace.From != "" {
+		filterArgs = append(filterArgs, "--tree-filter", s.treeFilter())
+	}
+	filterArgs = append(filterArgs, "--")
+	filterArgs = append(filterArgs, s.Branches...)
+
+	hints := []spellHint{{dir: dir, args: filterArgs}}
+	for _, branch := range s.Branches {
+		hints = append(hints, spellHint{
+			dir:  dir,
+			args: []string{"update-ref", "-d", "refs/original/refs/heads/" + branch},
+		})
+	}
+	hints = append(hints, spellHint{
+		dir:  dir,
+		args: []string{"reflog", "expire", "--expire=now", "--all"},
+	}, spellHint{
+		dir:  dir,
+		args: []string{"gc", "--quiet", "--prune=now"},
+	})
+
+	if s.Push {
+		hints = append(hints, spellHint{
+			dir:  dir,
+			args: append([]string{"push", "--force", "--tags", "origin"}, s.Branches...),
+		})
+	}
+
+	for _, hint := range hints {
+		a.info("%d/%d: %s", opt.currentSpell, opt.numberOfSpells,
+			strings.Join(hint.args, " "))
+		err := a.git(hint.dir, hint.args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// shellQuote quotes the string for the posix shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
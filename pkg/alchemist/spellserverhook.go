@@ -0,0 +1,59 @@
+package alchemist
+
+import (
+	"path/filepath"
+	"slices"
+)
+
+// serverHookSpell provides installing a hook script from the task
+// directory into a bare repo. This way, pushes can be rejected
+// like on a real git server.
+type serverHookSpell struct {
+	Bare   string `yaml:"bare"`
+	Hook   string `yaml:"hook"`   // name of the hook, e.g. pre-receive
+	Source string `yaml:"source"` // script in the task directory
+}
+
+// serverHooks are the hooks that are called on the receiving side of a push.
+var serverHooks = []string{
+	"pre-receive",
+	"update",
+	"proc-receive",
+	"post-receive",
+	"post-update",
+	"reference-transaction",
+}
+
+// validate checks the values and reports an error if something is missing.
+func (s serverHookSpell) validate() error {
+	if s.Bare == "" {
+		return MissingValueError("bare")
+	}
+	if s.Hook == "" {
+		return MissingValueError("hook")
+	}
+	if !slices.Contains(serverHooks, s.Hook) {
+		return InvalidValueError{Variable: "hook", Reason: "unknown server hook " + s.Hook}
+	}
+	if s.Source == "" {
+		return MissingValueError("source")
+	}
+	return nil
+}
+
+// cast copies the script to the hooks directory of the bare repo
+// and makes it executable.
+func (s serverHookSpell) cast(a assistant, opt Options) error {
+
+	from := filepath.Join(opt.CfgDir, opt.TaskDir, s.Source)
+	to := filepath.Join(opt.RepoDir, s.Bare, "hooks", s.Hook)
+	a.info("%d/%d: install %s hook %s to %s", opt.currentSpell, opt.numberOfSpells,
+		s.Hook, from, to)
+
+	err := a.copy(from, to)
+	if err != nil {
+		return err
+	}
+
+	return a.chmod(to, executableMode)
+}
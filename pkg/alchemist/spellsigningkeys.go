@@ -0,0 +1,85 @@
+package alchemist
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// signingKeysSpell provides ssh signing keys for authors of the
+// registry and configures the clone to verify ssh signatures.
+//
+// Only the trusted authors are added to the allowed signers file,
+// so signatures of the other authors can not be verified.
+type signingKeysSpell struct {
+	Authors []string `yaml:"authors"`
+	Trusted []string `yaml:"trusted"` // defaults to all authors
+}
+
+// validate checks the values and reports an error if something is missing.
+func (s signingKeysSpell) validate() error {
+	if len(s.Authors) == 0 {
+		return MissingValueError("authors")
+	}
+	for _, name := range s.Authors {
+		if _, ok := author[name]; !ok {
+			return InvalidValueError{Variable: "authors", Reason: "unknown author " + name}
+		}
+	}
+	for _, name := range s.Trusted {
+		if !slices.Contains(s.Authors, name) {
+			return InvalidValueError{Variable: "trusted", Reason: "no key for " + name}
+		}
+	}
+	return nil
+}
+
+// cast creates the keys and the allowed signers file and configures the clone.
+func (s signingKeysSpell) cast(a assistant, opt Options) error {
+
+	trusted := s.Trusted
+	if len(trusted) == 0 {
+		trusted = s.Authors
+	}
+
+	var signers strings.Builder
+	for _, name := range s.Authors {
+		a.info("%d/%d: signing key for %s", opt.currentSpell, opt.numberOfSpells, author[name])
+		public, err := a.keygen(signingKey(opt.RepoDir, name), email[name])
+		if err != nil {
+			return err
+		}
+		if slices.Contains(trusted, name) {
+			signers.WriteString(email[name] + ` namespaces="git" ` + public + "\n")
+		}
+	}
+
+	signersFile, err := filepath.Abs(filepath.Join(opt.RepoDir, keysDir, allowedSignersFile))
+	if err != nil {
+		return IOError{Cmd: "absolute path", Arg: allowedSignersFile, Err: err}
+	}
+	err = a.writeFile(signersFile, []byte(signers.String()), fileMode)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(opt.RepoDir, opt.cloneTo)
+	hints := []spellHint{{
+		dir:  dir,
+		args: []string{"config", "gpg.format", "ssh"},
+	}, {
+		dir:  dir,
+		args: []string{"config", "gpg.ssh.allowedSignersFile", signersFile},
+	}}
+
+	for _, hint := range hints {
+		a.info("%d/%d: %s", opt.currentSpell, opt.numberOfSpells,
+			strings.Join(hint.args, " "))
+		err := a.git(hint.dir, hint.args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
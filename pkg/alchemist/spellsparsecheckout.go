@@ -0,0 +1,56 @@
+package alchemist
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// sparseCheckoutSpell provides restricting the working tree of the
+// clone to some directories with sparse-checkout in cone mode.
+type sparseCheckoutSpell struct {
+	Action   string   `yaml:"action"` // set, add, or disable, defaults to set
+	Patterns []string `yaml:"patterns"`
+}
+
+// actions of the sparse checkout spell.
+const (
+	sparseSet     = "set"
+	sparseAdd     = "add"
+	sparseDisable = "disable"
+)
+
+// validate checks the values and reports an error if something is missing.
+func (s sparseCheckoutSpell) validate() error {
+	switch s.Action {
+	case "", sparseSet, sparseAdd:
+		if len(s.Patterns) == 0 {
+			return MissingValueError("patterns")
+		}
+	case sparseDisable:
+		if len(s.Patterns) > 0 {
+			return InvalidValueError{Variable: "patterns", Reason: "can not be used with disable"}
+		}
+	default:
+		return InvalidValueError{Variable: "action", Reason: "unknown action " + s.Action}
+	}
+	return nil
+}
+
+// cast executes git sparse-checkout.
+func (s sparseCheckoutSpell) cast(a assistant, opt Options) error {
+
+	var args []string
+	switch s.Action {
+	case "", sparseSet:
+		args = append([]string{"sparse-checkout", "set", "--cone"}, s.Patterns...)
+	case sparseAdd:
+		args = append([]string{"sparse-checkout", "add"}, s.Patterns...)
+	case sparseDisable:
+		args = []string{"sparse-checkout", "disable"}
+	}
+
+	dir := filepath.Join(opt.RepoDir, opt.cloneTo)
+	a.info("%d/%d: %s", opt.currentSpell, opt.numberOfSpells, strings.Join(args, " "))
+
+	return a.git(dir, args...)
+}
@@ -0,0 +1,75 @@
+package alchemist
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// submoduleAddSpell provides adding another bare repo of the
+// repo dir as submodule and committing it.
+type submoduleAddSpell struct {
+	Bare    string `yaml:"bare"`   // bare repo in the repo dir
+	Path    string `yaml:"path"`   // path of the submodule in the clone
+	Branch  string `yaml:"branch"` // optional, branch to track
+	Message string `yaml:"message"`
+	Author  string `yaml:"author"`
+}
+
+// allowFileProtocol allows cloning local submodules, which is
+// disabled by default since git 2.38.1.
+var allowFileProtocol = []string{"-c", "protocol.file.allow=always"}
+
+// validate checks the values and reports an error if something is missing.
+func (s submoduleAddSpell) validate() error {
+	if s.Bare == "" {
+		return MissingValueError("bare")
+	}
+	if s.Path == "" {
+		return MissingValueError("path")
+	}
+	return commitSpell{Message: s.Message, Author: s.Author}.validate()
+}
+
+// cast adds the submodule and commits it.
+func (s submoduleAddSpell) cast(a assistant, opt Options) error {
+
+	dir := filepath.Join(opt.RepoDir, opt.cloneTo)
+	origin, err := a.gitOutput(dir, "remote", "get-url", "origin")
+	if err != nil {
+		return err
+	}
+	url, err := submoduleURL(dir, strings.TrimSpace(origin), filepath.Join(opt.RepoDir, s.Bare))
+	if err != nil {
+		return err
+	}
+
+	args := append([]string{}, allowFileProtocol...)
+	args = append(args, "submodule", "add")
+	if s.Branch != "" {
+		args = append(args, "-b", s.Branch)
+	}
+	args = append(args, url, filepath.ToSlash(s.Path))
+
+	a.info("%d/%d: %s", opt.currentSpell, opt.numberOfSpells, strings.Join(args, " "))
+	err = a.git(dir, args...)
+	if err != nil {
+		return err
+	}
+
+	return commitSpell{Message: s.Message, Author: s.Author}.cast(a, opt)
+}
+
+// submoduleURL returns the url of the bare repo relative to the origin of the clone.
+//
+// Git resolves relative submodule urls against the url of the origin,
+// so every clone of the bare repo finds the submodule.
+func submoduleURL(dir, origin, bare string) (string, error) {
+	if !filepath.IsAbs(origin) {
+		origin = filepath.Join(dir, origin)
+	}
+	rel, err := filepath.Rel(filepath.Dir(origin), bare)
+	if err != nil {
+		return "", InvalidValueError{Variable: "bare", Reason: err.Error()}
+	}
+	return "../" + filepath.ToSlash(rel), nil
+}
@@ -0,0 +1,72 @@
+package alchemist
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// subtreeAddSpell provides adding the branch of another bare repo
+// of the repo dir as subtree with git subtree add.
+type subtreeAddSpell struct {
+	Bare    string `yaml:"bare"`    // bare repo in the repo dir
+	Prefix  string `yaml:"prefix"`  // path of the subtree in the clone
+	Branch  string `yaml:"branch"`  // defaults to main
+	Squash  bool   `yaml:"squash"`  // squash the history of the subtree
+	Message string `yaml:"message"` // optional, message of the merge commit
+	Author  string `yaml:"author"`  // optional, defaults to the user of the clone
+}
+
+// subtreePullSpell provides updating a subtree with git subtree pull.
+// It uses the same values as subtreeAddSpell.
+type subtreePullSpell subtreeAddSpell
+
+// validate checks the values and reports an error if something is missing.
+func (s subtreeAddSpell) validate() error {
+	if s.Bare == "" {
+		return MissingValueError("bare")
+	}
+	if s.Prefix == "" {
+		return MissingValueError("prefix")
+	}
+	return nil
+}
+
+// cast executes git subtree add.
+func (s subtreeAddSpell) cast(a assistant, opt Options) error {
+	return s.subtree(a, opt, "add")
+}
+
+// validate checks the values and reports an error if something is missing.
+func (s subtreePullSpell) validate() error {
+	return subtreeAddSpell(s).validate()
+}
+
+// cast executes git subtree pull.
+func (s subtreePullSpell) cast(a assistant, opt Options) error {
+	return subtreeAddSpell(s).subtree(a, opt, "pull")
+}
+
+// subtree executes the git subtree command.
+// The bare repo is fetched relative to the clone directory.
+func (s subtreeAddSpell) subtree(a assistant, opt Options, command string) error {
+
+	branch := s.Branch
+	if branch == "" {
+		branch = defaultBranch
+	}
+
+	args := authorConfig(s.Author)
+	args = append(args, "subtree", command, "--prefix="+filepath.ToSlash(s.Prefix))
+	if s.Squash {
+		args = append(args, "--squash")
+	}
+	if s.Message != "" {
+		args = append(args, "-m", s.Message)
+	}
+	args = append(args, filepath.ToSlash(filepath.Join("..", s.Bare)), branch)
+
+	dir := filepath.Join(opt.RepoDir, opt.cloneTo)
+	a.info("%d/%d: %s", opt.currentSpell, opt.numberOfSpells, strings.Join(args, " "))
+
+	return a.git(dir, args...)
+}
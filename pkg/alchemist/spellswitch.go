@@ -0,0 +1,75 @@
+package alchemist
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// switchSpell provides switching to a branch, creating a branch or
+// an orphan branch, or detaching HEAD at a commit.
+//
+// An orphan branch starts with an empty worktree: untracked files are
+// removed as well, so following spells like create_file always work
+// on an empty directory. Ignored files, e.g. .env or build output,
+// are kept.
+type switchSpell struct {
+	Branch string `yaml:"branch"`
+	Create bool   `yaml:"create,omitempty"` // create the branch
+	Base   string `yaml:"base,omitempty"`   // start point of the created branch
+	Orphan bool   `yaml:"orphan,omitempty"` // create a branch with unrelated history
+	Detach string `yaml:"detach,omitempty"` // commit to detach HEAD at, e.g. v1.0 or ":/add plan"
+}
+
+// validate checks the values and reports an error if something is missing.
+func (s switchSpell) validate() error {
+	if s.Branch == "" && s.Detach == "" {
+		return MissingValueError("branch or detach")
+	}
+	if s.Branch != "" && s.Detach != "" {
+		return InvalidValueError{Variable: "detach", Reason: "can not be combined with branch"}
+	}
+	if s.Create && s.Orphan {
+		return InvalidValueError{Variable: "orphan", Reason: "can not be combined with create"}
+	}
+	if s.Base != "" && !s.Create {
+		return InvalidValueError{Variable: "base", Reason: "can only be used with create"}
+	}
+	return nil
+}
+
+// cast executes git switch.
+func (s switchSpell) cast(a assistant, opt Options) error {
+
+	dir := filepath.Join(opt.RepoDir, opt.cloneTo)
+
+	var hints []spellHint
+	switch {
+	case s.Detach != "":
+		hints = []spellHint{{dir: dir, args: []string{"switch", "--detach", s.Detach}}}
+	case s.Orphan:
+		hints = []spellHint{
+			// clean first, the ignore rules are removed by the switch
+			{dir: dir, args: []string{"clean", "-d", "--force", "--quiet"}},
+			{dir: dir, args: []string{"switch", "--orphan", s.Branch}},
+		}
+	case s.Create:
+		args := []string{"switch", "--create", s.Branch}
+		if s.Base != "" {
+			args = append(args, s.Base)
+		}
+		hints = []spellHint{{dir: dir, args: args}}
+	default:
+		hints = []spellHint{{dir: dir, args: []string{"switch", s.Branch}}}
+	}
+
+	for _, hint := range hints {
+		a.info("%d/%d: %s", opt.currentSpell, opt.numberOfSpells,
+			strings.Join(hint.args, " "))
+		err := a.git(hint.dir, hint.args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
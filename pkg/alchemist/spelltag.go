@@ -0,0 +1,59 @@
+package alchemist
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// tagSpell provides creating a lightweight, annotated, or signed tag.
+type tagSpell struct {
+	Name    string `yaml:"name"`
+	Ref     string `yaml:"ref,omitempty"`     // optional, defaults to HEAD
+	Message string `yaml:"message,omitempty"` // optional, creates an annotated tag
+	Author  string `yaml:"author,omitempty"`  // optional, tagger, defaults to the user of the clone
+	Sign    bool   `yaml:"sign,omitempty"`    // sign with the ssh key of the author
+}
+
+// validate checks the values and reports an error if something is missing.
+func (s tagSpell) validate() error {
+	if s.Name == "" {
+		return MissingValueError("name")
+	}
+	if s.Sign && s.Message == "" {
+		return InvalidValueError{Variable: "sign", Reason: "signed tags need a message"}
+	}
+	if _, ok := author[s.Author]; s.Sign && !ok {
+		return InvalidValueError{Variable: "author", Reason: "unknown author " + s.Author + " can not sign"}
+	}
+	return nil
+}
+
+// cast executes git tag.
+func (s tagSpell) cast(a assistant, opt Options) error {
+
+	args := authorConfig(s.Author)
+	if s.Sign {
+		sign, err := signConfig(opt.RepoDir, s.Author)
+		if err != nil {
+			return err
+		}
+		args = append(args, sign...)
+	}
+
+	args = append(args, "tag")
+	switch {
+	case s.Sign:
+		args = append(args, "-s", "-m", s.Message)
+	case s.Message != "":
+		args = append(args, "-a", "-m", s.Message)
+	}
+	args = append(args, s.Name)
+	if s.Ref != "" {
+		args = append(args, s.Ref)
+	}
+
+	dir := filepath.Join(opt.RepoDir, opt.cloneTo)
+	a.info("%d/%d: %s", opt.currentSpell, opt.numberOfSpells, strings.Join(args, " "))
+
+	return a.git(dir, args...)
+}
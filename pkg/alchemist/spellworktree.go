@@ -0,0 +1,90 @@
+package alchemist
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// worktreeSpell provides adding and removing additional worktrees
+// of the clone.
+//
+// The path is relative to the repo dir like the clone directory,
+// so it can be used as workspace of the following spells.
+type worktreeSpell struct {
+	Action string `yaml:"action"` // add or remove, defaults to add
+	Path   string `yaml:"path"`
+	Branch string `yaml:"branch"` // add only, branch to check out
+	Create bool   `yaml:"create"` // add only, create the branch
+	Base   string `yaml:"base"`   // add only, start point of the created branch
+	Force  bool   `yaml:"force"`  // remove only, remove a dirty worktree
+}
+
+// actions of the worktree spell.
+const (
+	worktreeAdd    = "add"
+	worktreeRemove = "remove"
+)
+
+// validate checks the values and reports an error if something is missing.
+func (s worktreeSpell) validate() error {
+	if s.Path == "" {
+		return MissingValueError("path")
+	}
+
+	switch s.Action {
+	case "", worktreeAdd:
+		if s.Create && s.Branch == "" {
+			return MissingValueError("branch")
+		}
+		if s.Base != "" && !s.Create {
+			return InvalidValueError{Variable: "base", Reason: "can only be used with create"}
+		}
+	case worktreeRemove:
+		if s.Branch != "" || s.Create || s.Base != "" {
+			return InvalidValueError{Variable: "action",
+				Reason: "remove can not be combined with branch, create, or base"}
+		}
+	default:
+		return InvalidValueError{Variable: "action", Reason: "unknown action " + s.Action}
+	}
+
+	return nil
+}
+
+// cast executes git worktree.
+func (s worktreeSpell) cast(a assistant, opt Options) error {
+
+	dir := filepath.Join(opt.RepoDir, opt.cloneTo)
+	path, err := filepath.Rel(dir, filepath.Join(opt.RepoDir, s.Path))
+	if err != nil {
+		return InvalidValueError{Variable: "path", Reason: err.Error()}
+	}
+	path = filepath.ToSlash(path)
+
+	var args []string
+	switch s.Action {
+	case "", worktreeAdd:
+		args = []string{"worktree", "add"}
+		switch {
+		case s.Create:
+			args = append(args, "-b", s.Branch, path)
+			if s.Base != "" {
+				args = append(args, s.Base)
+			}
+		case s.Branch != "":
+			args = append(args, path, s.Branch)
+		default:
+			args = append(args, path)
+		}
+	case worktreeRemove:
+		args = []string{"worktree", "remove"}
+		if s.Force {
+			args = append(args, "--force")
+		}
+		args = append(args, path)
+	}
+
+	a.info("%d/%d: %s", opt.currentSpell, opt.numberOfSpells, strings.Join(args, " "))
+
+	return a.git(dir, args...)
+}
@@ -2,6 +2,7 @@ package alchemist
 
 import (
 	"fmt"
+	"io/fs"
 	"strings"
 )
 
@@ -11,6 +12,8 @@ type assistantSpy struct {
 	counter int        // track number of calls
 	errorAt int        // return error at this call, count starts with 1
 	calls   [][]string // recorded calls
+	output  string     // output returned by gitOutput
+	content string     // content returned by readFile
 
 	mortalLogger // noop, just to implement assistant interface
 }
@@ -27,6 +30,16 @@ func (s *assistantSpy) git(dir string, args ...string) error {
 	return nil
 }
 
+// gitOutput tracks the git calls and returns the output.
+// If errorAt is reached, an error is returned.
+func (s *assistantSpy) gitOutput(dir string, args ...string) (string, error) {
+	err := s.git(dir, args...)
+	if err != nil {
+		return "", err
+	}
+	return s.output, nil
+}
+
 // copy tracks the copy calls.
 // If errorAt is reached, an error is returned.
 func (s *assistantSpy) copy(from, to string) error {
@@ -38,6 +51,50 @@ func (s *assistantSpy) copy(from, to string) error {
 	return nil
 }
 
+// readFile tracks the readFile calls and returns the content.
+// If errorAt is reached, an error is returned.
+func (s *assistantSpy) readFile(name string) ([]byte, error) {
+	s.counter++
+	if s.counter == s.errorAt {
+		return nil, fmt.Errorf("read %s: spy error: %d", name, s.counter)
+	}
+	s.calls = append(s.calls, []string{"read", name})
+	return []byte(s.content), nil
+}
+
+// writeFile tracks the writeFile calls.
+// If errorAt is reached, an error is returned.
+func (s *assistantSpy) writeFile(name string, data []byte, mode fs.FileMode) error {
+	s.counter++
+	if s.counter == s.errorAt {
+		return fmt.Errorf("write %s: spy error: %d", name, s.counter)
+	}
+	s.calls = append(s.calls, []string{"write", name, mode.String(), string(data)})
+	return nil
+}
+
+// chmod tracks the chmod calls.
+// If errorAt is reached, an error is returned.
+func (s *assistantSpy) chmod(name string, mode fs.FileMode) error {
+	s.counter++
+	if s.counter == s.errorAt {
+		return fmt.Errorf("chmod %s: spy error: %d", name, s.counter)
+	}
+	s.calls = append(s.calls, []string{"chmod", name, mode.String()})
+	return nil
+}
+
+// keygen tracks the keygen calls and returns a dummy public key.
+// If errorAt is reached, an error is returned.
+func (s *assistantSpy) keygen(file, comment string) (string, error) {
+	s.counter++
+	if s.counter == s.errorAt {
+		return "", fmt.Errorf("keygen %s: spy error: %d", file, s.counter)
+	}
+	s.calls = append(s.calls, []string{"keygen", file, comment})
+	return "ssh-ed25519 SPY " + comment, nil
+}
+
 // makedir tracks the makedir calls.
 // If errorAt is reached, an error is returned.
 func (s *assistantSpy) makedir(dir string) error {
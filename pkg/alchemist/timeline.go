@@ -0,0 +1,371 @@
+package alchemist
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// timeline is the history of a repo without the files: the commits,
+// the branches, HEAD, and the tags. The commits are numbered from 1
+// in the order they were created.
+type timeline struct {
+	commits  []timelineCommit
+	branches map[string]int // branch -> commit, unborn branches are missing
+	tags     map[string]int // tag -> commit
+	head     string         // current branch, empty if HEAD is detached
+	detached int            // commit of the detached HEAD
+	forwards []fastForward  // fast-forward merges in the order they happened
+}
+
+// fastForward is a branch that was moved by a fast-forward merge.
+type fastForward struct {
+	branch string
+	commit int // new commit of the branch
+	after  int // number of commits when the branch was moved
+}
+
+// timelineCommit is a commit of the timeline.
+type timelineCommit struct {
+	message string // first line of the message
+	author  string
+	branch  string // branch the commit was created on, empty if detached
+	parents []int
+}
+
+// newTimeline returns an empty timeline on the branch.
+func newTimeline(branch string) *timeline {
+	return &timeline{
+		branches: map[string]int{},
+		tags:     map[string]int{},
+		head:     branch,
+	}
+}
+
+// commit returns the commit with the id.
+func (t *timeline) commit(id int) timelineCommit {
+	return t.commits[id-1]
+}
+
+// current returns the commit of HEAD, 0 if the branch is unborn.
+func (t *timeline) current() int {
+	if t.head == "" {
+		return t.detached
+	}
+	return t.branches[t.head]
+}
+
+// move sets HEAD and the current branch to the commit.
+func (t *timeline) move(id int) {
+	if t.head == "" {
+		t.detached = id
+		return
+	}
+	t.branches[t.head] = id
+}
+
+// add creates a commit on the current branch. With amend, the commit
+// replaces the current commit, an empty message or author is kept.
+func (t *timeline) add(message, author string, amend bool) error {
+
+	parents := []int{}
+	if current := t.current(); current != 0 {
+		parents = append(parents, current)
+	}
+
+	if amend {
+		if len(parents) == 0 {
+			return InvalidValueError{Variable: "amend", Reason: "no commit to amend on " + t.headName()}
+		}
+		last := t.commit(parents[0])
+		parents = slices.Clone(last.parents)
+		if message == "" {
+			message = last.message
+		}
+		if author == "" {
+			author = last.author
+		}
+	}
+
+	t.commits = append(t.commits, timelineCommit{
+		message: firstLine(message),
+		author:  author,
+		branch:  t.head,
+		parents: parents,
+	})
+	t.move(len(t.commits))
+	return nil
+}
+
+// headName returns the current branch or "detached HEAD".
+func (t *timeline) headName() string {
+	if t.head == "" {
+		return "detached HEAD"
+	}
+	return t.head
+}
+
+// firstLine returns the first line of the message.
+func firstLine(message string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	return strings.TrimSpace(line)
+}
+
+// resolve returns the commit of a revision: HEAD, a branch, a tag,
+// or ":/text" for the youngest commit with the text in its message,
+// each optionally followed by ~n and ^n.
+func (t *timeline) resolve(rev string) (int, error) {
+
+	if text, ok := strings.CutPrefix(rev, ":/"); ok {
+		for id := len(t.commits); id > 0; id-- {
+			if strings.Contains(t.commit(id).message, text) {
+				return id, nil
+			}
+		}
+		return 0, InvalidValueError{Variable: "revision", Reason: "no commit with message " + text}
+	}
+
+	name, suffix := rev, ""
+	if i := strings.IndexAny(rev, "~^"); i >= 0 {
+		name, suffix = rev[:i], rev[i:]
+	}
+	name = strings.TrimPrefix(strings.TrimPrefix(name, "refs/heads/"), "refs/tags/")
+
+	var id int
+	switch {
+	case name == "HEAD" || name == "@":
+		id = t.current()
+	case t.branches[name] != 0:
+		id = t.branches[name]
+	default:
+		id = t.tags[name]
+	}
+	if id == 0 {
+		return 0, InvalidValueError{Variable: "revision", Reason: "unknown revision " + rev}
+	}
+
+	for suffix != "" {
+		op := suffix[0]
+		end := 1
+		for end < len(suffix) && suffix[end] >= '0' && suffix[end] <= '9' {
+			end++
+		}
+		n := 1
+		if end > 1 {
+			n, _ = strconv.Atoi(suffix[1:end])
+		}
+		suffix = suffix[end:]
+
+		switch {
+		case op == '~':
+			for ; n > 0 && id != 0; n-- {
+				id = t.parent(id, 1)
+			}
+		case n > 0:
+			id = t.parent(id, n)
+		}
+		if id == 0 {
+			return 0, InvalidValueError{Variable: "revision", Reason: "unknown revision " + rev}
+		}
+	}
+	return id, nil
+}
+
+// parent returns the nth parent of the commit, 0 if it does not exist.
+func (t *timeline) parent(id, n int) int {
+	parents := t.commit(id).parents
+	if n > len(parents) {
+		return 0
+	}
+	return parents[n-1]
+}
+
+// switchTo switches to an existing branch.
+func (t *timeline) switchTo(branch string) error {
+	if _, ok := t.branches[branch]; !ok && branch != t.head {
+		return InvalidValueError{Variable: "branch", Reason: "unknown branch " + branch}
+	}
+	t.head = branch
+	return nil
+}
+
+// create creates a branch at the revision, HEAD if it is empty.
+func (t *timeline) create(branch, rev string) error {
+	if _, ok := t.branches[branch]; ok {
+		return InvalidValueError{Variable: "branch", Reason: "branch " + branch + " exists"}
+	}
+	if rev == "" {
+		rev = "HEAD"
+	}
+	id, err := t.resolve(rev)
+	if err != nil {
+		return err
+	}
+	t.branches[branch] = id
+	return nil
+}
+
+// orphan switches to a new unborn branch.
+func (t *timeline) orphan(branch string) error {
+	if _, ok := t.branches[branch]; ok {
+		return InvalidValueError{Variable: "branch", Reason: "branch " + branch + " exists"}
+	}
+	t.head = branch
+	return nil
+}
+
+// detach detaches HEAD at the revision.
+func (t *timeline) detach(rev string) error {
+	id, err := t.resolve(rev)
+	if err != nil {
+		return err
+	}
+	t.head, t.detached = "", id
+	return nil
+}
+
+// deleteBranch deletes a branch. Without force, it must be merged
+// into HEAD.
+func (t *timeline) deleteBranch(branch string, force bool) error {
+	id, ok := t.branches[branch]
+	switch {
+	case !ok:
+		return InvalidValueError{Variable: "branch", Reason: "unknown branch " + branch}
+	case branch == t.head:
+		return InvalidValueError{Variable: "branch", Reason: "can not delete the current branch " + branch}
+	case !force && !t.isAncestor(id, t.current()):
+		return InvalidValueError{Variable: "branch", Reason: "branch " + branch + " is not fully merged"}
+	}
+	delete(t.branches, branch)
+	return nil
+}
+
+// renameBranch renames a branch, the current branch is renamed as
+// well if it is unborn.
+func (t *timeline) renameBranch(from, to string) error {
+	if _, ok := t.branches[to]; ok {
+		return InvalidValueError{Variable: "branch", Reason: "branch " + to + " exists"}
+	}
+	id, ok := t.branches[from]
+	if !ok && from != t.head {
+		return InvalidValueError{Variable: "branch", Reason: "unknown branch " + from}
+	}
+	if ok {
+		delete(t.branches, from)
+		t.branches[to] = id
+	}
+	if t.head == from {
+		t.head = to
+	}
+	return nil
+}
+
+// tag creates a tag at the revision, HEAD if it is empty.
+func (t *timeline) tag(name, rev string) error {
+	if _, ok := t.tags[name]; ok {
+		return InvalidValueError{Variable: "tag", Reason: "tag " + name + " exists"}
+	}
+	if rev == "" {
+		rev = "HEAD"
+	}
+	id, err := t.resolve(rev)
+	if err != nil {
+		return err
+	}
+	t.tags[name] = id
+	return nil
+}
+
+// deleteTag deletes a tag.
+func (t *timeline) deleteTag(name string) error {
+	if _, ok := t.tags[name]; !ok {
+		return InvalidValueError{Variable: "tag", Reason: "unknown tag " + name}
+	}
+	delete(t.tags, name)
+	return nil
+}
+
+// merge merges the revision into the current branch. It is fast
+// forwarded unless noFF is set. A merge commit gets the default message
+// of git unless a message is given.
+func (t *timeline) merge(rev string, noFF bool, message, author string) error {
+
+	source, err := t.resolve(rev)
+	if err != nil {
+		return err
+	}
+	target := t.current()
+
+	switch {
+	case target == 0:
+		return InvalidValueError{Variable: "merge", Reason: "no commit on " + t.headName()}
+	case t.isAncestor(source, target):
+		// already up to date
+		return nil
+	case t.isAncestor(target, source) && !noFF:
+		if t.head != "" {
+			t.forwards = append(t.forwards, fastForward{branch: t.head, commit: source, after: len(t.commits)})
+		}
+		t.move(source)
+		return nil
+	}
+
+	if message == "" {
+		message = "Merge branch '" + rev + "'"
+		if _, ok := t.tags[rev]; ok && t.branches[rev] == 0 {
+			message = "Merge tag '" + rev + "'"
+		}
+		if t.head != "" && t.head != "main" && t.head != "master" {
+			message += " into " + t.head
+		}
+	}
+
+	t.commits = append(t.commits, timelineCommit{
+		message: firstLine(message),
+		author:  author,
+		branch:  t.head,
+		parents: []int{target, source},
+	})
+	t.move(len(t.commits))
+	return nil
+}
+
+// reset moves the current branch to the revision.
+func (t *timeline) reset(rev string) error {
+	id, err := t.resolve(rev)
+	if err != nil {
+		return err
+	}
+	t.move(id)
+	return nil
+}
+
+// isAncestor reports if commit a is an ancestor of commit b or b itself.
+func (t *timeline) isAncestor(a, b int) bool {
+	return a != 0 && b != 0 && t.ancestors(b)[a]
+}
+
+// ancestors returns the commits and all their ancestors.
+func (t *timeline) ancestors(ids ...int) map[int]bool {
+	result := map[int]bool{}
+	queue := slices.Clone(ids)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if id == 0 || result[id] {
+			continue
+		}
+		result[id] = true
+		queue = append(queue, t.commit(id).parents...)
+	}
+	return result
+}
+
+// reachable returns the commits that can be reached from the branches,
+// the tags, and HEAD.
+func (t *timeline) reachable() map[int]bool {
+	ids := slices.Collect(maps.Values(t.branches))
+	ids = append(ids, slices.Collect(maps.Values(t.tags))...)
+	return t.ancestors(append(ids, t.current())...)
+}